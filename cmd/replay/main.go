@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/minaorangina/shed/engine"
+	"github.com/minaorangina/shed/game"
+)
+
+const usageText = `Usage: replay [-to seq] [-all] game.json
+
+Steps through a saved game, one event at a time.
+Its players can save a finished game from the server at
+/snapshot?gameID=<game>&playerID=<player>.
+Press Enter to replay the next event, or type q to quit.
+`
+
+func main() {
+	to := flag.Int("to", 0, "replay up to this event before stepping")
+	all := flag.Bool("all", false, "replay every event without stopping")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usageText)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := ioutil.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("could not read game: %v", err)
+	}
+
+	var record game.Snapshot
+	if err := json.Unmarshal(data, &record); err != nil {
+		log.Fatalf("could not parse game: %v", err)
+	}
+
+	r, err := game.NewReplay(record)
+	if err != nil {
+		log.Fatalf("could not replay game: %v", err)
+	}
+
+	for r.Seq() < *to {
+		if err := r.Step(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if r.Seq() > 0 {
+		printState(r, r.Seq())
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	for {
+		start := r.Seq()
+		err := r.Step()
+		if errors.Is(err, game.ErrReplayFinished) {
+			fmt.Println("End of game.")
+			return
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		printState(r, start)
+
+		if *all {
+			continue
+		}
+		fmt.Printf("[%d/%d] Enter for the next event, q to quit: ", r.Seq(), r.Len())
+		if !in.Scan() || strings.TrimSpace(in.Text()) == "q" {
+			return
+		}
+	}
+}
+
+// printState shows the events replayed since start, and the game as it is now
+func printState(r *game.Replay, start int) {
+	events := r.Events()
+	for _, e := range events[start:] {
+		fmt.Printf("\n#%d %s %s", e.Seq, e.PlayerID, e.Kind)
+		if e.Group != "" {
+			fmt.Printf(" (%s)", e.Group)
+		}
+		fmt.Println()
+		fmt.Print(engine.BuildCardsText(e.Cards))
+	}
+
+	g := r.Game().Snapshot()
+	fmt.Printf("\nDeck: %d cards. Burned: %d cards.\n", len(g.Deck), len(g.Burned))
+	fmt.Printf("Pile:\n%s", engine.BuildCardsText(g.Pile))
+
+	for _, p := range g.PlayerInfo {
+		cards := g.PlayerCards[p.PlayerID]
+		marker := ""
+		if p.PlayerID == g.CurrentPlayer.PlayerID {
+			marker = " (to play)"
+		}
+		fmt.Printf("\n%s%s\n", p.PlayerID, marker)
+		fmt.Printf(" Hand:\n%s", engine.BuildCardsText(cards.Hand))
+		fmt.Printf(" Seen:\n%s", engine.BuildCardsText(cards.Seen))
+		fmt.Printf(" Unseen:\n%s", engine.BuildCardsText(cards.Unseen))
+	}
+	fmt.Println()
+}
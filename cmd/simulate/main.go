@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/minaorangina/shed/engine"
+	"github.com/minaorangina/shed/game"
+	"github.com/minaorangina/shed/simulate"
+)
+
+const usageText = `Usage: simulate [-games n] [-seed n] [-players list] [-rules rules.json]
+
+Plays many games between computer players and reports how each strategy did.
+Players are given as a comma-separated list of strategies, one per seat:
+random, lowest, power or montecarlo.
+`
+
+func main() {
+	games := flag.Int("games", 1000, "how many games to play")
+	seed := flag.Int64("seed", 1, "seed for the games")
+	players := flag.String("players", "random,lowest,power", "the strategy at each seat")
+	rulesPath := flag.String("rules", "", "JSON file of house rules to play by")
+	maxSteps := flag.Int("max-steps", 0, "steps before a game is called a stalemate")
+	samples := flag.Int("samples", 0, "imagined games per decision for montecarlo players")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usageText)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	rules := game.DefaultRules()
+	if *rulesPath != "" {
+		data, err := ioutil.ReadFile(*rulesPath)
+		if err != nil {
+			log.Fatalf("could not read rules: %v", err)
+		}
+		if err := json.Unmarshal(data, &rules); err != nil {
+			log.Fatalf("could not parse rules: %v", err)
+		}
+		if err := rules.Validate(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	contenders := []simulate.Contender{}
+	for i, name := range strings.Split(*players, ",") {
+		c, err := contender(strings.TrimSpace(name), rules, *samples)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// seats with the same strategy are told apart by number
+		c.Name = fmt.Sprintf("%d: %s", i+1, c.Name)
+		contenders = append(contenders, c)
+	}
+
+	result, err := simulate.Run(simulate.Opts{
+		Contenders: contenders,
+		Games:      *games,
+		Seed:       *seed,
+		Rules:      rules,
+		MaxSteps:   *maxSteps,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	printResult(result)
+	if len(result.Failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+func contender(name string, rules game.Rules, samples int) (simulate.Contender, error) {
+	var newStrategy func(g game.Game, seed int64) engine.Strategy
+
+	switch name {
+	case "random":
+		newStrategy = func(g game.Game, seed int64) engine.Strategy { return engine.NewRandomStrategy(seed) }
+	case "lowest":
+		newStrategy = func(g game.Game, seed int64) engine.Strategy { return engine.NewLowestFirstStrategy(rules) }
+	case "power":
+		newStrategy = func(g game.Game, seed int64) engine.Strategy { return engine.NewHoldPowerCardsStrategy(rules) }
+	case "montecarlo":
+		newStrategy = func(g game.Game, seed int64) engine.Strategy {
+			return engine.NewMonteCarloStrategy(game.SearchOpts{Samples: samples, Seed: seed})
+		}
+	default:
+		return simulate.Contender{}, fmt.Errorf("unknown strategy %q", name)
+	}
+
+	return simulate.Contender{Name: name, New: newStrategy}, nil
+}
+
+func printResult(result simulate.Result) {
+	fmt.Printf("games: %d, finished: %d, stalemates: %d, failures: %d\n",
+		result.Games, result.Finished, result.Stalemates, len(result.Failures))
+	fmt.Printf("average moves per game: %.1f\n\n", result.AverageMoves)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "player\twins\twin rate\tavg position\tburns\tpick ups")
+	for _, c := range result.Contenders {
+		fmt.Fprintf(w, "%s\t%d\t%.3f\t%.2f\t%d\t%d\n",
+			c.Name, c.Wins, c.WinRate, c.AverageFinishingPosition, c.Burns, c.PilePickUps)
+	}
+	w.Flush()
+
+	for _, f := range result.Failures {
+		fmt.Printf("\n%s", f.Error())
+	}
+	if len(result.Failures) > 0 {
+		fmt.Println()
+	}
+}
@@ -50,7 +50,7 @@ func main() {
 		}
 	}()
 
-	signalCh := make(chan os.Signal)
+	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
 
 	requestGracePeriod := time.Second * 5
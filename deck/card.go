@@ -8,7 +8,7 @@ import (
 // Rank represents a rank in a deck of cards
 type Rank int
 
-var rankNames = []string{"NullRank", "Ace", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine", "Ten", "Jack", "Queen", "King"}
+var rankNames = []string{"NullRank", "Ace", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine", "Ten", "Jack", "Queen", "King", "Joker"}
 
 const (
 	NullRank Rank = iota
@@ -25,6 +25,7 @@ const (
 	Jack
 	Queen
 	King
+	Joker // has no suit
 )
 
 func (r Rank) String() string {
@@ -52,31 +53,53 @@ func (s Suit) String() string {
 type Card struct {
 	Rank Rank
 	Suit Suit
-	// maybe a property to represent Jack, King, Queen, Joker etc
+	// ID identifies the card within a game, so that identical cards
+	// from different decks can be told apart. Zero means no ID has been assigned.
+	ID int
 }
 
-// NewCard constructs a card
+// NewCard constructs a card. Jokers have no suit.
 func NewCard(rank Rank, suit Suit) Card {
-	if rank < Rank(0) || rank > King || suit < Suit(0) || suit > Spades {
+	if rank < Rank(0) || rank > Joker || suit < Suit(0) || suit > Spades {
 		panic("arguments out of range")
 	}
 
-	if (rank == NullRank && suit != NullSuit) || (suit == NullSuit && rank != NullRank) {
+	suitless := rank == NullRank || rank == Joker
+	if suitless != (suit == NullSuit) {
 		panic("invalid card")
 	}
 
 	return Card{Rank: Rank(rank), Suit: Suit(suit)}
 }
 
+// NewJoker constructs a joker
+func NewJoker() Card {
+	return NewCard(Joker, NullSuit)
+}
+
 func (c Card) String() string {
+	if c.Rank == Joker {
+		return rankNames[c.Rank]
+	}
 	return fmt.Sprintf("%s of %s", rankNames[c.Rank], suitNames[c.Suit])
 }
 
+// Face returns the card without its ID, i.e. just its rank and suit
+func (c Card) Face() Card {
+	return Card{Rank: c.Rank, Suit: c.Suit}
+}
+
+// Matches reports whether two cards have the same rank and suit, regardless of ID
+func (c Card) Matches(other Card) bool {
+	return c.Face() == other.Face()
+}
+
 func (c Card) ToWireCard() WireCard {
 	return WireCard{
 		Rank:          rankNames[c.Rank],
 		Suit:          suitNames[c.Suit],
 		CanonicalName: c.String(),
+		ID:            c.ID,
 	}
 }
 
@@ -98,6 +121,7 @@ type WireCard struct {
 	Rank          string `json:"rank"`
 	Suit          string `json:"suit"`
 	CanonicalName string `json:"canonicalName"`
+	ID            int    `json:"id,omitempty"`
 }
 
 func (wc WireCard) String() string {
@@ -122,5 +146,7 @@ func (wc WireCard) ToCard() Card {
 		}
 	}
 
-	return NewCard(rank, suit)
+	card := NewCard(rank, suit)
+	card.ID = wc.ID
+	return card
 }
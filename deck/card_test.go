@@ -18,6 +18,7 @@ func TestCard(t *testing.T) {
 		{"Lowest value card", NewCard(1, 1), "Ace of Clubs"},
 		{"Specific card", NewCard(12, 3), "Queen of Hearts"},
 		{"Highest value card", NewCard(13, 4), "King of Spades"},
+		{"Joker", NewJoker(), "Joker"},
 	}
 
 	for _, c := range cases {
@@ -29,15 +30,17 @@ func TestCard(t *testing.T) {
 		utils.ShouldPanic(t, func() { NewCard(4, 5) })
 		utils.ShouldPanic(t, func() { NewCard(0, 4) })
 		utils.ShouldPanic(t, func() { NewCard(4, 0) })
+		utils.ShouldPanic(t, func() { NewCard(Joker, Hearts) })
+		utils.ShouldPanic(t, func() { NewCard(Joker+1, NullSuit) })
 	})
 
 	t.Run("get rank", func(t *testing.T) {
-		six := NewCard(Rank(6), Suit(rand.Intn(4)))
+		six := NewCard(Rank(6), Suit(rand.Intn(4)+1))
 		utils.AssertEqual(t, six.Rank.String(), "Six")
 	})
 
 	t.Run("get suit", func(t *testing.T) {
-		spade := NewCard(Rank(rand.Intn(13)), Suit(4))
+		spade := NewCard(Rank(rand.Intn(13)+1), Suit(4))
 		utils.AssertEqual(t, spade.Suit.String(), "Spades")
 	})
 }
@@ -112,14 +115,24 @@ func TestWireCard(t *testing.T) {
 		}{
 			{
 				"Ace of Spades",
-				WireCard{"Ace", "Spades", "Ace of Spades"},
+				WireCard{Rank: "Ace", Suit: "Spades", CanonicalName: "Ace of Spades"},
 				NewCard(Ace, Spades),
 			},
 			{
 				"NullRank of NullSuit",
-				WireCard{"NullRank", "NullSuit", "NullRank of NullSuit"},
+				WireCard{Rank: "NullRank", Suit: "NullSuit", CanonicalName: "NullRank of NullSuit"},
 				NewCard(NullRank, NullSuit),
 			},
+			{
+				"Queen of Hearts with an ID",
+				WireCard{Rank: "Queen", Suit: "Hearts", CanonicalName: "Queen of Hearts", ID: 17},
+				Card{Rank: Queen, Suit: Hearts, ID: 17},
+			},
+			{
+				"Joker",
+				WireCard{Rank: "Joker", Suit: "NullSuit", CanonicalName: "Joker"},
+				NewJoker(),
+			},
 		}
 
 		for _, tc := range tt {
@@ -151,6 +164,16 @@ func TestWireCard(t *testing.T) {
 				NewCard(NullRank, NullSuit),
 				[]byte(`{"rank":"NullRank","suit":"NullSuit","canonicalName":"NullRank of NullSuit"}`),
 			},
+			{
+				"Queen of Hearts with an ID",
+				Card{Rank: Queen, Suit: Hearts, ID: 17},
+				[]byte(`{"rank":"Queen","suit":"Hearts","canonicalName":"Queen of Hearts","id":17}`),
+			},
+			{
+				"Joker",
+				NewJoker(),
+				[]byte(`{"rank":"Joker","suit":"NullSuit","canonicalName":"Joker"}`),
+			},
 		}
 
 		for _, tc := range tt {
@@ -162,3 +185,14 @@ func TestWireCard(t *testing.T) {
 		}
 	})
 }
+
+func TestCardMatches(t *testing.T) {
+	queen := NewCard(Queen, Hearts)
+	otherQueen := queen
+	otherQueen.ID = 2
+
+	utils.AssertTrue(t, queen.Matches(otherQueen))
+	utils.AssertTrue(t, queen != otherQueen)
+	utils.AssertEqual(t, otherQueen.Face(), queen)
+	utils.AssertTrue(t, !queen.Matches(NewCard(Queen, Spades)))
+}
@@ -10,10 +10,10 @@ type Deck []Card
 
 // New creates a deck of cards
 func New() Deck {
-	// all cards (option to leave out jokers)
+	// all cards, without jokers
 	cards := []Card{}
 	for suit := range suitNames[1:] {
-		for rank := range rankNames[1:] {
+		for rank := range rankNames[1:Joker] {
 			// Add 1 to skip null cards
 			c := NewCard(Rank(rank+1), Suit(suit+1))
 			cards = append(cards, c)
@@ -22,12 +22,43 @@ func New() Deck {
 	return cards
 }
 
-// Shuffle shuffles the deck of cards
+// ShoeOpts configures a shoe of cards
+type ShoeOpts struct {
+	// Decks is the number of decks in the shoe. Fewer than one means one.
+	Decks int
+	// Jokers is the number of jokers added to the shoe.
+	Jokers int
+}
+
+// NewShoe creates a shoe: several decks of cards combined into one,
+// plus any jokers. A shoe of n decks holds n of every card.
+func NewShoe(opts ShoeOpts) Deck {
+	decks := opts.Decks
+	if decks < 1 {
+		decks = 1
+	}
+
+	cards := Deck{}
+	for i := 0; i < decks; i++ {
+		cards = append(cards, New()...)
+	}
+	for i := 0; i < opts.Jokers; i++ {
+		cards = append(cards, NewJoker())
+	}
+	return cards
+}
+
+// Shuffle shuffles the deck of cards using a time-seeded random source
 func (d *Deck) Shuffle() {
-	rand.Seed(time.Now().UnixNano())
+	d.ShuffleWith(rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+// ShuffleWith shuffles the deck of cards using the given random source.
+// The same source (i.e. the same seed) always yields the same order.
+func (d *Deck) ShuffleWith(r *rand.Rand) {
 	actualDeck := (*d)
 	for i := len(actualDeck) - 1; i > 0; i-- {
-		randomNumber := rand.Intn(i)
+		randomNumber := r.Intn(i)
 		actualDeck[i], actualDeck[randomNumber] = actualDeck[randomNumber], actualDeck[i]
 	}
 }
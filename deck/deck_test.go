@@ -1,6 +1,7 @@
 package deck
 
 import (
+	"math/rand"
 	"reflect"
 	"testing"
 
@@ -16,6 +17,42 @@ func TestNewDeck(t *testing.T) {
 		utils.FailureMessage(t, len(deckOfCards), fullDeckCount)
 	}
 }
+
+func TestNewShoe(t *testing.T) {
+	t.Run("holds every card once per deck", func(t *testing.T) {
+		shoe := NewShoe(ShoeOpts{Decks: 3})
+		utils.AssertEqual(t, len(shoe), 3*fullDeckCount)
+
+		counts := map[Card]int{}
+		for _, c := range shoe {
+			counts[c]++
+		}
+		utils.AssertEqual(t, len(counts), fullDeckCount)
+		for c, n := range counts {
+			if n != 3 {
+				t.Errorf("%s appears %d times, want 3", c, n)
+			}
+		}
+	})
+
+	t.Run("defaults to one deck", func(t *testing.T) {
+		utils.AssertDeepEqual(t, NewShoe(ShoeOpts{}), New())
+	})
+
+	t.Run("includes jokers", func(t *testing.T) {
+		shoe := NewShoe(ShoeOpts{Decks: 2, Jokers: 3})
+		utils.AssertEqual(t, len(shoe), 2*fullDeckCount+3)
+
+		jokers := 0
+		for _, c := range shoe {
+			if c.Rank == Joker {
+				jokers++
+			}
+		}
+		utils.AssertEqual(t, jokers, 3)
+	})
+}
+
 func TestDeckShuffle(t *testing.T) {
 	deckOfCards := New()
 	anotherDeckOfCards := New()
@@ -84,3 +121,22 @@ func TestDeckRemoveCards(t *testing.T) {
 		utils.AssertEqual(t, len(removedCards), 52)
 	})
 }
+
+func TestDeckShuffleWith(t *testing.T) {
+	t.Run("same seed gives the same order", func(t *testing.T) {
+		d1, d2 := New(), New()
+		d1.ShuffleWith(rand.New(rand.NewSource(42)))
+		d2.ShuffleWith(rand.New(rand.NewSource(42)))
+
+		utils.AssertDeepEqual(t, d1, d2)
+		utils.AssertNotDeepEqual(t, d1, New())
+	})
+
+	t.Run("different seeds give different orders", func(t *testing.T) {
+		d1, d2 := New(), New()
+		d1.ShuffleWith(rand.New(rand.NewSource(1)))
+		d2.ShuffleWith(rand.New(rand.NewSource(2)))
+
+		utils.AssertNotDeepEqual(t, d1, d2)
+	})
+}
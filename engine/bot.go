@@ -0,0 +1,391 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/minaorangina/shed/deck"
+	"github.com/minaorangina/shed/game"
+	"github.com/minaorangina/shed/protocol"
+)
+
+// ErrUnknownDifficulty is returned for a Difficulty there is no strategy for
+var ErrUnknownDifficulty = errors.New("unknown difficulty")
+
+// Difficulty is how hard a computer player is to beat
+type Difficulty string
+
+const (
+	Easy   Difficulty = "easy"
+	Medium Difficulty = "medium"
+	Hard   Difficulty = "hard"
+)
+
+// NewStrategyFor returns the Strategy a computer player of the given difficulty plays the game with:
+// Easy plays at random, Medium saves its power cards, and Hard searches the game for its best decision
+func NewStrategyFor(difficulty Difficulty, g game.Game, seed int64) (Strategy, error) {
+	switch difficulty {
+	case Easy:
+		return NewRandomStrategy(seed), nil
+	case Medium:
+		return NewHoldPowerCardsStrategy(game.RulesOf(g)), nil
+	case Hard:
+		return NewMonteCarloStrategy(game.SearchOpts{Seed: seed}), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownDifficulty, difficulty)
+}
+
+// Strategy decides which cards a computer player chooses
+type Strategy interface {
+	// Decide returns the indices of the cards to choose in answer to a message
+	// asking for a Reorg, PlayHand, PlaySeen, PlayHandAndSeen or PlayUnseen decision.
+	// The indices are into the cards the message offers: hand and seen together
+	// for Reorg and PlayHandAndSeen, and the group being played otherwise.
+	Decide(msg protocol.OutboundMessage) []int
+}
+
+// GameStrategy is a Strategy that reads the game, as well as the message, to decide
+type GameStrategy interface {
+	Strategy
+	// DecideIn decides as Decide does, reading the game as it was when the decision was asked for.
+	// The game must not change while it decides.
+	DecideIn(g game.Game, msg protocol.OutboundMessage) []int
+}
+
+// BotPlayerOpts represents options for constructing a new BotPlayer
+type BotPlayerOpts struct {
+	ID       string
+	Name     string
+	Strategy Strategy
+	Engine   GameEngine
+	// Delay is how long the bot waits before answering, so that people can follow its moves
+	Delay time.Duration
+}
+
+// BotPlayer is a Player played by the computer.
+// It answers every message that asks for a response by sending a decision to the GameEngine.
+type BotPlayer struct {
+	id       string
+	name     string
+	strategy Strategy
+	ge       GameEngine
+	delay    time.Duration
+
+	mu      sync.Mutex
+	cards   game.PlayerCards
+	pending []request
+	last    *request // the last message that asked the bot for a response
+	retries int      // how many times in a row the game has rejected the bot's answer to last
+	wake    chan struct{}
+}
+
+// retryLimit is how many times in a row a bot decides again when the game rejects its decision
+const retryLimit = 3
+
+// request is a message for the bot to answer, along with a copy of the game
+// for a GameStrategy to read, taken when the message was sent
+type request struct {
+	msg  protocol.OutboundMessage
+	view game.Game
+}
+
+// NewBotPlayer constructs a new BotPlayer
+func NewBotPlayer(opts BotPlayerOpts) *BotPlayer {
+	bot := &BotPlayer{
+		id:       opts.ID,
+		name:     opts.Name,
+		strategy: opts.Strategy,
+		ge:       opts.Engine,
+		delay:    opts.Delay,
+		wake:     make(chan struct{}, 1),
+	}
+
+	go bot.play()
+
+	return bot
+}
+
+func (b *BotPlayer) Info() protocol.Player {
+	return protocol.Player{
+		PlayerID: b.id,
+		Name:     b.name,
+	}
+}
+
+func (b *BotPlayer) ID() string {
+	return b.id
+}
+
+func (b *BotPlayer) Name() string {
+	return b.name
+}
+
+// Cards returns the bot's cards, as the game last showed them
+func (b *BotPlayer) Cards() *game.PlayerCards {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return &game.PlayerCards{
+		Hand:   b.cards.Hand,
+		Seen:   b.cards.Seen,
+		Unseen: b.cards.Unseen,
+	}
+}
+
+// Send queues a message for the bot to answer. It never blocks,
+// as the GameEngine must not wait for the bot while the bot waits for the GameEngine.
+// The GameEngine sends messages while the game waits for its players, so a bot
+// whose strategy reads the game copies it here, rather than reading it while it is played.
+func (b *BotPlayer) Send(msg protocol.OutboundMessage) error {
+	var view game.Game
+	if _, ok := b.strategy.(GameStrategy); ok && msg.ShouldRespond && b.ge != nil {
+		b.ge.Inspect(func(g game.Game) {
+			view = game.CloneOf(g)
+		})
+	}
+
+	b.mu.Lock()
+	if msg.Hand != nil || msg.Seen != nil || msg.Unseen != nil {
+		b.cards = game.PlayerCards{Hand: msg.Hand, Seen: msg.Seen, Unseen: msg.Unseen}
+	}
+	switch {
+	case msg.Command == protocol.Error:
+		// the game rejected the bot's answer, so it decides again on what it was asked
+		if msg.ShouldRespond && b.last != nil && b.retries < retryLimit {
+			b.retries++
+			b.pending = append(b.pending, request{msg: b.last.msg, view: view})
+		}
+	case msg.ShouldRespond:
+		req := request{msg: msg, view: view}
+		b.pending = append(b.pending, req)
+		b.last, b.retries = &req, 0
+	case msg.Command == protocol.GameOver:
+		b.pending = append(b.pending, request{msg: msg})
+	}
+	b.mu.Unlock()
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Receive does nothing: the bot is sent messages with Send
+func (b *BotPlayer) Receive(data []byte) {}
+
+// play answers each queued message in turn, until the game is over or the GameEngine stops
+func (b *BotPlayer) play() {
+	var done <-chan struct{}
+	if b.ge != nil {
+		done = b.ge.Done()
+	}
+
+	for {
+		select {
+		case <-b.wake:
+		case <-done:
+			return
+		}
+
+		for {
+			b.mu.Lock()
+			if len(b.pending) == 0 {
+				b.mu.Unlock()
+				break
+			}
+			req := b.pending[0]
+			b.pending = b.pending[1:]
+			b.mu.Unlock()
+
+			if req.msg.Command == protocol.GameOver {
+				return
+			}
+
+			time.Sleep(b.delay)
+			b.ge.Receive(Respond(b.strategy, req.view, req.msg))
+		}
+	}
+}
+
+// Respond answers a message that asks for a response, as a computer player playing the strategy does:
+// with the strategy's decision if the message asks for one, and an acknowledgement otherwise.
+// A GameStrategy reads g to decide, if it is given.
+func Respond(strategy Strategy, g game.Game, msg protocol.OutboundMessage) protocol.InboundMessage {
+	response := protocol.InboundMessage{PlayerID: msg.PlayerID, Command: msg.Command}
+
+	decide := strategy.Decide
+	if gs, ok := strategy.(GameStrategy); ok && g != nil {
+		decide = func(msg protocol.OutboundMessage) []int { return gs.DecideIn(g, msg) }
+	}
+
+	switch msg.Command {
+	case protocol.Reorg, protocol.PlayHand, protocol.PlaySeen, protocol.PlayHandAndSeen:
+		response.Decision = decide(msg)
+
+	case protocol.PlayUnseen:
+		// unseen cards are turned over one at a time
+		response.Decision = decide(msg)[:1]
+	}
+
+	return response
+}
+
+// offeredCards returns the cards the message's decision is indexed into
+func offeredCards(msg protocol.OutboundMessage) []deck.Card {
+	switch msg.Command {
+	case protocol.Reorg, protocol.PlayHandAndSeen:
+		return append(append([]deck.Card{}, msg.Hand...), msg.Seen...)
+	case protocol.PlaySeen:
+		return msg.Seen
+	case protocol.PlayUnseen:
+		return msg.Unseen
+	}
+	return msg.Hand
+}
+
+// sameRank returns the moves that have the same rank as the chosen card
+func sameRank(cards []deck.Card, moves []int, chosen int) []int {
+	decision := []int{}
+	for _, m := range moves {
+		if cards[m].Rank == cards[chosen].Rank {
+			decision = append(decision, m)
+		}
+	}
+	return decision
+}
+
+type randomStrategy struct {
+	rng *rand.Rand
+}
+
+// NewRandomStrategy returns a Strategy that chooses at random:
+// it keeps three random cards in hand, and plays one random legal card
+func NewRandomStrategy(seed int64) Strategy {
+	return &randomStrategy{rng: rand.New(rand.NewSource(seed))}
+}
+
+func (s *randomStrategy) Decide(msg protocol.OutboundMessage) []int {
+	switch msg.Command {
+	case protocol.Reorg:
+		return s.rng.Perm(len(offeredCards(msg)))[:3]
+	case protocol.PlayHandAndSeen:
+		return msg.Moves
+	}
+	return []int{msg.Moves[s.rng.Intn(len(msg.Moves))]}
+}
+
+type lowestFirstStrategy struct {
+	rules game.Rules
+}
+
+// NewLowestFirstStrategy returns a Strategy that gets rid of its weakest cards first:
+// it plays every card of the lowest rank it can, and keeps the cards it was dealt
+func NewLowestFirstStrategy(rules game.Rules) Strategy {
+	return &lowestFirstStrategy{rules: rules}
+}
+
+func (s *lowestFirstStrategy) Decide(msg protocol.OutboundMessage) []int {
+	switch msg.Command {
+	case protocol.Reorg:
+		return []int{0, 1, 2}
+	case protocol.PlayHandAndSeen:
+		return msg.Moves
+	}
+
+	cards := offeredCards(msg)
+	lowest := msg.Moves[0]
+	for _, m := range msg.Moves {
+		if s.rules.Value(cards[m].Rank) < s.rules.Value(cards[lowest].Rank) {
+			lowest = m
+		}
+	}
+	return sameRank(cards, msg.Moves, lowest)
+}
+
+type holdPowerCardsStrategy struct {
+	rules game.Rules
+}
+
+// NewHoldPowerCardsStrategy returns a Strategy that saves its power cards, such as wild and burn cards,
+// for when nothing else can be played. It puts its strongest cards face up,
+// to be played once its hand is gone, and plays every card of the lowest rank it can.
+func NewHoldPowerCardsStrategy(rules game.Rules) Strategy {
+	return &holdPowerCardsStrategy{rules: rules}
+}
+
+func (s *holdPowerCardsStrategy) Decide(msg protocol.OutboundMessage) []int {
+	cards := offeredCards(msg)
+
+	switch msg.Command {
+	case protocol.Reorg:
+		weakest := []int{}
+		for i := range cards {
+			weakest = append(weakest, i)
+		}
+		sort.SliceStable(weakest, func(i, j int) bool {
+			return s.strength(cards[weakest[i]]) < s.strength(cards[weakest[j]])
+		})
+		return weakest[:3]
+
+	case protocol.PlayHandAndSeen:
+		return msg.Moves
+	}
+
+	chosen := msg.Moves[0]
+	for _, m := range msg.Moves {
+		if s.strength(cards[m]) < s.strength(cards[chosen]) {
+			chosen = m
+		}
+	}
+	return sameRank(cards, msg.Moves, chosen)
+}
+
+// strength orders cards from weakest to strongest, with power cards strongest of all
+func (s *holdPowerCardsStrategy) strength(card deck.Card) int {
+	if s.rules.HasPower(card.Rank) {
+		return len(s.rules.Ranking)
+	}
+	return s.rules.Value(card.Rank)
+}
+
+type monteCarloStrategy struct {
+	opts game.SearchOpts
+	rng  *rand.Rand
+}
+
+// NewMonteCarloStrategy returns a GameStrategy that searches the game for its best decision,
+// playing each one out in many imagined games that match what the bot can see.
+// It falls back to keeping the cards it was dealt, or playing a legal card, when the game cannot be searched.
+func NewMonteCarloStrategy(opts game.SearchOpts) GameStrategy {
+	return &monteCarloStrategy{opts: opts, rng: rand.New(rand.NewSource(opts.Seed))}
+}
+
+func (s *monteCarloStrategy) DecideIn(g game.Game, msg protocol.OutboundMessage) []int {
+	opts := s.opts
+	opts.Seed = s.rng.Int63()
+
+	decision, err := game.Search(g, msg.PlayerID, opts)
+	if err == nil {
+		return decision
+	}
+	return s.Decide(msg)
+}
+
+// Decide makes the decision it falls back to when it cannot search the game
+func (s *monteCarloStrategy) Decide(msg protocol.OutboundMessage) []int {
+	switch msg.Command {
+	case protocol.Reorg:
+		return []int{0, 1, 2}
+	case protocol.PlayHandAndSeen:
+		return msg.Moves
+	case protocol.PlayUnseen:
+		return []int{0}
+	}
+	return msg.Moves[:1]
+}
@@ -0,0 +1,261 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/minaorangina/shed/deck"
+	"github.com/minaorangina/shed/game"
+	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
+)
+
+func TestStrategies(t *testing.T) {
+	rules := game.DefaultRules()
+
+	two := deck.NewCard(deck.Two, deck.Hearts)
+	ten := deck.NewCard(deck.Ten, deck.Clubs)
+	ace := deck.NewCard(deck.Ace, deck.Spades)
+	four := deck.NewCard(deck.Four, deck.Diamonds)
+	otherFour := deck.NewCard(deck.Four, deck.Clubs)
+	nine := deck.NewCard(deck.Nine, deck.Hearts)
+
+	tt := []struct {
+		name     string
+		strategy Strategy
+		msg      protocol.OutboundMessage
+		want     []int
+	}{
+		{
+			name:     "lowest first plays every card of the lowest rank",
+			strategy: NewLowestFirstStrategy(rules),
+			msg: protocol.OutboundMessage{
+				Command: protocol.PlayHand, Hand: []deck.Card{nine, four, ace, otherFour}, Moves: []int{0, 1, 2, 3},
+			},
+			want: []int{1, 3},
+		},
+		{
+			name:     "lowest first plays only cards that can be played",
+			strategy: NewLowestFirstStrategy(rules),
+			msg: protocol.OutboundMessage{
+				Command: protocol.PlaySeen, Seen: []deck.Card{four, nine, ace}, Moves: []int{1, 2},
+			},
+			want: []int{1},
+		},
+		{
+			name:     "lowest first keeps the cards it was dealt",
+			strategy: NewLowestFirstStrategy(rules),
+			msg: protocol.OutboundMessage{
+				Command: protocol.Reorg, Hand: []deck.Card{two, ten, ace}, Seen: []deck.Card{four, otherFour, nine},
+			},
+			want: []int{0, 1, 2},
+		},
+		{
+			name:     "holding power cards plays an ordinary card instead",
+			strategy: NewHoldPowerCardsStrategy(rules),
+			msg: protocol.OutboundMessage{
+				Command: protocol.PlayHand, Hand: []deck.Card{two, ten, ace}, Moves: []int{0, 1, 2},
+			},
+			want: []int{2},
+		},
+		{
+			name:     "holding power cards plays one when nothing else can be played",
+			strategy: NewHoldPowerCardsStrategy(rules),
+			msg: protocol.OutboundMessage{
+				Command: protocol.PlayHand, Hand: []deck.Card{four, ten, nine}, Moves: []int{1},
+			},
+			want: []int{1},
+		},
+		{
+			name:     "holding power cards puts the strongest cards face up",
+			strategy: NewHoldPowerCardsStrategy(rules),
+			msg: protocol.OutboundMessage{
+				Command: protocol.Reorg, Hand: []deck.Card{two, ten, ace}, Seen: []deck.Card{four, nine, otherFour},
+			},
+			want: []int{3, 5, 4},
+		},
+		{
+			name:     "every strategy plays hand and seen cards together",
+			strategy: NewRandomStrategy(1),
+			msg: protocol.OutboundMessage{
+				Command: protocol.PlayHandAndSeen, Hand: []deck.Card{four}, Seen: []deck.Card{otherFour, nine}, Moves: []int{0, 1},
+			},
+			want: []int{0, 1},
+		},
+		{
+			name:     "monte carlo plays a legal card when the game cannot be searched",
+			strategy: NewMonteCarloStrategy(game.SearchOpts{}),
+			msg: protocol.OutboundMessage{
+				PlayerID: "p1", Command: protocol.PlayHand, Hand: []deck.Card{four, nine}, Moves: []int{1},
+			},
+			want: []int{1},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			utils.AssertDeepEqual(t, tc.strategy.Decide(tc.msg), tc.want)
+		})
+	}
+
+	t.Run("random plays one card that can be played", func(t *testing.T) {
+		strategy := NewRandomStrategy(1)
+		msg := protocol.OutboundMessage{
+			Command: protocol.PlayHand, Hand: []deck.Card{four, nine, ace, two}, Moves: []int{1, 3},
+		}
+
+		for i := 0; i < 20; i++ {
+			decision := strategy.Decide(msg)
+			utils.AssertEqual(t, len(decision), 1)
+			utils.AssertEqualToOneOf(t, decision[0], 1, 3)
+		}
+	})
+
+	t.Run("random keeps three different cards in hand", func(t *testing.T) {
+		strategy := NewRandomStrategy(1)
+		msg := protocol.OutboundMessage{
+			Command: protocol.Reorg, Hand: []deck.Card{two, ten, ace}, Seen: []deck.Card{four, nine, otherFour},
+		}
+
+		for i := 0; i < 20; i++ {
+			decision := strategy.Decide(msg)
+			utils.AssertEqual(t, len(decision), 3)
+			utils.AssertEqual(t, len(map[int]bool{decision[0]: true, decision[1]: true, decision[2]: true}), 3)
+		}
+	})
+}
+
+func TestNewStrategyFor(t *testing.T) {
+	g := UndealtGame()
+
+	for _, difficulty := range []Difficulty{Easy, Medium, Hard} {
+		strategy, err := NewStrategyFor(difficulty, g, 1)
+		utils.AssertNoError(t, err)
+		utils.AssertNotNil(t, strategy)
+	}
+
+	_, err := NewStrategyFor("impossible", g, 1)
+	utils.AssertTrue(t, errors.Is(err, ErrUnknownDifficulty))
+}
+
+func TestBotPlayer(t *testing.T) {
+	t.Run("bots play a game to the end", func(t *testing.T) {
+		rules := game.DefaultRules()
+
+		// Given a game of bots
+		shed, err := game.ExistingShed(game.ShedOpts{Seed: 2, Rules: rules})
+		utils.AssertNoError(t, err)
+		ge, err := NewGameEngine(GameEngineOpts{GameID: "bot-game", Game: shed, SnapWindow: time.Millisecond})
+		utils.AssertNoError(t, err)
+
+		strategies := []Strategy{
+			NewRandomStrategy(1),
+			NewLowestFirstStrategy(rules),
+			NewHoldPowerCardsStrategy(rules),
+			NewMonteCarloStrategy(game.SearchOpts{Samples: 2, Seed: 1}),
+		}
+
+		// the first bot tells the test when the game is over
+		over := make(chan struct{}, 1)
+		for i, strategy := range strategies {
+			var bot Player = NewBotPlayer(BotPlayerOpts{
+				ID:       fmt.Sprintf("bot-%d", i),
+				Name:     fmt.Sprintf("Bot %d", i),
+				Strategy: strategy,
+				Engine:   ge,
+			})
+			if i == 0 {
+				bot = &gameOverSpy{Player: bot, over: over}
+			}
+			utils.AssertNoError(t, ge.AddPlayer(bot))
+		}
+
+		// When the game starts
+		ge.Receive(protocol.InboundMessage{PlayerID: "bot-0", Command: protocol.Start})
+
+		// Then the bots play until it is over
+		select {
+		case <-over:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the bots did not finish the game")
+		}
+	})
+
+	t.Run("decides again when the game rejects its decision", func(t *testing.T) {
+		// Given a game of bots, one of which chooses no cards on its first play
+		shed, err := game.ExistingShed(game.ShedOpts{Seed: 2, Rules: game.DefaultRules()})
+		utils.AssertNoError(t, err)
+		ge, err := NewGameEngine(GameEngineOpts{GameID: "bot-game", Game: shed, SnapWindow: time.Millisecond})
+		utils.AssertNoError(t, err)
+
+		fumbler := &fumblingStrategy{Strategy: NewRandomStrategy(1)}
+		over := make(chan struct{}, 1)
+		for i := 0; i < 3; i++ {
+			var strategy Strategy = NewRandomStrategy(int64(i))
+			if i == 0 {
+				strategy = fumbler
+			}
+			var bot Player = NewBotPlayer(BotPlayerOpts{ID: fmt.Sprintf("bot-%d", i), Strategy: strategy, Engine: ge})
+			if i == 0 {
+				bot = &gameOverSpy{Player: bot, over: over}
+			}
+			utils.AssertNoError(t, ge.AddPlayer(bot))
+		}
+
+		// When the game starts
+		ge.Receive(protocol.InboundMessage{PlayerID: "bot-0", Command: protocol.Start})
+
+		// Then the bot's rejected play is made again, and the game is played to the end
+		select {
+		case <-over:
+			utils.AssertTrue(t, fumbler.fumbled)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the bots did not finish the game")
+		}
+	})
+
+	t.Run("stops when the engine stops", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		ge := gameEngineWithPlayers()
+		NewBotPlayer(BotPlayerOpts{ID: "bot", Strategy: NewRandomStrategy(1), Engine: ge})
+
+		ge.Stop()
+
+		// neither the engine nor the bot is left waiting for a game that will not be played
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d goroutines are still running", runtime.NumGoroutine()-before)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	t.Run("decides in a copy of the game taken when it was asked", func(t *testing.T) {
+		ge := gameEngineWithPlayers()
+		strategy := &spyGameStrategy{games: make(chan game.Game, 1)}
+		bot := NewBotPlayer(BotPlayerOpts{ID: "bot", Strategy: strategy, Engine: ge})
+
+		utils.AssertNoError(t, bot.Send(protocol.OutboundMessage{PlayerID: "bot", Command: protocol.Reorg, ShouldRespond: true}))
+
+		select {
+		case g := <-strategy.games:
+			utils.AssertNotNil(t, g)
+			utils.AssertTrue(t, g != ge.Game())
+		case <-time.After(time.Second):
+			t.Fatal("the bot did not decide")
+		}
+	})
+
+	t.Run("remembers the cards it was last shown", func(t *testing.T) {
+		bot := NewBotPlayer(BotPlayerOpts{ID: "bot", Strategy: NewRandomStrategy(1), Engine: gameEngineWithPlayers()})
+		hand := []deck.Card{deck.NewCard(deck.Four, deck.Clubs)}
+
+		utils.AssertNoError(t, bot.Send(protocol.OutboundMessage{PlayerID: "bot", Command: protocol.Turn, Hand: hand}))
+
+		utils.AssertDeepEqual(t, bot.Cards().Hand, hand)
+	})
+}
@@ -31,15 +31,20 @@ func buildCardDisplayText(cards game.PlayerCards) string {
 	handText := fmt.Sprintf("In your hand, you have three cards 🤲\n")
 	seenText := fmt.Sprintf("On the table, there are three more cards \n")
 	unseenText := "Underneath those cards, there are three cards you can't see 🙈\n- ?\n- ?\n- ?\n"
-	for _, card := range cards.Seen {
-		seenText += "- " + card.String() + "\n"
-	}
-	for _, card := range cards.Hand {
-		handText += "- " + card.String() + "\n"
-	}
+	seenText += BuildCardsText(cards.Seen)
+	handText += BuildCardsText(cards.Hand)
 	return displayText + handText + "\n" + seenText + "\n" + unseenText
 }
 
+// BuildCardsText lists cards one per line
+func BuildCardsText(cards []deck.Card) string {
+	text := ""
+	for _, card := range cards {
+		text += "- " + card.String() + "\n"
+	}
+	return text
+}
+
 func buildReorgDisplayText(msg protocol.OutboundMessage, visibleCards []deck.Card) string {
 	displayText := "\nOk, choose the cards you wish to have in your hand\nExample: if you want cards A, C and F, type ACF (the order of the letters does not matter).\n\n"
 	cardsText := ""
@@ -10,7 +10,13 @@ import (
 	"github.com/minaorangina/shed/protocol"
 )
 
-var ErrNilGame = errors.New("game is nil")
+var (
+	ErrNilGame = errors.New("game is nil")
+	ErrStopped = errors.New("game engine has stopped")
+)
+
+// defaultSnapWindow is how long the engine waits for more snaps after the first
+const defaultSnapWindow = 250 * time.Millisecond
 
 // PlayState represents the state of the current game
 // idle -> no game play (pre game and post game)
@@ -47,6 +53,10 @@ type GameEngine interface {
 	Receive(protocol.InboundMessage)
 	PlayState() PlayState
 	Game() gm.Game
+	Inspect(func(gm.Game))
+	Seed() int64
+	Stop()
+	Done() <-chan struct{}
 }
 
 // gameEngine represents the engine of the game
@@ -61,6 +71,11 @@ type gameEngine struct {
 	outboundCh               chan []protocol.OutboundMessage
 	gameCh                   chan []protocol.InboundMessage
 	game                     gm.Game
+	gameMu                   sync.Mutex // held while the game is being played on, so that it can be read from elsewhere
+	seed                     int64
+	snapWindow               time.Duration
+	done                     chan struct{}
+	stopOnce                 sync.Once
 }
 
 // GameEngineOpts represents options for constructing a new GameEngine
@@ -74,6 +89,13 @@ type GameEngineOpts struct {
 	GameCh                   chan []protocol.InboundMessage
 	PlayState                PlayState
 	Game                     gm.Game
+	// Seed is the seed the Game was constructed with, kept so that
+	// the game can be reproduced later.
+	Seed int64
+	// SnapWindow is how long to wait for other players' snaps after the first arrives,
+	// so that near-simultaneous snaps are resolved by the game in seating order,
+	// rather than by whichever reached the server first. Defaults to 250ms.
+	SnapWindow time.Duration
 }
 
 // NewGameEngine constructs a new GameEngine
@@ -93,6 +115,9 @@ func NewGameEngine(opts GameEngineOpts) (*gameEngine, error) {
 	if opts.GameCh == nil {
 		opts.GameCh = make(chan []protocol.InboundMessage)
 	}
+	if opts.SnapWindow == 0 {
+		opts.SnapWindow = defaultSnapWindow
+	}
 	engine := &gameEngine{
 		id:           opts.GameID,
 		creatorID:    opts.CreatorID,
@@ -104,6 +129,9 @@ func NewGameEngine(opts GameEngineOpts) (*gameEngine, error) {
 		gameCh:       opts.GameCh,
 		playState:    opts.PlayState,
 		game:         opts.Game,
+		seed:         opts.Seed,
+		snapWindow:   opts.SnapWindow,
+		done:         make(chan struct{}),
 	}
 
 	// Listen for websocket connections
@@ -120,7 +148,9 @@ func (ge *gameEngine) Start() error {
 	if ge.game == nil {
 		return ErrNilGame
 	}
+	ge.gameMu.Lock()
 	err := ge.game.Start(ge.players.Info())
+	ge.gameMu.Unlock()
 	if err != nil {
 		return err
 	}
@@ -133,18 +163,26 @@ func (ge *gameEngine) Start() error {
 }
 
 func (ge *gameEngine) Play() {
-	// this will need some way to shutdown gracefully
-	for inbound := range ge.gameCh {
+	for {
+		var inbound []protocol.InboundMessage
+		select {
+		case inbound = <-ge.gameCh:
+		case <-ge.done:
+			return
+		}
+
 		var (
 			outbound []protocol.OutboundMessage
 			err      error
 		)
 
+		ge.gameMu.Lock()
 		if len(inbound) == 0 {
 			outbound, err = ge.game.Next()
 		} else {
 			outbound, err = ge.game.ReceiveResponse(inbound)
 		}
+		ge.gameMu.Unlock()
 		if err != nil {
 			log.Printf("error: %s\n%v", err.Error(), outbound)
 		}
@@ -166,8 +204,97 @@ func (ge *gameEngine) Listen() {
 		expectedCommand: protocol.Reorg,
 	}
 
+	// The game handles one batch of messages at a time, and replies on outboundCh.
+	// Until it has replied, anything else for the game waits in the queue,
+	// as sending to it again would block both the engine and the game.
+	var (
+		busy  bool
+		queue []protocol.InboundMessage
+	)
+	toGame := func(msgs []protocol.InboundMessage) {
+		busy = true
+		ge.sendToGame(msgs)
+	}
+
+	// snaps are collected for a short window, then queued for the game together
+	var (
+		snaps      []protocol.InboundMessage
+		snapWindow <-chan time.Time
+	)
+	flushSnaps := func() {
+		queue = append(queue, snaps...)
+		snaps, snapWindow = nil, nil
+	}
+
+	// undo votes are sent to the game together, so that it can tell when everyone agrees
+	var undoVotes []protocol.InboundMessage
+
+	handle := func(msg protocol.InboundMessage) {
+		// Ignore messages that are not expected
+		if !ge.game.AcceptsCommand(msg.Command) {
+			log.Printf("lgr: unexpected cmd %s, ignoring\n", msg.Command)
+			return
+		}
+
+		switch msg.Command {
+		case protocol.Reorg:
+			commTracker.mu.Lock()
+			commTracker.messages = append(commTracker.messages, msg)
+			// send back
+			commTracker.mu.Unlock()
+
+			if len(commTracker.messages) == len(ge.Players()) {
+				commTracker.mu.Lock()
+				log.Printf("lgr %s: all players have reorg'd", time.Now().Format(time.StampMilli))
+				toGame(commTracker.messages)
+
+				commTracker.messages = []protocol.InboundMessage{}
+				commTracker.expectedCommand = protocol.Null
+
+				commTracker.mu.Unlock()
+			}
+
+		case protocol.Undo:
+			undoVotes = addVote(undoVotes, msg)
+			toGame(append([]protocol.InboundMessage{}, undoVotes...))
+
+		default:
+			// votes are for the move before this one
+			undoVotes = nil
+			toGame([]protocol.InboundMessage{msg}) // handle failures
+		}
+	}
+
+	// drain passes queued messages to the game, in the order they arrived,
+	// until it is busy again
+	drain := func() {
+		for !busy && len(queue) > 0 {
+			if queue[0].Command == protocol.Snap {
+				n := 1
+				for n < len(queue) && queue[n].Command == protocol.Snap {
+					n++
+				}
+				batch := queue[:n:n]
+				queue = queue[n:]
+				toGame(batch)
+				continue
+			}
+
+			msg := queue[0]
+			queue = queue[1:]
+			handle(msg)
+		}
+	}
+
 	for {
 		select {
+		case <-ge.done:
+			return
+
+		case <-snapWindow:
+			flushSnaps()
+			drain()
+
 		case joiner := <-ge.registerCh:
 			ps := ge.Players()
 			ge.players = AppendPlayer(ps, joiner)
@@ -191,10 +318,19 @@ func (ge *gameEngine) Listen() {
 			}
 
 		case msgs := <-ge.outboundCh:
+			busy = false
 			ge.messagePlayers(msgs)
+			for _, m := range msgs {
+				if m.Command == protocol.Undo {
+					undoVotes = nil
+					break
+				}
+			}
 			if !ge.game.GameOver() && ge.game.AwaitingResponse() == protocol.Null {
-				ge.sendToGame(nil)
+				toGame(nil)
+				continue
 			}
+			drain()
 
 		case msg := <-ge.inboundCh:
 			if msg.Command == protocol.Start {
@@ -215,40 +351,36 @@ func (ge *gameEngine) Listen() {
 				}
 				// small delay before game starts
 				<-time.After(time.Millisecond * 400)
-				ge.sendToGame(nil)
+				toGame(nil)
 
 				continue
 			}
 
-			// Ignore messages that are not expected
-			if msg.Command != ge.game.AwaitingResponse() {
-				log.Printf("lgr: unexpected cmd %s, ignoring\n", msg.Command)
+			if msg.Command == protocol.Snap {
+				if snapWindow == nil {
+					snapWindow = time.After(ge.snapWindow)
+				}
+				snaps = append(snaps, msg)
 				continue
 			}
 
-			switch msg.Command {
-			case protocol.Reorg:
-				commTracker.mu.Lock()
-				commTracker.messages = append(commTracker.messages, msg)
-				// send back
-				commTracker.mu.Unlock()
-
-				if len(commTracker.messages) == len(ge.Players()) {
-					commTracker.mu.Lock()
-					log.Printf("lgr %s: all players have reorg'd", time.Now().Format(time.StampMilli))
-					ge.sendToGame(commTracker.messages)
-
-					commTracker.messages = []protocol.InboundMessage{}
-					commTracker.expectedCommand = protocol.Null
-
-					commTracker.mu.Unlock()
-				}
+			// Snaps that arrived first are resolved first
+			flushSnaps()
+			queue = append(queue, msg)
+			drain()
+		}
+	}
+}
 
-			default:
-				ge.sendToGame([]protocol.InboundMessage{msg}) // handle failures
-			}
+// addVote adds the message to the votes, replacing any earlier vote from the same player
+func addVote(votes []protocol.InboundMessage, msg protocol.InboundMessage) []protocol.InboundMessage {
+	for i, v := range votes {
+		if v.PlayerID == msg.PlayerID {
+			votes = append(votes[:i], votes[i+1:]...)
+			break
 		}
 	}
+	return append(votes, msg)
 }
 
 func (ge *gameEngine) messagePlayers(msgs []protocol.OutboundMessage) {
@@ -261,16 +393,26 @@ func (ge *gameEngine) messagePlayers(msgs []protocol.OutboundMessage) {
 }
 
 func (ge *gameEngine) sendToGame(msgs []protocol.InboundMessage) {
-	ge.gameCh <- msgs
+	select {
+	case ge.gameCh <- msgs:
+	case <-ge.done:
+	}
 }
 
 func (ge *gameEngine) Send(msgs []protocol.OutboundMessage) {
-	ge.outboundCh <- msgs
+	select {
+	case ge.outboundCh <- msgs:
+	case <-ge.done:
+	}
 }
 
-// Receive forwards protocol.InboundMessages from Players for sorting
+// Receive forwards protocol.InboundMessages from Players for sorting.
+// Messages for an engine that has stopped are dropped.
 func (ge *gameEngine) Receive(msg protocol.InboundMessage) {
-	ge.inboundCh <- msg
+	select {
+	case ge.inboundCh <- msg:
+	case <-ge.done:
+	}
 }
 
 // AddPlayer adds a player to a game
@@ -278,12 +420,32 @@ func (ge *gameEngine) AddPlayer(p Player) error {
 	if ge.playState != Idle {
 		return errors.New("cannot add player - game has started")
 	}
-	ge.registerCh <- p
-	return nil
+	select {
+	case ge.registerCh <- p:
+		return nil
+	case <-ge.done:
+		return ErrStopped
+	}
 }
 
 func (ge *gameEngine) RemovePlayer(p Player) {
-	ge.unregisterCh <- p
+	select {
+	case ge.unregisterCh <- p:
+	case <-ge.done:
+	}
+}
+
+// Stop stops the engine and the game it plays, and tells its players with Done.
+// It can be called more than once.
+func (ge *gameEngine) Stop() {
+	ge.stopOnce.Do(func() {
+		close(ge.done)
+	})
+}
+
+// Done is closed once the engine has stopped
+func (ge *gameEngine) Done() <-chan struct{} {
+	return ge.done
 }
 
 func (ge *gameEngine) ID() string {
@@ -305,3 +467,15 @@ func (ge *gameEngine) PlayState() PlayState {
 func (ge *gameEngine) Game() gm.Game {
 	return ge.game
 }
+
+// Inspect calls f with the game once no move is being made on it,
+// so that the game can be read while it is played. f must not keep the game.
+func (ge *gameEngine) Inspect(f func(gm.Game)) {
+	ge.gameMu.Lock()
+	defer ge.gameMu.Unlock()
+	f(ge.game)
+}
+
+func (ge *gameEngine) Seed() int64 {
+	return ge.seed
+}
@@ -154,7 +154,10 @@ func TestGameEngineStart(t *testing.T) {
 			},
 			{
 				"too many players",
-				namesToPlayers([]string{"Ada", "Katherine", "Grace", "Hedy", "Marlyn"}),
+				namesToPlayers([]string{
+					"Ada", "Katherine", "Grace", "Hedy", "Marlyn",
+					"Radia", "Frances", "Barbara", "Margaret",
+				}),
 				game.ErrTooManyPlayers,
 			},
 			{
@@ -162,10 +165,15 @@ func TestGameEngineStart(t *testing.T) {
 				namesToPlayers([]string{"Ada", "Katherine", "Grace", "Hedy"}),
 				nil,
 			},
+			{
+				"two decks' worth",
+				namesToPlayers([]string{"Ada", "Katherine", "Grace", "Hedy", "Marlyn", "Radia"}),
+				nil,
+			},
 		}
 
 		for _, et := range testsShouldError {
-			ge, err := NewGameEngine(GameEngineOpts{Players: et.input, Game: game.ExistingShed(game.ShedOpts{})})
+			ge, err := NewGameEngine(GameEngineOpts{Players: et.input, Game: UndealtGame()})
 			utils.AssertNoError(t, err)
 			utils.AssertNotNil(t, ge)
 			err = ge.Start()
@@ -204,3 +212,176 @@ func TestGameEngineReceiveMessage(t *testing.T) {
 		utils.AssertEqual(t, ge.playState, InProgress)
 	})
 }
+
+func TestGameEngineSnaps(t *testing.T) {
+	snapWindow := 20 * time.Millisecond
+
+	t.Run("snaps arriving together are sent to the game together", func(t *testing.T) {
+		gameCh := make(chan []protocol.InboundMessage)
+		ge, err := NewGameEngine(GameEngineOpts{
+			Players:    SomePlayers(),
+			Game:       NewSpyGame(),
+			GameCh:     gameCh,
+			SnapWindow: snapWindow,
+		})
+		utils.AssertNoError(t, err)
+
+		ge.Receive(protocol.InboundMessage{PlayerID: "p2", Command: protocol.Snap, Decision: []int{0}})
+		ge.Receive(protocol.InboundMessage{PlayerID: "p3", Command: protocol.Snap, Decision: []int{1}})
+
+		utils.Within(t, gameEngineTestTimeout, func() {
+			snaps := <-gameCh
+			utils.AssertEqual(t, len(snaps), 2)
+			utils.AssertEqual(t, snaps[0].PlayerID, "p2")
+			utils.AssertEqual(t, snaps[1].PlayerID, "p3")
+		})
+	})
+
+	t.Run("snaps are sent to the game before later messages", func(t *testing.T) {
+		gameCh := make(chan []protocol.InboundMessage)
+		spy := NewSpyGame()
+		spy.awaiting = protocol.PlayHand
+		ge, err := NewGameEngine(GameEngineOpts{
+			Players:    SomePlayers(),
+			Game:       spy,
+			GameCh:     gameCh,
+			SnapWindow: time.Hour,
+		})
+		utils.AssertNoError(t, err)
+
+		ge.Receive(protocol.InboundMessage{PlayerID: "p2", Command: protocol.Snap, Decision: []int{0}})
+		go ge.Receive(protocol.InboundMessage{PlayerID: "p1", Command: protocol.PlayHand})
+
+		utils.Within(t, gameEngineTestTimeout, func() {
+			snaps := <-gameCh
+			utils.AssertEqual(t, len(snaps), 1)
+			utils.AssertEqual(t, snaps[0].Command, protocol.Snap)
+
+			// the game replies before it is sent anything else
+			ge.Send(nil)
+
+			next := <-gameCh
+			utils.AssertEqual(t, next[0].PlayerID, "p1")
+		})
+	})
+
+	t.Run("the game keeps going when a message follows a snap", func(t *testing.T) {
+		spy := NewSpyGame()
+		spy.awaiting = protocol.PlayHand
+		spy.received = make(chan []protocol.InboundMessage, 3)
+		ge, err := NewGameEngine(GameEngineOpts{
+			Players:    SomePlayers(),
+			Game:       spy,
+			SnapWindow: time.Hour,
+		})
+		utils.AssertNoError(t, err)
+		utils.AssertNoError(t, ge.Start())
+
+		go func() {
+			ge.Receive(protocol.InboundMessage{PlayerID: "p2", Command: protocol.Snap, Decision: []int{0}})
+			ge.Receive(protocol.InboundMessage{PlayerID: "p1", Command: protocol.PlayHand})
+			ge.Receive(protocol.InboundMessage{PlayerID: "p1", Command: protocol.PlayHand})
+		}()
+
+		utils.Within(t, gameEngineTestTimeout, func() {
+			utils.AssertEqual(t, (<-spy.received)[0].Command, protocol.Snap)
+			utils.AssertEqual(t, (<-spy.received)[0].Command, protocol.PlayHand)
+			utils.AssertEqual(t, (<-spy.received)[0].Command, protocol.PlayHand)
+		})
+	})
+}
+
+func TestGameEnginePickUpPile(t *testing.T) {
+	t.Run("the next turn starts once a player who chose to pick up the pile acknowledges it", func(t *testing.T) {
+		shed, err := game.ExistingShed(game.ShedOpts{Seed: 2, Rules: game.DefaultRules()})
+		utils.AssertNoError(t, err)
+		ge, err := NewGameEngine(GameEngineOpts{Game: shed, SnapWindow: time.Millisecond})
+		utils.AssertNoError(t, err)
+
+		// Given a game of bots, one of which picks up the pile rather than play onto it
+		picker := &pickUpSpy{ge: ge, nextTurn: make(chan struct{}, 1)}
+		for i := 0; i < 3; i++ {
+			var bot Player = NewBotPlayer(BotPlayerOpts{
+				ID:       fmt.Sprintf("bot-%d", i),
+				Strategy: NewRandomStrategy(int64(i)),
+				Engine:   ge,
+			})
+			if i == 0 {
+				picker.Player = bot
+				bot = picker
+			}
+			utils.AssertNoError(t, ge.AddPlayer(bot))
+		}
+
+		// When the game is played
+		ge.Receive(protocol.InboundMessage{PlayerID: "bot-0", Command: protocol.Start})
+
+		// Then the next player's turn starts after the bot has picked up the pile
+		select {
+		case <-picker.nextTurn:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the next turn did not start")
+		}
+	})
+}
+
+func TestGameEngineUndoVotes(t *testing.T) {
+	newEngine := func(t *testing.T) (*gameEngine, chan []protocol.InboundMessage) {
+		gameCh := make(chan []protocol.InboundMessage)
+		spy := NewSpyGame()
+		spy.awaiting = protocol.PlayHand
+		ge, err := NewGameEngine(GameEngineOpts{
+			Players: SomePlayers(),
+			Game:    spy,
+			GameCh:  gameCh,
+		})
+		utils.AssertNoError(t, err)
+		return ge, gameCh
+	}
+
+	// received returns what the game was sent, and replies with nothing
+	received := func(ge *gameEngine, gameCh chan []protocol.InboundMessage) []protocol.InboundMessage {
+		msgs := <-gameCh
+		ge.Send(nil)
+		return msgs
+	}
+
+	playerIDs := func(msgs []protocol.InboundMessage) []string {
+		ids := []string{}
+		for _, m := range msgs {
+			ids = append(ids, m.PlayerID)
+		}
+		return ids
+	}
+
+	t.Run("votes are sent to the game together", func(t *testing.T) {
+		ge, gameCh := newEngine(t)
+
+		utils.Within(t, gameEngineTestTimeout, func() {
+			go ge.Receive(protocol.InboundMessage{PlayerID: "p1", Command: protocol.Undo})
+			utils.AssertDeepEqual(t, playerIDs(received(ge, gameCh)), []string{"p1"})
+
+			go ge.Receive(protocol.InboundMessage{PlayerID: "p2", Command: protocol.Undo})
+			utils.AssertDeepEqual(t, playerIDs(received(ge, gameCh)), []string{"p1", "p2"})
+
+			// a second vote from the same player is not counted twice
+			go ge.Receive(protocol.InboundMessage{PlayerID: "p1", Command: protocol.Undo})
+			utils.AssertDeepEqual(t, playerIDs(received(ge, gameCh)), []string{"p2", "p1"})
+		})
+	})
+
+	t.Run("votes are forgotten after another message", func(t *testing.T) {
+		ge, gameCh := newEngine(t)
+
+		utils.Within(t, gameEngineTestTimeout, func() {
+			go ge.Receive(protocol.InboundMessage{PlayerID: "p1", Command: protocol.Undo})
+			received(ge, gameCh)
+
+			go ge.Receive(protocol.InboundMessage{PlayerID: "p2", Command: protocol.PlayHand})
+			received(ge, gameCh)
+
+			go ge.Receive(protocol.InboundMessage{PlayerID: "p3", Command: protocol.Undo})
+			utils.AssertDeepEqual(t, playerIDs(received(ge, gameCh)), []string{"p3"})
+		})
+	})
+}
@@ -11,6 +11,10 @@ import (
 type SpyGame struct {
 	startCalled bool
 	mu          *sync.Mutex
+	// awaiting is the command the game waits for
+	awaiting protocol.Cmd
+	// received, if set, is sent each batch of messages the game receives
+	received chan []protocol.InboundMessage
 }
 
 func NewSpyGame() *SpyGame {
@@ -18,7 +22,11 @@ func NewSpyGame() *SpyGame {
 }
 
 func (g *SpyGame) AwaitingResponse() protocol.Cmd {
-	return protocol.Null
+	return g.awaiting
+}
+
+func (g *SpyGame) AcceptsCommand(cmd protocol.Cmd) bool {
+	return cmd == g.AwaitingResponse() || cmd == protocol.Undo
 }
 
 func (g *SpyGame) Start(info []protocol.Player) error {
@@ -33,6 +41,9 @@ func (g *SpyGame) Next() ([]protocol.OutboundMessage, error) {
 }
 
 func (g *SpyGame) ReceiveResponse(messages []protocol.InboundMessage) ([]protocol.OutboundMessage, error) {
+	if g.received != nil {
+		g.received <- messages
+	}
 	return nil, nil
 }
 
@@ -40,6 +51,10 @@ func (g *SpyGame) GameOver() bool {
 	return false
 }
 
+func (g *SpyGame) Events() []game.Event {
+	return nil
+}
+
 func namesToPlayers(names []string) Players {
 	ps := []Player{}
 	for _, n := range names {
@@ -68,12 +83,94 @@ func playerInfoToPlayers(playerInfo []protocol.Player) Players {
 	return NewPlayers(ps...)
 }
 
+// gameOverSpy is a Player that signals when it is told the game is over
+type gameOverSpy struct {
+	Player
+	over chan struct{}
+}
+
+func (p *gameOverSpy) Send(msg protocol.OutboundMessage) error {
+	if msg.Command == protocol.GameOver {
+		select {
+		case p.over <- struct{}{}:
+		default:
+		}
+	}
+	return p.Player.Send(msg)
+}
+
+// pickUpSpy is a Player that chooses to pick up the pile the first time it could play onto it,
+// and signals once it is told that another player's turn has started
+type pickUpSpy struct {
+	Player
+	ge       GameEngine
+	picked   bool
+	nextTurn chan struct{}
+}
+
+func (p *pickUpSpy) Send(msg protocol.OutboundMessage) error {
+	if p.picked && msg.CurrentTurn.PlayerID != "" && msg.CurrentTurn.PlayerID != p.ID() {
+		select {
+		case p.nextTurn <- struct{}{}:
+		default:
+		}
+	}
+
+	switch msg.Command {
+	case protocol.PlayHand, protocol.PlaySeen, protocol.PlayHandAndSeen:
+		if !p.picked && msg.ShouldRespond && len(msg.Pile) > 0 {
+			p.picked = true
+			go p.ge.Receive(protocol.InboundMessage{PlayerID: p.ID(), Command: protocol.PickUpPile})
+			return nil
+		}
+	}
+	return p.Player.Send(msg)
+}
+
+// spyGameStrategy passes on the game it is given to decide in
+type spyGameStrategy struct {
+	games chan game.Game
+}
+
+func (s *spyGameStrategy) Decide(msg protocol.OutboundMessage) []int {
+	return []int{0, 1, 2}
+}
+
+func (s *spyGameStrategy) DecideIn(g game.Game, msg protocol.OutboundMessage) []int {
+	s.games <- g
+	return s.Decide(msg)
+}
+
+// fumblingStrategy chooses no cards the first time it is asked to play from its hand,
+// which the game rejects, then decides as its Strategy does
+type fumblingStrategy struct {
+	Strategy
+	fumbled bool
+}
+
+func (s *fumblingStrategy) Decide(msg protocol.OutboundMessage) []int {
+	if msg.Command == protocol.PlayHand && !s.fumbled {
+		s.fumbled = true
+		return []int{}
+	}
+	return s.Strategy.Decide(msg)
+}
+
+// UndealtGame returns a game of Shed waiting for its players
+func UndealtGame() game.Game {
+	g, err := game.ExistingShed(game.ShedOpts{})
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
 func gameEngineWithPlayers() GameEngine {
 	ge, _ := NewGameEngine(GameEngineOpts{
 		GameID:    "theid",
 		CreatorID: "some-user-id",
 		Players:   SomePlayers(),
-		Game:      game.ExistingShed(game.ShedOpts{}),
+		Game:      UndealtGame(),
 	})
 	return ge
 }
@@ -0,0 +1,80 @@
+package game
+
+import (
+	"time"
+
+	"github.com/minaorangina/shed/deck"
+)
+
+// EventKind is the kind of thing that happened in a game
+type EventKind string
+
+const (
+	EventDealt          EventKind = "dealt"          // a group of cards was dealt to a player
+	EventReorganised    EventKind = "reorganised"    // a player chose their hand before play began
+	EventPlayed         EventKind = "played"         // a player played cards from a group onto the pile
+	EventSnapped        EventKind = "snapped"        // a player played cards from their hand out of turn
+	EventDrew           EventKind = "drew"           // a player took cards from the deck into their hand
+	EventPickedUpPile   EventKind = "pickedUpPile"   // a player picked up the pile
+	EventBurned         EventKind = "burned"         // the pile was burned
+	EventUnseenSuccess  EventKind = "unseenSuccess"  // a player turned over an unseen card that can be played
+	EventUnseenFailure  EventKind = "unseenFailure"  // a player turned over an unseen card that cannot be played
+	EventPlayerFinished EventKind = "playerFinished" // a player got rid of all of their cards
+	EventRanked         EventKind = "ranked"         // a stalemate ended the game, and the player was placed by how few cards they held
+	EventGameOver       EventKind = "gameOver"       // the game ended. The player is the one left with cards, if the game was not drawn
+	EventUndone         EventKind = "undone"         // a player's last move was taken back
+)
+
+// CardGroup names one of a player's groups of cards
+type CardGroup string
+
+const (
+	HandGroup   CardGroup = "hand"
+	SeenGroup   CardGroup = "seen"
+	UnseenGroup CardGroup = "unseen"
+)
+
+// Event is a record of something that happened in a game.
+// Events are numbered in the order they happened, starting from one.
+type Event struct {
+	Seq      int         `json:"seq"`
+	Time     time.Time   `json:"time"`
+	Kind     EventKind   `json:"kind"`
+	PlayerID string      `json:"playerID,omitempty"`
+	Group    CardGroup   `json:"group,omitempty"`
+	Cards    []deck.Card `json:"cards,omitempty"`
+}
+
+// Events returns every event in the game so far, oldest first
+func (s *shed) Events() []Event {
+	return copyEvents(s.events)
+}
+
+// emit records that something happened to the player's cards
+func (s *shed) emit(kind EventKind, playerID string, group CardGroup, cards []deck.Card) {
+	e := Event{
+		Seq:      len(s.events) + 1,
+		Time:     time.Now().UTC(),
+		Kind:     kind,
+		PlayerID: playerID,
+		Group:    group,
+	}
+	if len(cards) > 0 {
+		e.Cards = copyCards(cards)
+	}
+	s.events = append(s.events, e)
+}
+
+func copyEvents(events []Event) []Event {
+	if events == nil {
+		return nil
+	}
+	copied := make([]Event, len(events))
+	for i, e := range events {
+		copied[i] = e
+		if e.Cards != nil {
+			copied[i].Cards = copyCards(e.Cards)
+		}
+	}
+	return copied
+}
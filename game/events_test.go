@@ -0,0 +1,158 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/minaorangina/shed/deck"
+	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
+)
+
+func TestEvents(t *testing.T) {
+	t.Run("records a whole game in order", func(t *testing.T) {
+		// Given a game played to the end
+		game, err := NewShed(ShedOpts{Players: threePlayers(), Seed: 7})
+		utils.AssertNoError(t, err)
+		playGame(t, game, 3000)
+		utils.AssertTrue(t, game.GameOver())
+
+		events := game.Events()
+
+		// Then events are numbered in order, and never go back in time
+		for i, e := range events {
+			utils.AssertEqual(t, e.Seq, i+1)
+			utils.AssertTrue(t, !e.Time.IsZero())
+			if i > 0 {
+				utils.AssertTrue(t, !e.Time.Before(events[i-1].Time))
+			}
+		}
+
+		// And every player is dealt three groups of cards, then reorganises them
+		for i, p := range game.PlayerInfo {
+			for j, group := range []CardGroup{HandGroup, SeenGroup, UnseenGroup} {
+				e := events[3*i+j]
+				utils.AssertEqual(t, e.Kind, EventDealt)
+				utils.AssertEqual(t, e.PlayerID, p.PlayerID)
+				utils.AssertEqual(t, e.Group, group)
+				utils.AssertEqual(t, len(e.Cards), numCardsInGroup)
+			}
+			utils.AssertTrue(t, containsEvent(events, EventReorganised, p.PlayerID))
+		}
+
+		// And every player but the last finishes, and the last loses
+		loser := game.FinishedPlayers[len(game.FinishedPlayers)-1]
+		for _, p := range game.FinishedPlayers[:len(game.FinishedPlayers)-1] {
+			utils.AssertTrue(t, containsEvent(events, EventPlayerFinished, p.PlayerID))
+		}
+		utils.AssertEqual(t, containsEvent(events, EventPlayerFinished, loser.PlayerID), false)
+		utils.AssertEqual(t, events[len(events)-1].Kind, EventGameOver)
+		utils.AssertEqual(t, events[len(events)-1].PlayerID, loser.PlayerID)
+
+		// And the cards each player holds can be worked out from the events
+		held := map[string]int{}
+		for _, e := range events {
+			switch e.Kind {
+			case EventDealt, EventDrew, EventPickedUpPile:
+				held[e.PlayerID] += len(e.Cards)
+			case EventPlayed, EventSnapped:
+				held[e.PlayerID] -= len(e.Cards)
+			}
+		}
+		for id, pc := range game.PlayerCards {
+			utils.AssertEqual(t, held[id], len(pc.Hand)+len(pc.Seen)+len(pc.Unseen))
+		}
+	})
+
+	t.Run("records playing cards from a group", func(t *testing.T) {
+		ps := twoPlayers()
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearCards,
+			Deck:          deck.Deck{},
+			Pile:          []deck.Card{deck.NewCard(deck.Four, deck.Hearts)},
+			Players:       ps,
+			CurrentPlayer: ps[0],
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards(nil, []deck.Card{
+					deck.NewCard(deck.Six, deck.Clubs),
+					deck.NewCard(deck.Six, deck.Spades),
+				}, cardsOf(deck.Hearts, deck.Five, deck.Six, deck.Jack), nil),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+			},
+		}))
+		utils.AssertNoError(t, err)
+
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p1", Command: protocol.PlaySeen, Decision: []int{1, 0},
+		}})
+		utils.AssertNoError(t, err)
+
+		events := game.Events()
+		utils.AssertEqual(t, len(events), 1)
+		utils.AssertEqual(t, events[0].Kind, EventPlayed)
+		utils.AssertEqual(t, events[0].PlayerID, "p1")
+		utils.AssertEqual(t, events[0].Group, SeenGroup)
+		utils.AssertDeepEqual(t, events[0].Cards, game.Pile[1:])
+	})
+
+	t.Run("records an unseen card that cannot be played", func(t *testing.T) {
+		ps := twoPlayers()
+		ace := deck.NewCard(deck.Ace, deck.Hearts)
+		four := deck.NewCard(deck.Four, deck.Clubs)
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearCards,
+			Deck:          deck.Deck{},
+			Pile:          []deck.Card{ace},
+			Players:       ps,
+			CurrentPlayer: ps[0],
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards(nil, nil, []deck.Card{four}, nil),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+			},
+		}))
+		utils.AssertNoError(t, err)
+
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p1", Command: protocol.PlayUnseen, Decision: []int{0},
+		}})
+		utils.AssertNoError(t, err)
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{PlayerID: "p1", Command: protocol.UnseenFailure}})
+		utils.AssertNoError(t, err)
+
+		kinds := []EventKind{}
+		for _, e := range game.Events() {
+			kinds = append(kinds, e.Kind)
+		}
+		utils.AssertDeepEqual(t, kinds, []EventKind{EventUnseenFailure, EventPlayed, EventPickedUpPile})
+
+		events := game.Events()
+		utils.AssertEqual(t, events[0].Cards[0].Rank, deck.Four)
+		utils.AssertEqual(t, events[1].Group, UnseenGroup)
+		utils.AssertEqual(t, len(events[2].Cards), 2)
+	})
+
+	t.Run("cannot be changed from outside the game", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers(), Seed: 3})
+		utils.AssertNoError(t, err)
+
+		events := game.Events()
+		events[0].Kind = EventBurned
+		events[0].Cards[0] = deck.NewCard(deck.Ten, deck.Spades)
+
+		utils.AssertEqual(t, game.Events()[0].Kind, EventDealt)
+		utils.AssertNotDeepEqual(t, game.Events()[0].Cards, events[0].Cards)
+	})
+}
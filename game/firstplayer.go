@@ -0,0 +1,125 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/minaorangina/shed/deck"
+	"github.com/minaorangina/shed/protocol"
+)
+
+// FirstPlayerPolicy decides who takes the first turn, once players have reorganised their cards.
+// If the policy cannot choose a player, the first player is chosen at random.
+type FirstPlayerPolicy int
+
+const (
+	// RandomFirstPlayer chooses the first player at random
+	RandomFirstPlayer FirstPlayerPolicy = iota
+	// LowestCardFirstPlayer chooses the player holding the lowest ranked card in their hand,
+	// with ties broken by suit (Clubs, Diamonds, Hearts, Spades), then by seating order.
+	LowestCardFirstPlayer
+	// PreviousLoserFirstPlayer chooses the player who lost the previous game.
+	// Games played by this policy must be told who that was.
+	PreviousLoserFirstPlayer
+	// CreatorFirstPlayer chooses the player who created the game
+	CreatorFirstPlayer
+)
+
+var firstPlayerPolicyNames = map[FirstPlayerPolicy]string{
+	RandomFirstPlayer:        "random",
+	LowestCardFirstPlayer:    "lowestCard",
+	PreviousLoserFirstPlayer: "previousLoser",
+	CreatorFirstPlayer:       "creator",
+}
+
+func (p FirstPlayerPolicy) String() string {
+	if name, ok := firstPlayerPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("FirstPlayerPolicy(%d)", int(p))
+}
+
+// MarshalText encodes the policy by name
+func (p FirstPlayerPolicy) MarshalText() ([]byte, error) {
+	if _, ok := firstPlayerPolicyNames[p]; !ok {
+		return nil, fmt.Errorf("%w: unknown first player policy %d", ErrInvalidRules, p)
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a policy from its name
+func (p *FirstPlayerPolicy) UnmarshalText(text []byte) error {
+	for policy, name := range firstPlayerPolicyNames {
+		if name == string(text) {
+			*p = policy
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown first player policy %q", ErrInvalidRules, text)
+}
+
+// validateFirstPlayer checks the rules' policy has what it needs to choose the first player
+func validateFirstPlayer(rules Rules, previousLoser string) error {
+	if rules.FirstPlayer == PreviousLoserFirstPlayer && previousLoser == "" {
+		return fmt.Errorf("%w: %s first player policy without a previous loser", ErrInvalidRules, rules.FirstPlayer)
+	}
+	return nil
+}
+
+// chooseFirstPlayer makes the player chosen by the rules the current player,
+// and prepares the announcement for the first turn.
+func (s *shed) chooseFirstPlayer() {
+	idx, reason := -1, ""
+
+	switch s.Rules.FirstPlayer {
+	case LowestCardFirstPlayer:
+		if i, card, ok := s.lowestCardHolder(); ok {
+			idx, reason = i, fmt.Sprintf("with the lowest card, the %s", card)
+		}
+	case PreviousLoserFirstPlayer:
+		if i := indexOfPlayerID(s.ActivePlayers, s.PreviousLoser); i >= 0 {
+			idx, reason = i, "having lost the last game"
+		}
+	case CreatorFirstPlayer:
+		if i := indexOfPlayerID(s.ActivePlayers, s.Creator); i >= 0 {
+			idx, reason = i, "having created the game"
+		}
+	}
+
+	if idx < 0 {
+		idx, reason = s.rng.Intn(len(s.ActivePlayers)), "chosen at random"
+	}
+
+	s.CurrentTurnIdx = idx
+	s.CurrentPlayer = s.ActivePlayers[idx]
+	s.firstTurnMsg = fmt.Sprintf("%s goes first, %s.", s.CurrentPlayer.Name, reason)
+}
+
+// lowestCardHolder returns the index of the active player holding the lowest ranked card in their hand
+func (s *shed) lowestCardHolder() (int, deck.Card, bool) {
+	for _, rank := range s.Rules.Ranking {
+		idx, lowest := -1, deck.Card{}
+		for i, p := range s.ActivePlayers {
+			for _, c := range s.PlayerCards[p.PlayerID].Hand {
+				if c.Rank == rank && (idx < 0 || c.Suit < lowest.Suit) {
+					idx, lowest = i, c
+				}
+			}
+		}
+		if idx >= 0 {
+			return idx, lowest, true
+		}
+	}
+
+	return -1, deck.Card{}, false
+}
+
+// announceFirstPlayer adds the first player announcement to the first turn's messages
+func (s *shed) announceFirstPlayer(msgs []protocol.OutboundMessage) {
+	if s.firstTurnMsg == "" {
+		return
+	}
+	for i := range msgs {
+		msgs[i].Message = s.firstTurnMsg + " " + msgs[i].Message
+	}
+	s.firstTurnMsg = ""
+}
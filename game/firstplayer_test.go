@@ -0,0 +1,221 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/minaorangina/shed/deck"
+	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
+)
+
+func TestFirstPlayer(t *testing.T) {
+	rulesWith := func(policy FirstPlayerPolicy) Rules {
+		r := DefaultRules()
+		r.FirstPlayer = policy
+		return r
+	}
+
+	// gameWithHands is a game about to choose its first player, with the given hands
+	gameWithHands := func(opts ShedOpts, hands ...[]deck.Card) *shed {
+		// everything else is dealt from the cards left over
+		rest := deck.Deck{}
+		for _, c := range deck.New() {
+			held := false
+			for _, hand := range hands {
+				held = held || containsCard(hand, c)
+			}
+			if !held {
+				rest = append(rest, c)
+			}
+		}
+
+		opts.Players = fourPlayers()
+		opts.Stage = preGame
+		opts.CurrentPlayer = opts.Players[0]
+		opts.Deck = deck.Deck{}
+		opts.PlayerCards = map[string]*PlayerCards{}
+		for i, p := range opts.Players {
+			var hand []deck.Card
+			if i < len(hands) {
+				hand = hands[i]
+			}
+			hand = append(hand, rest.Deal(3-len(hand))...)
+			opts.PlayerCards[p.PlayerID] = NewPlayerCards(hand, rest.Deal(3), rest.Deal(3), nil)
+		}
+		game, err := ExistingShed(burnTheRest(t, opts))
+		utils.AssertNoError(t, err)
+		return game
+	}
+
+	t.Run("random choice can pick any player", func(t *testing.T) {
+		chosen := map[string]bool{}
+		for seed := int64(1); seed <= 50; seed++ {
+			game, err := NewShed(ShedOpts{Players: fourPlayers(), Seed: seed})
+			utils.AssertNoError(t, err)
+
+			game.chooseFirstPlayer()
+			chosen[game.CurrentPlayer.PlayerID] = true
+		}
+
+		utils.AssertEqual(t, len(chosen), 4)
+	})
+
+	cards := func(cs ...deck.Card) []deck.Card { return cs }
+
+	tt := []struct {
+		name   string
+		opts   ShedOpts
+		hands  [][]deck.Card
+		want   string
+		reason string
+	}{
+		{
+			name: "lowest card",
+			opts: ShedOpts{Rules: rulesWith(LowestCardFirstPlayer)},
+			hands: [][]deck.Card{
+				cards(deck.NewCard(deck.Six, deck.Clubs)),
+				cards(deck.NewCard(deck.Five, deck.Spades)),
+				cards(deck.NewCard(deck.Four, deck.Hearts)),
+				cards(deck.NewCard(deck.Seven, deck.Clubs)),
+			},
+			want:   "p3",
+			reason: "lowest card, the Four of Hearts",
+		},
+		{
+			name: "lowest card, tie broken by suit",
+			opts: ShedOpts{Rules: rulesWith(LowestCardFirstPlayer)},
+			hands: [][]deck.Card{
+				cards(deck.NewCard(deck.Six, deck.Clubs)),
+				cards(deck.NewCard(deck.Four, deck.Diamonds)),
+				cards(deck.NewCard(deck.Four, deck.Spades)),
+				cards(deck.NewCard(deck.Four, deck.Clubs)),
+			},
+			want:   "p4",
+			reason: "lowest card, the Four of Clubs",
+		},
+		{
+			name: "lowest card ignores cards with powers",
+			opts: ShedOpts{Rules: rulesWith(LowestCardFirstPlayer)},
+			hands: [][]deck.Card{
+				cards(deck.NewCard(deck.Two, deck.Clubs), deck.NewCard(deck.Three, deck.Clubs)),
+				cards(deck.NewCard(deck.Eight, deck.Spades)),
+				cards(deck.NewCard(deck.Nine, deck.Hearts)),
+				cards(deck.NewCard(deck.Jack, deck.Clubs)),
+			},
+			want: "p2",
+		},
+		{
+			name: "previous loser",
+			opts: ShedOpts{Rules: rulesWith(PreviousLoserFirstPlayer), PreviousLoser: "p2"},
+			want: "p2",
+		},
+		{
+			name: "creator",
+			opts: ShedOpts{Rules: rulesWith(CreatorFirstPlayer), Creator: "p4"},
+			want: "p4",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			game := gameWithHands(tc.opts, tc.hands...)
+
+			game.chooseFirstPlayer()
+
+			utils.AssertEqual(t, game.CurrentPlayer.PlayerID, tc.want)
+			utils.AssertEqual(t, game.ActivePlayers[game.CurrentTurnIdx], game.CurrentPlayer)
+			utils.AssertContains(t, game.firstTurnMsg, tc.reason)
+		})
+	}
+
+	fallbacks := []struct {
+		name  string
+		opts  ShedOpts
+		hands [][]deck.Card
+	}{
+		{
+			name: "nobody holds a ranked card",
+			opts: ShedOpts{Rules: rulesWith(LowestCardFirstPlayer)},
+			hands: [][]deck.Card{
+				cards(deck.NewCard(deck.Two, deck.Clubs), deck.NewCard(deck.Two, deck.Diamonds), deck.NewCard(deck.Two, deck.Hearts)),
+				cards(deck.NewCard(deck.Two, deck.Spades), deck.NewCard(deck.Three, deck.Clubs), deck.NewCard(deck.Three, deck.Diamonds)),
+				cards(deck.NewCard(deck.Three, deck.Hearts), deck.NewCard(deck.Three, deck.Spades), deck.NewCard(deck.Ten, deck.Clubs)),
+				cards(deck.NewCard(deck.Ten, deck.Diamonds), deck.NewCard(deck.Ten, deck.Hearts), deck.NewCard(deck.Ten, deck.Spades)),
+			},
+		},
+		{
+			name: "previous loser is not playing",
+			opts: ShedOpts{Rules: rulesWith(PreviousLoserFirstPlayer), PreviousLoser: "someone-else"},
+		},
+		{
+			name: "creator is not playing",
+			opts: ShedOpts{Rules: rulesWith(CreatorFirstPlayer), Creator: "someone-else"},
+		},
+	}
+
+	for _, tc := range fallbacks {
+		t.Run("chooses at random when "+tc.name, func(t *testing.T) {
+			game := gameWithHands(tc.opts, tc.hands...)
+
+			game.chooseFirstPlayer()
+
+			utils.AssertTrue(t, sliceContainsPlayerID(game.ActivePlayers, game.CurrentPlayer.PlayerID))
+			utils.AssertContains(t, game.firstTurnMsg, "at random")
+		})
+	}
+
+	t.Run("first player is announced in the first turn", func(t *testing.T) {
+		// Given a game created by p2
+		players := []protocol.Player{{PlayerID: "p1", Name: "Penelope"}, {PlayerID: "p2", Name: "Wendy"}}
+		game, err := NewShed(ShedOpts{Players: players, Rules: rulesWith(CreatorFirstPlayer), Creator: "p2"})
+		utils.AssertNoError(t, err)
+
+		// When everyone has reorganised their cards
+		msgs, err := game.Next()
+		utils.AssertNoError(t, err)
+		_, err = game.ReceiveResponse(reorganiseSomeCards(msgs))
+		utils.AssertNoError(t, err)
+
+		// Then the first turn goes to the creator, and everyone is told
+		msgs, err = game.Next()
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p2")
+		for _, m := range msgs {
+			utils.AssertContains(t, m.Message, "Wendy goes first, having created the game.")
+		}
+
+		// But only once
+		for _, m := range playGame(t, game, 10) {
+			utils.AssertTrue(t, !strings.Contains(m.Message, "goes first"))
+		}
+	})
+
+	t.Run("previous loser policy needs a previous loser", func(t *testing.T) {
+		opts := ShedOpts{Players: fourPlayers(), Rules: rulesWith(PreviousLoserFirstPlayer)}
+
+		_, err := NewShed(opts)
+		utils.AssertTrue(t, errors.Is(err, ErrInvalidRules))
+		_, err = ExistingShed(opts)
+		utils.AssertTrue(t, errors.Is(err, ErrInvalidRules))
+
+		opts.PreviousLoser = "p3"
+		_, err = NewShed(opts)
+		utils.AssertNoError(t, err)
+	})
+
+	t.Run("policies are encoded by name", func(t *testing.T) {
+		data, err := json.Marshal(rulesWith(LowestCardFirstPlayer))
+		utils.AssertNoError(t, err)
+		utils.AssertContains(t, string(data), `"firstPlayer":"lowestCard"`)
+
+		var rules Rules
+		utils.AssertNoError(t, json.Unmarshal(data, &rules))
+		utils.AssertEqual(t, rules.FirstPlayer, LowestCardFirstPlayer)
+
+		err = json.Unmarshal([]byte(`{"firstPlayer":"tallest"}`), &rules)
+		utils.AssertTrue(t, errors.Is(err, ErrInvalidRules))
+	})
+}
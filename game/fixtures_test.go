@@ -0,0 +1,59 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/minaorangina/shed/deck"
+)
+
+// burnTheRest completes a hand-built fixture by adding every card of the game's decks
+// that the fixture does not place to the burned pile, so that the game holds all of its cards.
+// It fails the test if the fixture places a card more times than the decks hold it.
+func burnTheRest(t *testing.T, opts ShedOpts) ShedOpts {
+	t.Helper()
+
+	remaining := map[deck.Card]int{}
+	shoe := deck.NewShoe(deck.ShoeOpts{Decks: decksFor(len(opts.Players)), Jokers: opts.Jokers})
+	for _, c := range shoe {
+		remaining[c]++
+	}
+
+	place := func(cards []deck.Card) {
+		for _, c := range cards {
+			if remaining[c.Face()] == 0 {
+				t.Fatalf("fixture holds %v more times than the game's decks", c)
+			}
+			remaining[c.Face()]--
+		}
+	}
+
+	place(opts.Deck)
+	place(opts.Pile)
+	place(opts.Burned)
+	for _, pc := range opts.PlayerCards {
+		if pc != nil {
+			place(pc.Hand)
+			place(pc.Seen)
+			place(pc.Unseen)
+		}
+	}
+
+	opts.Burned = copyCards(opts.Burned)
+	for _, c := range shoe {
+		if remaining[c] > 0 {
+			opts.Burned = append(opts.Burned, c)
+			remaining[c]--
+		}
+	}
+
+	return opts
+}
+
+// cardsOf returns a card of each rank, all of the one suit
+func cardsOf(suit deck.Suit, ranks ...deck.Rank) []deck.Card {
+	cards := []deck.Card{}
+	for _, r := range ranks {
+		cards = append(cards, deck.NewCard(r, suit))
+	}
+	return cards
+}
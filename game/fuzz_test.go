@@ -0,0 +1,84 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
+)
+
+func TestReceiveResponseFuzz(t *testing.T) {
+	rules := DefaultRules()
+	rules.Snap = true
+	rules.UndoLimit = 2
+
+	for seed := int64(1); seed <= 40; seed++ {
+		players := twoPlayers()
+		if seed%2 == 0 {
+			players = fourPlayers()
+		}
+
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			game, err := NewShed(ShedOpts{Players: players, Rules: rules, Seed: seed})
+			utils.AssertNoError(t, err)
+			rng := rand.New(rand.NewSource(seed))
+
+			for step := 0; step < 2000 && !game.GameOver(); step++ {
+				if game.AwaitingResponse() == protocol.Null {
+					_, err := game.Next()
+					utils.AssertNoError(t, err)
+					continue
+				}
+
+				// Given a client sends a random, possibly tampered, response
+				before, events := game.state(), len(game.events)
+				_, err := game.ReceiveResponse(randomResponses(rng, game))
+
+				// Then the game does not panic, and rejected responses change nothing
+				if err != nil {
+					utils.AssertDeepEqual(t, game.state(), before)
+					utils.AssertEqual(t, len(game.events), events)
+				}
+				utils.AssertNoError(t, validateStateMachine(game))
+
+				// And the game still accepts legal responses
+				if game.AwaitingResponse() != protocol.Null && rng.Intn(2) == 0 {
+					_, err = game.ReceiveResponse(firstLegalResponses(game))
+					utils.AssertNoError(t, err)
+					utils.AssertNoError(t, validateStateMachine(game))
+				}
+			}
+		})
+	}
+}
+
+func randomResponses(rng *rand.Rand, game *shed) []protocol.InboundMessage {
+	playerIDs := []string{"intruder", game.CurrentPlayer.PlayerID}
+	for _, p := range game.PlayerInfo {
+		playerIDs = append(playerIDs, p.PlayerID)
+	}
+
+	msgs := []protocol.InboundMessage{}
+	for i := rng.Intn(len(game.PlayerInfo) + 2); i > 0; i-- {
+		msg := protocol.InboundMessage{
+			PlayerID: playerIDs[rng.Intn(len(playerIDs))],
+			Command:  protocol.Cmd(rng.Intn(int(protocol.UndoRequested) + 1)),
+		}
+		if rng.Intn(2) == 0 {
+			msg.Command = game.AwaitingResponse()
+		}
+
+		for j := rng.Intn(5); j > 0; j-- {
+			if rng.Intn(4) == 0 {
+				msg.CardIDs = append(msg.CardIDs, rng.Intn(60))
+			} else {
+				msg.Decision = append(msg.Decision, rng.Intn(10)-2)
+			}
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return msgs
+}
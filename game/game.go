@@ -6,7 +6,6 @@ import (
 	"math/rand"
 	"reflect"
 	"sort"
-	"time"
 
 	"github.com/minaorangina/shed/protocol"
 
@@ -16,7 +15,7 @@ import (
 var (
 	ErrNilGame                = errors.New("game is nil")
 	ErrTooFewPlayers          = errors.New("minimum of 2 players required")
-	ErrTooManyPlayers         = errors.New("maximum of 4 players allowed")
+	ErrTooManyPlayers         = errors.New("maximum of 8 players allowed")
 	ErrNoPlayers              = errors.New("game has no players")
 	ErrGameUnexpectedResponse = errors.New("game received unexpected response")
 	ErrGameAwaitingResponse   = errors.New("game is awaiting a response")
@@ -24,6 +23,8 @@ var (
 	ErrPlayOneCard            = errors.New("must play one card only")
 	ErrInvalidGameState       = errors.New("invalid game state")
 	ErrGameOver               = errors.New("game is already over")
+	ErrUnknownCard            = errors.New("unknown card")
+	ErrInvalidSnap            = errors.New("invalid snap")
 )
 
 const (
@@ -36,29 +37,50 @@ type Game interface {
 	Next() ([]protocol.OutboundMessage, error)
 	ReceiveResponse([]protocol.InboundMessage) ([]protocol.OutboundMessage, error)
 	AwaitingResponse() protocol.Cmd
+	AcceptsCommand(protocol.Cmd) bool
 	GameOver() bool
+	Events() []Event
 }
 
 type shed struct {
 	Deck              deck.Deck
 	Pile              []deck.Card
+	Burned            []deck.Card
+	lastBurnedCount   int
 	PlayerCards       map[string]*PlayerCards
 	PlayerInfo        []protocol.Player
 	ActivePlayers     []protocol.Player
 	FinishedPlayers   []protocol.Player
 	CurrentTurnIdx    int
 	CurrentPlayer     protocol.Player
-	playerRepeatsTurn bool // not serialisable
+	Direction         Direction
+	SkipCount         int    // players to skip at the next turn
+	playerRepeatsTurn bool   // the current player plays again after a burn
+	firstTurnMsg      string // announces who goes first
 	Stage             Stage
 	gamePlay          GamePlayState
 	ExpectedCommand   protocol.Cmd
 	gameOver          bool
 	unseenDecision    *protocol.InboundMessage
+	history           []undoEntry
+	events            []Event
+	turns             int            // turns started, for the move limit
+	positions         map[uint64]int // how often each position has started a turn
+	turnCounted       bool           // the turn starting again after an undo has already been counted
+	endReason         protocol.GameOverReason
+	Seed              int64
+	Rules             Rules
+	Jokers            int
+	Creator           string
+	PreviousLoser     string
+	src               *countingSource
+	rng               *rand.Rand
 }
 
 type ShedOpts struct {
 	Deck            deck.Deck
 	Pile            []deck.Card
+	Burned          []deck.Card
 	PlayerCards     map[string]*PlayerCards
 	Players         []protocol.Player
 	FinishedPlayers []protocol.Player
@@ -66,132 +88,195 @@ type ShedOpts struct {
 	Stage           Stage
 	State           GamePlayState
 	ExpectedCommand protocol.Cmd
+	// Direction is the order of play. Forwards follows the order of Players.
+	Direction Direction
+	// SkipCount is how many players will miss their turn when the current player's turn ends.
+	SkipCount int
+	// Seed seeds all of the game's randomness (shuffling, choosing the first player).
+	// The same seed and players always produce the same game.
+	// If zero, a seed is generated from the current time.
+	Seed int64
+	// Rules are the house rules the game is played by. If unset, DefaultRules are used.
+	// If only other options are set, the cards have their powers from DefaultRules.
+	Rules Rules
+	// Jokers is the number of jokers shuffled into the deck.
+	Jokers int
+	// Creator is the ID of the player who created the game.
+	Creator string
+	// PreviousLoser is the ID of the player who lost the previous game, if any.
+	// It must be set if the Rules choose the previous loser to go first.
+	PreviousLoser string
 }
 
 // NewShed constructs a new game of Shed
-func NewShed(playerInfo []protocol.Player) (*shed, error) {
-	if len(playerInfo) < minPlayers {
+func NewShed(opts ShedOpts) (*shed, error) {
+	if len(opts.Players) < minPlayers {
 		return nil, ErrTooFewPlayers
 	}
-	if len(playerInfo) > maxPlayers {
+	if len(opts.Players) > maxPlayers {
 		return nil, ErrTooManyPlayers
 	}
-
-	s := &shed{
-		Deck:            deck.New(),
-		Pile:            []deck.Card{},
-		PlayerCards:     map[string]*PlayerCards{},
-		PlayerInfo:      []protocol.Player{},
-		ActivePlayers:   []protocol.Player{},
-		FinishedPlayers: []protocol.Player{},
+	rules := opts.Rules.orDefault()
+	if err := rules.validateFor(opts.Jokers); err != nil {
+		return nil, err
 	}
-
-	s.PlayerInfo = playerInfo
-	s.ActivePlayers = s.PlayerInfo
-
-	// initial card deal
-	for _, info := range playerInfo {
-		playerCards := NewPlayerCards(
-			s.Deck.Deal(numCardsInGroup),
-			s.Deck.Deal(numCardsInGroup),
-			s.Deck.Deal(numCardsInGroup),
-			nil,
-		)
-		s.PlayerCards[info.PlayerID] = playerCards
+	if err := validateFirstPlayer(rules, opts.PreviousLoser); err != nil {
+		return nil, err
 	}
 
-	rand.Seed(time.Now().UnixNano())
-	s.CurrentTurnIdx = rand.Intn(len(s.PlayerInfo) - 1)
-	s.CurrentPlayer = s.ActivePlayers[s.CurrentTurnIdx]
-
-	s.gamePlay = gameInProgress
+	s := newShed(opts)
+	s.deal(opts.Players)
 
 	return s, nil
 }
 
-// ExistingShed constructs an existing game of Shed
-func ExistingShed(opts ShedOpts) *shed {
-	if reflect.ValueOf(opts).IsZero() {
+// ExistingShed constructs an existing game of Shed.
+// It returns a *StateError if opts do not describe a valid game,
+// or ErrInvalidRules if the rules are invalid.
+// To resume a game exactly where it left off, use Restore.
+func ExistingShed(opts ShedOpts) (*shed, error) {
+	rules := opts.Rules.orDefault()
+	if err := rules.validateFor(opts.Jokers); err != nil {
+		return nil, err
+	}
+	if err := validateFirstPlayer(rules, opts.PreviousLoser); err != nil {
+		return nil, err
+	}
+
+	if isNewGame(opts) {
 		// new game flow
-		s := &shed{
-			Deck:            deck.New(),
-			Pile:            []deck.Card{},
-			PlayerCards:     map[string]*PlayerCards{},
-			PlayerInfo:      []protocol.Player{},
-			ActivePlayers:   []protocol.Player{},
-			FinishedPlayers: []protocol.Player{},
-		}
-		return s
+		return newShed(opts), nil
 	}
 
 	s := &shed{
 		Deck:            opts.Deck,
 		Pile:            opts.Pile,
+		Burned:          opts.Burned,
 		PlayerCards:     opts.PlayerCards,
 		PlayerInfo:      opts.Players,
 		FinishedPlayers: opts.FinishedPlayers,
 		CurrentPlayer:   opts.CurrentPlayer,
+		Direction:       opts.Direction,
+		SkipCount:       opts.SkipCount,
 		Stage:           opts.Stage,
 		gamePlay:        opts.State,
 		ExpectedCommand: opts.ExpectedCommand,
 		gameOver:        opts.State == gameOver,
+		Rules:           rules,
+		Jokers:          opts.Jokers,
+		Creator:         opts.Creator,
+		PreviousLoser:   opts.PreviousLoser,
 	}
-
-	// if existing game, check it's valid, set to gameStarted
-
-	// who's turn is it
-	if len(s.PlayerInfo) > 0 {
-		for i, info := range s.PlayerInfo {
-			if info.PlayerID == s.CurrentPlayer.PlayerID {
-				s.CurrentTurnIdx = i
-				break
-			}
-		}
-	}
+	s.seedRand(seedOrNow(opts.Seed), 0)
 
 	if s.Deck == nil {
-		s.Deck = deck.New()
-		s.Deck.Shuffle()
+		s.Deck = s.newShoe()
+		s.Deck.ShuffleWith(s.rng)
 	}
 	if s.Pile == nil {
 		s.Pile = []deck.Card{}
 	}
+	if s.Burned == nil {
+		s.Burned = []deck.Card{}
+	}
 	if s.PlayerCards == nil {
 		s.PlayerCards = map[string]*PlayerCards{}
 	}
-	if s.PlayerInfo == nil {
-		// new game
-		s.PlayerInfo = []protocol.Player{}
-		s.ActivePlayers = []protocol.Player{}
+	if s.FinishedPlayers == nil {
 		s.FinishedPlayers = []protocol.Player{}
-	} else if s.FinishedPlayers == nil {
-		s.PlayerInfo = opts.Players
-		activePlayers := make([]protocol.Player, len(opts.Players)-len(opts.FinishedPlayers))
-		copy(activePlayers, opts.Players)
-		s.ActivePlayers = activePlayers
-	} else {
-		// work out who is still playing the game
-		stillPlaying := []protocol.Player{}
-		for _, pi := range opts.Players {
-			for _, fp := range opts.FinishedPlayers {
-				if fp.PlayerID != pi.PlayerID {
-					stillPlaying = append(stillPlaying, pi)
-				}
-			}
+	}
+
+	// work out who is still playing the game
+	s.ActivePlayers = []protocol.Player{}
+	for _, p := range opts.Players {
+		if !sliceContainsPlayerID(opts.FinishedPlayers, p.PlayerID) {
+			s.ActivePlayers = append(s.ActivePlayers, p)
 		}
-		s.PlayerInfo = opts.Players
-		s.ActivePlayers = stillPlaying
 	}
 
-	s.gamePlay = gameInProgress
+	// who's turn is it
+	for i, p := range s.ActivePlayers {
+		if p.PlayerID == s.CurrentPlayer.PlayerID {
+			s.CurrentTurnIdx = i
+			break
+		}
+	}
+
+	s.assignCardIDs()
+
+	if err := validateStateMachine(s); err != nil {
+		return nil, err
+	}
+
+	if !s.gameOver {
+		s.gamePlay = gameInProgress
+	}
+
+	return s, nil
+}
+
+// isNewGame reports whether opts describe a game yet to be dealt,
+// i.e. nothing other than configuration has been set.
+func isNewGame(opts ShedOpts) bool {
+	opts.Seed = 0
+	opts.Rules = Rules{}
+	opts.Jokers = 0
+	opts.Creator = ""
+	opts.PreviousLoser = ""
+	return reflect.ValueOf(opts).IsZero()
+}
+
+// newShed constructs a game of Shed configured by opts, to be shuffled and dealt later.
+func newShed(opts ShedOpts) *shed {
+	s := &shed{
+		Deck:            deck.Deck{},
+		Pile:            []deck.Card{},
+		Burned:          []deck.Card{},
+		PlayerCards:     map[string]*PlayerCards{},
+		PlayerInfo:      []protocol.Player{},
+		ActivePlayers:   []protocol.Player{},
+		FinishedPlayers: []protocol.Player{},
+		Rules:           opts.Rules.orDefault(),
+		Jokers:          opts.Jokers,
+		Creator:         opts.Creator,
+		PreviousLoser:   opts.PreviousLoser,
+	}
+	s.seedRand(seedOrNow(opts.Seed), 0)
 
 	return s
 }
 
+// newShoe returns every card the game is played with, unshuffled:
+// enough decks for the players, plus any jokers.
+func (s *shed) newShoe() deck.Deck {
+	return deck.NewShoe(deck.ShoeOpts{Decks: decksFor(len(s.PlayerInfo)), Jokers: s.Jokers})
+}
+
+// seedRand sets up the game's source of randomness, as if draws
+// values had already been taken from it.
+func (s *shed) seedRand(seed int64, draws uint64) {
+	s.Seed = seed
+	s.src = newCountingSource(seed, draws)
+	s.rng = rand.New(s.src)
+}
+
 func (s *shed) AwaitingResponse() protocol.Cmd {
 	return s.ExpectedCommand
 }
 
+// AcceptsCommand reports whether the game will accept cmd from the current player.
+// As well as the command it is awaiting, a player may choose to pick up the pile
+// instead of playing, and then acknowledges having picked it up.
+func (s *shed) AcceptsCommand(cmd protocol.Cmd) bool {
+	switch cmd {
+	case protocol.PickUpPile:
+		return s.ExpectedCommand == protocol.PickUpPile || s.canPickUpPile()
+	case protocol.Undo:
+		return s.canUndo()
+	}
+	return cmd == s.ExpectedCommand
+}
+
 func (s *shed) GameOver() bool {
 	return s.gamePlay == gameOver
 }
@@ -207,8 +292,20 @@ func (s *shed) Start(playerInfo []protocol.Player) error {
 		return ErrTooManyPlayers
 	}
 
+	s.deal(playerInfo)
+
+	return nil
+}
+
+// deal shuffles enough decks for the players, deals the initial cards
+// to each player and chooses who goes first.
+func (s *shed) deal(playerInfo []protocol.Player) {
 	s.PlayerInfo = playerInfo
-	s.ActivePlayers = s.PlayerInfo
+	s.ActivePlayers = copyPlayers(playerInfo)
+
+	s.Deck = s.newShoe()
+	s.Deck.ShuffleWith(s.rng)
+	s.assignCardIDs()
 
 	// initial card deal
 	for _, info := range playerInfo {
@@ -219,15 +316,17 @@ func (s *shed) Start(playerInfo []protocol.Player) error {
 			nil,
 		)
 		s.PlayerCards[info.PlayerID] = playerCards
+
+		s.emit(EventDealt, info.PlayerID, HandGroup, playerCards.Hand)
+		s.emit(EventDealt, info.PlayerID, SeenGroup, playerCards.Seen)
+		s.emit(EventDealt, info.PlayerID, UnseenGroup, playerCards.Unseen)
 	}
 
-	rand.Seed(time.Now().UnixNano())
-	s.CurrentTurnIdx = rand.Intn(len(s.PlayerInfo) - 1)
+	// the first player is chosen once everyone has reorganised their cards
+	s.CurrentTurnIdx = 0
 	s.CurrentPlayer = s.ActivePlayers[s.CurrentTurnIdx]
 
 	s.gamePlay = gameInProgress
-
-	return nil
 }
 
 func (s *shed) Next() ([]protocol.OutboundMessage, error) {
@@ -244,6 +343,17 @@ func (s *shed) Next() ([]protocol.OutboundMessage, error) {
 		return s.buildGameOverMessages(), nil
 	}
 
+	// the last burn is announced at the start of the turn after it, and not again
+	defer func() { s.lastBurnedCount = 0 }()
+
+	// a game going nowhere is ended before the next turn starts
+	if s.Stage != preGame {
+		if reason, stalemate := s.checkProgress(); stalemate {
+			return s.endStalemate(reason), nil
+		}
+		s.startTurnForUndo()
+	}
+
 	currentPlayerCards := s.PlayerCards[s.CurrentPlayer.PlayerID]
 
 	switch s.Stage {
@@ -254,6 +364,7 @@ func (s *shed) Next() ([]protocol.OutboundMessage, error) {
 
 	case clearDeck:
 		msgs, legalMoves := s.attemptMove(protocol.PlayHand)
+		s.announceFirstPlayer(msgs)
 		if legalMoves {
 			s.ExpectedCommand = protocol.PlayHand
 		} else {
@@ -262,6 +373,13 @@ func (s *shed) Next() ([]protocol.OutboundMessage, error) {
 		return msgs, nil
 
 	case clearCards:
+		// The last cards in hand can be played along with matching seen cards
+		moves := s.Rules.legalHandAndSeenMoves(s.Pile, currentPlayerCards.Hand, currentPlayerCards.Seen)
+		if len(moves) > 0 {
+			s.ExpectedCommand = protocol.PlayHandAndSeen
+			return s.buildTurnMessages(protocol.PlayHandAndSeen, moves), nil
+		}
+
 		if len(currentPlayerCards.Hand) > 0 {
 			msgs, legalMoves := s.attemptMove(protocol.PlayHand)
 			if legalMoves {
@@ -302,24 +420,74 @@ func (s *shed) Next() ([]protocol.OutboundMessage, error) {
 }
 
 func (s *shed) ReceiveResponse(inboundMsgs []protocol.InboundMessage) ([]protocol.OutboundMessage, error) {
+	if s == nil || s.Rules.UndoLimit == 0 || !s.isMove(inboundMsgs) {
+		return s.receiveResponse(inboundMsgs)
+	}
+
+	// keep the game as it was before the move, so that it can be undone
+	before := s.state()
+	undoable := s.isUndoable(inboundMsgs)
+
+	msgs, err := s.receiveResponse(inboundMsgs)
+	if err == nil {
+		s.recordMove(before, undoable)
+	}
+	return msgs, err
+}
+
+func (s *shed) receiveResponse(inboundMsgs []protocol.InboundMessage) ([]protocol.OutboundMessage, error) {
 	if s == nil {
 		return nil, ErrNilGame
 	}
 	if s.PlayerCards == nil {
 		return nil, ErrNoPlayers
 	}
+	if s.gamePlay == gameOver || s.gameOver == true { //todo: consolidate
+		return s.buildGameOverMessages(), nil
+	}
+
+	// undo requests can come from any player, at any point in a turn
+	if len(inboundMsgs) > 0 && inboundMsgs[0].Command == protocol.Undo {
+		return s.undo(inboundMsgs)
+	}
+
 	if s.ExpectedCommand == protocol.Null {
 		return nil, ErrGameUnexpectedResponse
 	}
-	if s.gamePlay == gameOver || s.gameOver == true { //todo: consolidate
-		return s.buildGameOverMessages(), nil
+
+	// snaps can come from any player, so are resolved separately
+	if len(inboundMsgs) > 0 && inboundMsgs[0].Command == protocol.Snap {
+		return s.snap(inboundMsgs)
+	}
+
+	resolved := make([]protocol.InboundMessage, 0, len(inboundMsgs))
+	for _, m := range inboundMsgs {
+		r, err := s.resolveCardIDs(m)
+		if err != nil {
+			return []protocol.OutboundMessage{s.buildErrorMessage(m.PlayerID, err)}, err
+		}
+		resolved = append(resolved, r)
 	}
+	inboundMsgs = resolved
 
 	// stage 0
 	if s.Stage == preGame {
 		numPlayers, numMessages := len(s.PlayerInfo), len(inboundMsgs)
 		if numPlayers != numMessages {
-			return nil, fmt.Errorf("expected %d messages, got %d", numMessages, numPlayers)
+			return nil, fmt.Errorf("expected %d messages, got %d", numPlayers, numMessages)
+		}
+
+		// check every player's choice before reorganising anyone's cards
+		reorganised := map[string]bool{}
+		for _, m := range inboundMsgs {
+			err := s.checkReorg(m)
+			if err == nil && reorganised[m.PlayerID] {
+				err = fmt.Errorf("%w: more than one reorganisation from player %s", ErrGameUnexpectedResponse, m.PlayerID)
+			}
+			if err != nil {
+				return []protocol.OutboundMessage{s.buildErrorMessage(m.PlayerID, err)}, err
+			}
+			reorganised[m.PlayerID] = true
 		}
 
 		for _, m := range inboundMsgs {
@@ -340,29 +508,68 @@ func (s *shed) ReceiveResponse(inboundMsgs []protocol.InboundMessage) ([]protoco
 
 			s.PlayerCards[m.PlayerID].Hand = newHand
 			s.PlayerCards[m.PlayerID].Seen = newSeen
+			s.emit(EventReorganised, m.PlayerID, HandGroup, newHand)
 		}
 
+		s.chooseFirstPlayer()
+
 		// switch to stage 1
 		s.Stage = clearDeck
 		s.ExpectedCommand = protocol.Null
 		return nil, nil
 	}
 
+	if len(inboundMsgs) != 1 {
+		return nil, fmt.Errorf("expected one message, got %d", len(inboundMsgs))
+	}
+
 	msg := inboundMsgs[0]
 	if msg.PlayerID != s.CurrentPlayer.PlayerID {
-		err := fmt.Errorf("unexpected message from player %s", msg.PlayerID)
+		err := newMoveError(protocol.NotYourTurn, nil,
+			"unexpected message from player %s - it is %s's turn", msg.PlayerID, s.CurrentPlayer.Name)
 		return []protocol.OutboundMessage{s.buildErrorMessage(msg.PlayerID, err)}, err
 	}
+	if msg.Command == protocol.PickUpPile && s.canPickUpPile() {
+		s.pickUpPile()
+		s.ExpectedCommand = protocol.PickUpPile
+		return s.buildPickUpPileMessages(), nil
+	}
 	if msg.Command != s.ExpectedCommand {
-		err := fmt.Errorf("unexpected command - got %s, want %s", msg.Command.String(), s.ExpectedCommand.String())
+		err := newMoveError(protocol.WrongCommand, nil,
+			"unexpected command - got %s, want %s", msg.Command.String(), s.ExpectedCommand.String())
 		return []protocol.OutboundMessage{s.buildErrorMessage(s.CurrentPlayer.PlayerID, err)}, err
 	}
 
 	if msg.Command == protocol.Burn { // ack
-		// Maybe in future the old cards are banished out of sight but not deleted
-		// Useful for undo mechanism etc
+		// The burned cards are banished out of sight, but kept
+		s.emit(EventBurned, s.CurrentPlayer.PlayerID, "", s.Pile)
+		s.Burned = append(s.Burned, s.Pile...)
+		s.lastBurnedCount = len(s.Pile)
 		s.Pile = []deck.Card{}
 		s.ExpectedCommand = protocol.Null
+
+		// If the burn emptied the deck, switch to stage 2
+		if s.Stage == clearDeck && len(s.Deck) == 0 {
+			s.Stage = clearCards
+		}
+
+		repeatsTurn := s.playerRepeatsTurn
+		s.playerRepeatsTurn = false
+
+		if s.Stage == clearCards && s.playerHasFinished() {
+			s.ExpectedCommand = protocol.PlayerFinished
+			return s.buildPlayerFinishedMessages(), nil
+		}
+
+		if !repeatsTurn {
+			s.turn()
+		}
+		return nil, nil
+	}
+
+	if msg.Command == protocol.PickUpPile { // ack
+		s.ExpectedCommand = protocol.Null
+		s.turn()
 		return nil, nil
 	}
 
@@ -374,26 +581,15 @@ func (s *shed) ReceiveResponse(inboundMsgs []protocol.InboundMessage) ([]protoco
 
 	// stage 1
 	if s.Stage == clearDeck {
-		if len(inboundMsgs) != 1 {
-			return nil, fmt.Errorf("expected one message, got %d", len(inboundMsgs))
-		}
-
 		switch msg.Command {
 
 		case protocol.PlayHand:
 			// check this is a legal move. this has already been done, but worth
 			// double checking in case of client tampering.
 
-			// If playing more than one card, they must be of the same rank
-			if len(msg.Decision) > 1 {
-				pc := s.PlayerCards[s.CurrentPlayer.PlayerID].Hand
-				referenceCard := pc[msg.Decision[0]]
-
-				for _, idx := range msg.Decision[1:] {
-					if pc[idx].Rank != referenceCard.Rank {
-						return s.buildErrorMessages(ErrInvalidMove), ErrInvalidMove
-					}
-				}
+			hand := s.PlayerCards[s.CurrentPlayer.PlayerID].Hand
+			if err := s.Rules.checkMove(s.Pile, hand, msg.Decision); err != nil {
+				return []protocol.OutboundMessage{s.buildErrorMessage(s.CurrentPlayer.PlayerID, err)}, err
 			}
 
 			s.completeMove(msg)
@@ -403,11 +599,12 @@ func (s *shed) ReceiveResponse(inboundMsgs []protocol.InboundMessage) ([]protoco
 				s.pluckFromDeck(msg)
 			}
 
-			if isBurn(s.Pile) {
-				s.ExpectedCommand = protocol.Burn
-				return s.buildBurnMessages(), nil
+			if s.Rules.isBurn(s.Pile) {
+				return s.startBurn(), nil
 			}
 
+			s.applyTurnEffects(len(msg.Decision))
+
 			msgs := s.buildReplenishHandMessages()
 			s.ExpectedCommand = protocol.ReplenishHand
 			return msgs, nil
@@ -430,12 +627,15 @@ func (s *shed) ReceiveResponse(inboundMsgs []protocol.InboundMessage) ([]protoco
 
 		case protocol.PlayerFinished: // ack
 			s.ExpectedCommand = protocol.Null
+			s.emit(EventPlayerFinished, s.CurrentPlayer.PlayerID, "", nil)
 			s.moveToFinishedPlayers() // handles the next turn
 
 			if s.onePlayerLeft() {
 				s.gamePlay = gameOver
 				// move the remaining player
+				loser := s.ActivePlayers[0]
 				s.moveToFinishedPlayers()
+				s.emit(EventGameOver, loser.PlayerID, "", nil)
 				return s.buildGameOverMessages(), nil
 			}
 
@@ -450,12 +650,13 @@ func (s *shed) ReceiveResponse(inboundMsgs []protocol.InboundMessage) ([]protoco
 			s.completeMove(*s.unseenDecision)
 			s.unseenDecision = nil
 
-			if isBurn(s.Pile) {
+			if s.Rules.isBurn(s.Pile) {
 				// Delay burn until after ack
-				s.ExpectedCommand = protocol.Burn
-				return s.buildBurnMessages(), nil
+				return s.startBurn(), nil
 			}
 
+			s.applyTurnEffects(1)
+
 			if s.playerHasFinished() {
 				s.ExpectedCommand = protocol.PlayerFinished
 				return s.buildPlayerFinishedMessages(), nil
@@ -476,14 +677,19 @@ func (s *shed) ReceiveResponse(inboundMsgs []protocol.InboundMessage) ([]protoco
 			s.turn()
 			return nil, nil
 
-		case protocol.PlayHand, protocol.PlaySeen:
+		case protocol.PlayHand, protocol.PlaySeen, protocol.PlayHandAndSeen:
+			if err := s.checkClearCardsMove(msg); err != nil {
+				return []protocol.OutboundMessage{s.buildErrorMessage(s.CurrentPlayer.PlayerID, err)}, err
+			}
+
 			s.completeMove(msg)
 
-			if isBurn(s.Pile) {
-				s.ExpectedCommand = protocol.Burn
-				return s.buildBurnMessages(), nil
+			if s.Rules.isBurn(s.Pile) {
+				return s.startBurn(), nil
 			}
 
+			s.applyTurnEffects(len(msg.Decision))
+
 			if s.playerHasFinished() {
 				s.ExpectedCommand = protocol.PlayerFinished
 				return s.buildPlayerFinishedMessages(), nil
@@ -499,6 +705,10 @@ func (s *shed) ReceiveResponse(inboundMsgs []protocol.InboundMessage) ([]protoco
 					s.buildErrorMessage(s.CurrentPlayer.PlayerID, ErrPlayOneCard),
 				}, ErrPlayOneCard
 			}
+			if idx := msg.Decision[0]; idx < 0 || idx >= len(s.PlayerCards[s.CurrentPlayer.PlayerID].Unseen) {
+				err := newMoveError(protocol.IndexOutOfRange, nil, "no card at position %d", idx)
+				return []protocol.OutboundMessage{s.buildErrorMessage(s.CurrentPlayer.PlayerID, err)}, err
+			}
 			// possible optimisation: could precalculate legal Unseen card moves
 
 			// The player plays their chosen card regardless of the legality of the move
@@ -509,15 +719,17 @@ func (s *shed) ReceiveResponse(inboundMsgs []protocol.InboundMessage) ([]protoco
 			// Flip chosen card
 			cardIdx := msg.Decision[0]
 			chosenCard := s.PlayerCards[s.CurrentPlayer.PlayerID].Unseen[cardIdx]
-			s.PlayerCards[s.CurrentPlayer.PlayerID].UnseenVisibility[chosenCard] = true
+			s.PlayerCards[s.CurrentPlayer.PlayerID].UnseenVisibility[chosenCard.ID] = true
 
-			legalMoves := getLegalMoves(s.Pile, []deck.Card{chosenCard})
+			legalMoves := s.Rules.legalMoves(s.Pile, []deck.Card{chosenCard})
 
 			if len(legalMoves) > 0 {
+				s.emit(EventUnseenSuccess, s.CurrentPlayer.PlayerID, UnseenGroup, []deck.Card{chosenCard})
 				s.ExpectedCommand = protocol.UnseenSuccess
 				return s.buildEndOfTurnMessages(protocol.UnseenSuccess), nil
 			}
 
+			s.emit(EventUnseenFailure, s.CurrentPlayer.PlayerID, UnseenGroup, []deck.Card{chosenCard})
 			s.ExpectedCommand = protocol.UnseenFailure
 			return s.buildEndOfTurnMessages(protocol.UnseenFailure), nil
 
@@ -542,7 +754,7 @@ func (s *shed) attemptMove(currentPlayerCmd protocol.Cmd) ([]protocol.OutboundMe
 		panic(fmt.Sprintf("unrecognised move protocol %s", currentPlayerCmd))
 	}
 
-	legalMoves := getLegalMoves(s.Pile, cards)
+	legalMoves := s.Rules.legalMoves(s.Pile, cards)
 	if len(legalMoves) > 0 {
 		toSend := s.buildTurnMessages(currentPlayerCmd, legalMoves)
 		return toSend, true
@@ -550,6 +762,7 @@ func (s *shed) attemptMove(currentPlayerCmd protocol.Cmd) ([]protocol.OutboundMe
 
 	// no legal moves
 	s.pickUpPile()
+	s.endSoloUndo()
 
 	toSend := s.buildSkipTurnMessages(protocol.SkipTurn)
 	return toSend, false
@@ -557,34 +770,212 @@ func (s *shed) attemptMove(currentPlayerCmd protocol.Cmd) ([]protocol.OutboundMe
 
 // step 2 of 2 of a player playing their cards (Hand or Seen)
 func (s *shed) completeMove(msg protocol.InboundMessage) {
-
 	var (
-		toPile       = []deck.Card{}
-		cardGroup    *[]deck.Card
-		cardGroupSet = map[deck.Card]struct{}{}
+		cardGroup *[]deck.Card
+		group     CardGroup
 	)
 
 	switch msg.Command {
-	case protocol.PlayHand:
-		cardGroup = &s.PlayerCards[s.CurrentPlayer.PlayerID].Hand
-		cardGroupSet = cardSliceToSet(s.PlayerCards[s.CurrentPlayer.PlayerID].Hand)
+	case protocol.PlayHand, protocol.Snap:
+		cardGroup, group = &s.PlayerCards[s.CurrentPlayer.PlayerID].Hand, HandGroup
 
 	case protocol.PlaySeen:
-		cardGroup = &s.PlayerCards[s.CurrentPlayer.PlayerID].Seen
-		cardGroupSet = cardSliceToSet(s.PlayerCards[s.CurrentPlayer.PlayerID].Seen)
+		cardGroup, group = &s.PlayerCards[s.CurrentPlayer.PlayerID].Seen, SeenGroup
 
 	case protocol.PlayUnseen:
-		cardGroup = &s.PlayerCards[s.CurrentPlayer.PlayerID].Unseen
-		cardGroupSet = cardSliceToSet(s.PlayerCards[s.CurrentPlayer.PlayerID].Unseen)
-	}
+		cardGroup, group = &s.PlayerCards[s.CurrentPlayer.PlayerID].Unseen, UnseenGroup
 
-	for _, cardIdx := range msg.Decision {
-		toPile = append(toPile, (*cardGroup)[cardIdx])
-		delete(cardGroupSet, (*cardGroup)[cardIdx])
+	case protocol.PlayHandAndSeen:
+		s.completeHandAndSeenMove(msg)
+		return
 	}
 
+	remaining, toPile := removeCards(*cardGroup, msg.Decision)
+
 	s.Pile = append(s.Pile, toPile...)
-	*cardGroup = setToCardSlice(cardGroupSet)
+	*cardGroup = remaining
+
+	kind := EventPlayed
+	if msg.Command == protocol.Snap {
+		kind = EventSnapped
+	}
+	s.emit(kind, s.CurrentPlayer.PlayerID, group, toPile)
+}
+
+// checkClearCardsMove returns a *MoveError if the hand or seen cards chosen in stage 2 cannot be played
+func (s *shed) checkClearCardsMove(msg protocol.InboundMessage) error {
+	pc := s.PlayerCards[s.CurrentPlayer.PlayerID]
+	switch msg.Command {
+	case protocol.PlayHand:
+		return s.Rules.checkMove(s.Pile, pc.Hand, msg.Decision)
+	case protocol.PlaySeen:
+		return s.Rules.checkMove(s.Pile, pc.Seen, msg.Decision)
+	}
+
+	// Decisions index into the hand followed by seen cards
+	cards := append(append([]deck.Card{}, pc.Hand...), pc.Seen...)
+	if err := s.Rules.checkMove(s.Pile, cards, msg.Decision); err != nil {
+		return err
+	}
+
+	// The hand must be emptied before any seen cards are played
+	chosen := intSliceToSet(msg.Decision)
+	for _, idx := range msg.Decision {
+		if idx < len(pc.Hand) {
+			continue
+		}
+		for i := range pc.Hand {
+			if _, ok := chosen[i]; !ok {
+				return newMoveError(protocol.SeenBeforeHand, &cards[idx],
+					"%s cannot be played until the hand is empty", cards[idx])
+			}
+		}
+	}
+
+	return nil
+}
+
+// completeHandAndSeenMove plays cards indexed into the hand followed by seen cards
+func (s *shed) completeHandAndSeenMove(msg protocol.InboundMessage) {
+	pc := s.PlayerCards[s.CurrentPlayer.PlayerID]
+
+	handIndices, seenIndices := []int{}, []int{}
+	for _, idx := range msg.Decision {
+		if idx < len(pc.Hand) {
+			handIndices = append(handIndices, idx)
+		} else {
+			seenIndices = append(seenIndices, idx-len(pc.Hand))
+		}
+	}
+
+	remainingHand, fromHand := removeCards(pc.Hand, handIndices)
+	remainingSeen, fromSeen := removeCards(pc.Seen, seenIndices)
+
+	s.Pile = append(s.Pile, fromHand...)
+	s.Pile = append(s.Pile, fromSeen...)
+	pc.Hand, pc.Seen = remainingHand, remainingSeen
+
+	s.emit(EventPlayed, s.CurrentPlayer.PlayerID, HandGroup, fromHand)
+	s.emit(EventPlayed, s.CurrentPlayer.PlayerID, SeenGroup, fromSeen)
+}
+
+// snap resolves snaps sent by players out of turn.
+// If several players snap, the first to play after the current player wins.
+func (s *shed) snap(msgs []protocol.InboundMessage) ([]protocol.OutboundMessage, error) {
+	var (
+		winner          *protocol.InboundMessage
+		winnerSeats     int
+		firstErr        error
+		firstErrMessage protocol.InboundMessage
+	)
+
+	for _, m := range msgs {
+		resolved, err := s.resolveCardIDs(m)
+		if err == nil {
+			err = s.checkSnap(resolved)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr, firstErrMessage = err, m
+			}
+			continue
+		}
+
+		seats := s.seatsAfterCurrentPlayer(resolved.PlayerID)
+		if winner == nil || seats < winnerSeats {
+			winner, winnerSeats = &resolved, seats
+		}
+	}
+
+	if winner == nil {
+		if s.PlayerCards[firstErrMessage.PlayerID] == nil {
+			return nil, firstErr
+		}
+		return []protocol.OutboundMessage{s.buildErrorMessage(firstErrMessage.PlayerID, firstErr)}, firstErr
+	}
+
+	return s.completeSnap(*winner), nil
+}
+
+// checkSnap returns an error if msg is not a snap that can be played right now
+func (s *shed) checkSnap(msg protocol.InboundMessage) error {
+	if !s.Rules.Snap {
+		return fmt.Errorf("%w: snaps are not allowed", ErrInvalidSnap)
+	}
+	if msg.Command != protocol.Snap {
+		return fmt.Errorf("%w: got %s", ErrInvalidSnap, msg.Command)
+	}
+	awaitingAck := s.ExpectedCommand == protocol.ReplenishHand || s.ExpectedCommand == protocol.EndOfTurn
+	if !awaitingAck || len(s.Pile) == 0 {
+		return fmt.Errorf("%w: nothing to snap", ErrInvalidSnap)
+	}
+	if !sliceContainsPlayerID(s.ActivePlayers, msg.PlayerID) || msg.PlayerID == s.CurrentPlayer.PlayerID {
+		return fmt.Errorf("%w: player %s cannot snap", ErrInvalidSnap, msg.PlayerID)
+	}
+
+	hand := s.PlayerCards[msg.PlayerID].Hand
+	chosen := intSliceToSet(msg.Decision)
+	if len(msg.Decision) == 0 || len(chosen) != len(msg.Decision) {
+		return fmt.Errorf("%w: choose each card once", ErrInvalidSnap)
+	}
+
+	topCard := s.Pile[len(s.Pile)-1]
+	for _, idx := range msg.Decision {
+		if idx < 0 || idx >= len(hand) {
+			return fmt.Errorf("%w: no card at %d", ErrInvalidSnap, idx)
+		}
+		if hand[idx].Rank != topCard.Rank {
+			return fmt.Errorf("%w: %s does not match %s", ErrInvalidSnap, hand[idx], topCard)
+		}
+	}
+
+	return nil
+}
+
+// seatsAfterCurrentPlayer returns how many turns from now the player would play,
+// ignoring any skips
+func (s *shed) seatsAfterCurrentPlayer(playerID string) int {
+	for i, p := range s.ActivePlayers {
+		if p.PlayerID == playerID {
+			return mod((i-s.CurrentTurnIdx)*s.Direction.step(), len(s.ActivePlayers))
+		}
+	}
+	return len(s.ActivePlayers)
+}
+
+// completeSnap plays the snapped cards, making the snapping player the current player
+func (s *shed) completeSnap(msg protocol.InboundMessage) []protocol.OutboundMessage {
+	for i, p := range s.ActivePlayers {
+		if p.PlayerID == msg.PlayerID {
+			s.CurrentTurnIdx = i
+			s.CurrentPlayer = p
+		}
+	}
+	s.SkipCount = 0
+
+	s.completeMove(msg)
+	if s.Stage == clearDeck && len(s.PlayerCards[s.CurrentPlayer.PlayerID].Hand) < numCardsInGroup {
+		s.pluckFromDeck(msg)
+	}
+
+	if s.Rules.isBurn(s.Pile) {
+		return s.startBurn()
+	}
+
+	s.applyTurnEffects(len(msg.Decision))
+
+	if s.Stage == clearDeck {
+		s.ExpectedCommand = protocol.ReplenishHand
+		return s.buildReplenishHandMessages()
+	}
+
+	if s.playerHasFinished() {
+		s.ExpectedCommand = protocol.PlayerFinished
+		return s.buildPlayerFinishedMessages()
+	}
+
+	s.ExpectedCommand = protocol.EndOfTurn
+	return s.buildEndOfTurnMessages(protocol.EndOfTurn)
 }
 
 func (s *shed) pluckFromDeck(msg protocol.InboundMessage) {
@@ -593,15 +984,46 @@ func (s *shed) pluckFromDeck(msg protocol.InboundMessage) {
 	}
 	fromDeck := s.Deck.Deal(len(msg.Decision))
 	s.PlayerCards[s.CurrentPlayer.PlayerID].Hand = append(s.PlayerCards[s.CurrentPlayer.PlayerID].Hand, fromDeck...)
+	s.emit(EventDrew, s.CurrentPlayer.PlayerID, HandGroup, fromDeck)
+}
+
+// startBurn awaits the current player's acknowledgement of a burn,
+// deciding whether they will play again once the pile has been burned.
+func (s *shed) startBurn() []protocol.OutboundMessage {
+	s.playerRepeatsTurn = !s.Rules.BurnEndsTurn
+	s.ExpectedCommand = protocol.Burn
+	return s.buildBurnMessages()
+}
+
+// lastBurned returns the cards burned most recently, if any
+func (s *shed) lastBurned() []deck.Card {
+	if s.lastBurnedCount == 0 {
+		return nil
+	}
+	return s.Burned[len(s.Burned)-s.lastBurnedCount:]
 }
 
 func (s *shed) pickUpPile() {
 	playerID := s.CurrentPlayer.PlayerID
 	currentPlayerCards := s.PlayerCards[playerID]
 	currentPlayerCards.Hand = append(currentPlayerCards.Hand, s.Pile...)
+	s.emit(EventPickedUpPile, playerID, HandGroup, s.Pile)
 	s.Pile = []deck.Card{}
 }
 
+// canPickUpPile reports whether the current player may choose to pick up the pile
+// rather than play
+func (s *shed) canPickUpPile() bool {
+	if len(s.Pile) == 0 {
+		return false
+	}
+	switch s.ExpectedCommand {
+	case protocol.PlayHand, protocol.PlaySeen, protocol.PlayHandAndSeen:
+		return true
+	}
+	return false
+}
+
 func (s *shed) playerHasFinished() bool {
 	pc := s.PlayerCards[s.CurrentPlayer.PlayerID]
 	return len(pc.Hand) == 0 &&
@@ -613,37 +1035,111 @@ func (s *shed) onePlayerLeft() bool {
 	return len(s.ActivePlayers) == 1
 }
 
-// nextPlayer returns the player who is next in line behind the current player.
+// nextPlayer returns the player who is next in line behind the current player,
+// taking the direction of play and any skipped players into account.
 func (s *shed) nextPlayer() protocol.Player {
 	// Return empty player if there are no players left (for game over message)
 	if len(s.ActivePlayers) == 0 {
 		return protocol.Player{}
 	}
-	idx := (s.CurrentTurnIdx + 1) % len(s.ActivePlayers)
-	return s.ActivePlayers[idx]
+	if s.Stage == clearCards && len(s.ActivePlayers) > 1 && s.playerHasFinished() {
+		// the current player is about to leave the queue
+		idx := s.nextTurnIdxAfterFinishing()
+		if idx >= s.CurrentTurnIdx {
+			idx++
+		}
+		return s.ActivePlayers[idx]
+	}
+	if s.playerRepeatsTurn {
+		return s.CurrentPlayer
+	}
+	return s.ActivePlayers[s.nextTurnIdx()]
+}
+
+// nextTurnIdx returns the index in ActivePlayers of the player who plays next
+func (s *shed) nextTurnIdx() int {
+	steps := s.Direction.step() * (1 + s.SkipCount)
+	return mod(s.CurrentTurnIdx+steps, len(s.ActivePlayers))
+}
+
+// nextTurnIdxAfterFinishing returns the index of the player who plays next
+// once the current player has been removed from ActivePlayers.
+func (s *shed) nextTurnIdxAfterFinishing() int {
+	// Removing the current player shifts everyone behind them forward one place,
+	// so going forwards, the next player takes over the current index.
+	idx := s.CurrentTurnIdx
+	if s.Direction == Backwards {
+		idx--
+	}
+	return mod(idx+s.Direction.step()*s.SkipCount, len(s.ActivePlayers)-1)
 }
 
 // turn changes the CurrentPlayer to the next Player in the queue.
 func (s *shed) turn() {
-	currTurnIdx := (s.CurrentTurnIdx + 1) % len(s.ActivePlayers)
-	s.CurrentTurnIdx = currTurnIdx
-	s.CurrentPlayer = s.ActivePlayers[currTurnIdx]
+	s.CurrentTurnIdx = s.nextTurnIdx()
+	s.CurrentPlayer = s.ActivePlayers[s.CurrentTurnIdx]
+	s.SkipCount = 0
+}
+
+// applyTurnEffects applies the skip and reverse powers of the numPlayed cards just played
+func (s *shed) applyTurnEffects(numPlayed int) {
+	skips, reversals := s.Rules.turnEffects(s.Pile, numPlayed)
+	s.SkipCount += skips
+	if reversals%2 == 1 {
+		s.Direction = s.Direction.reversed()
+	}
 }
 
 func (s *shed) moveToFinishedPlayers() {
 	if len(s.ActivePlayers) == 1 {
 		s.FinishedPlayers = append(s.FinishedPlayers, s.ActivePlayers[0])
 		s.ActivePlayers = []protocol.Player{}
+		s.SkipCount = 0
 		// zero out CurrentPlayer?
 		return
 	}
 
-	s.ActivePlayers = append(s.ActivePlayers[:s.CurrentTurnIdx],
-		s.ActivePlayers[(s.CurrentTurnIdx+1)%len(s.ActivePlayers):]...)
+	nextIdx := s.nextTurnIdxAfterFinishing()
+
+	stillPlaying := append([]protocol.Player{}, s.ActivePlayers[:s.CurrentTurnIdx]...)
+	s.ActivePlayers = append(stillPlaying, s.ActivePlayers[s.CurrentTurnIdx+1:]...)
 
 	s.FinishedPlayers = append(s.FinishedPlayers, s.CurrentPlayer)
 
+	s.CurrentTurnIdx = nextIdx
 	s.CurrentPlayer = s.ActivePlayers[s.CurrentTurnIdx]
+	s.SkipCount = 0
+}
+
+// checkReorg returns an error unless the message chooses exactly three
+// different cards from the player's hand and seen cards
+func (s *shed) checkReorg(msg protocol.InboundMessage) error {
+	pc, ok := s.PlayerCards[msg.PlayerID]
+	if !ok {
+		return newMoveError(protocol.NotYourTurn, nil, "unexpected message from player %s", msg.PlayerID)
+	}
+	if msg.Command != protocol.Reorg {
+		return newMoveError(protocol.WrongCommand, nil,
+			"unexpected command - got %s, want %s", msg.Command.String(), protocol.Reorg.String())
+	}
+	if len(msg.Decision) != numCardsInGroup {
+		return newMoveError(protocol.WrongCardCount, nil,
+			"choose %d cards for your hand, not %d", numCardsInGroup, len(msg.Decision))
+	}
+
+	cards := append(copyCards(pc.Hand), pc.Seen...)
+	chosen := map[int]bool{}
+	for _, idx := range msg.Decision {
+		if idx < 0 || idx >= len(cards) {
+			return newMoveError(protocol.IndexOutOfRange, nil, "no card at position %d", idx)
+		}
+		if chosen[idx] {
+			return newMoveError(protocol.DuplicateCard, &cards[idx], "%s chosen more than once", cards[idx])
+		}
+		chosen[idx] = true
+	}
+
+	return nil
 }
 
 func (s *shed) getReorgCard(playerID string, choice int) deck.Card {
@@ -660,14 +1156,99 @@ func (s *shed) getReorgCard(playerID string, choice int) deck.Card {
 	return card
 }
 
+// assignCardIDs gives every card without an ID a new one, unique within the game.
+// Unseen cards given a new ID start off hidden.
+func (s *shed) assignCardIDs() {
+	groups := []*[]deck.Card{}
+	groups = append(groups, (*[]deck.Card)(&s.Deck), &s.Pile, &s.Burned)
+	for _, p := range s.PlayerInfo {
+		if pc := s.PlayerCards[p.PlayerID]; pc != nil {
+			groups = append(groups, &pc.Hand, &pc.Seen, &pc.Unseen)
+		}
+	}
+
+	nextID := 1
+	for _, g := range groups {
+		for _, c := range *g {
+			if c.ID >= nextID {
+				nextID = c.ID + 1
+			}
+		}
+	}
+
+	for _, g := range groups {
+		for i := range *g {
+			if (*g)[i].ID == 0 {
+				(*g)[i].ID = nextID
+				nextID++
+			}
+		}
+	}
+
+	// keep visibility only for the cards the player actually holds
+	for _, p := range s.PlayerInfo {
+		pc := s.PlayerCards[p.PlayerID]
+		if pc == nil {
+			continue
+		}
+		visibility := map[int]bool{}
+		for _, c := range pc.Unseen {
+			visibility[c.ID] = pc.UnseenVisibility[c.ID]
+		}
+		pc.UnseenVisibility = visibility
+	}
+}
+
+// resolveCardIDs converts cards chosen by ID into their indices in the message's Decision
+func (s *shed) resolveCardIDs(msg protocol.InboundMessage) (protocol.InboundMessage, error) {
+	if len(msg.CardIDs) == 0 {
+		return msg, nil
+	}
+
+	pc := s.PlayerCards[msg.PlayerID]
+	if pc == nil {
+		return msg, ErrUnknownCard
+	}
+
+	var cards []deck.Card
+	switch msg.Command {
+	case protocol.Reorg, protocol.PlayHandAndSeen:
+		// hand cards, followed by seen cards
+		cards = append(copyCards(pc.Hand), pc.Seen...)
+	case protocol.PlayHand, protocol.Snap:
+		cards = pc.Hand
+	case protocol.PlaySeen:
+		cards = pc.Seen
+	case protocol.PlayUnseen:
+		cards = pc.Unseen
+	default:
+		return msg, nil
+	}
+
+	decision := []int{}
+	for _, id := range msg.CardIDs {
+		idx := indexOfCardID(cards, id)
+		if idx < 0 {
+			return msg, fmt.Errorf("%w: %d", ErrUnknownCard, id)
+		}
+		decision = append(decision, idx)
+	}
+	msg.Decision = decision
+
+	return msg, nil
+}
+
 func (s *shed) mapUnseenToPublicUnseen(playerID string) []deck.Card {
 	playerCards := s.PlayerCards[playerID]
 	publicUnseen := []deck.Card{}
 	for _, c := range playerCards.Unseen {
-		if playerCards.UnseenVisibility[c] {
+		if playerCards.UnseenVisibility[c.ID] {
 			publicUnseen = append(publicUnseen, c)
 		} else {
-			publicUnseen = append(publicUnseen, deck.NewCard(deck.NullRank, deck.NullSuit))
+			// hide the card, but keep its ID so that it can still be chosen
+			hidden := deck.NewCard(deck.NullRank, deck.NullSuit)
+			hidden.ID = c.ID
+			publicUnseen = append(publicUnseen, hidden)
 		}
 	}
 
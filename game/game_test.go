@@ -1,6 +1,7 @@
 package game
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -10,7 +11,6 @@ import (
 	"github.com/minaorangina/shed/deck"
 	utils "github.com/minaorangina/shed/internal"
 	"github.com/minaorangina/shed/protocol"
-	"github.com/stretchr/testify/assert"
 )
 
 var (
@@ -28,16 +28,23 @@ func TestGameTurn(t *testing.T) {
 	gameWithRandomPlayers := func() (int, *shed) {
 		randomNumberOfPlayers := rand.Intn(2) + 2
 
+		d := deck.New()
 		players := map[string]*PlayerCards{}
 		playerInfo := []protocol.Player{}
 
 		for i := 0; i < randomNumberOfPlayers; i++ {
 			id := fmt.Sprintf("player-%d", i)
 			playerInfo = append(playerInfo, protocol.Player{PlayerID: id})
-			players[id] = &PlayerCards{}
+			players[id] = NewPlayerCards(d.Deal(3), d.Deal(3), d.Deal(3), nil)
 		}
 
-		game := ExistingShed(ShedOpts{Deck: deck.New(), PlayerCards: players, Players: playerInfo})
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Deck:          d,
+			PlayerCards:   players,
+			Players:       playerInfo,
+			CurrentPlayer: playerInfo[0],
+		}))
+		utils.AssertNoError(t, err)
 
 		return randomNumberOfPlayers, game
 	}
@@ -65,8 +72,7 @@ func TestGameTurn(t *testing.T) {
 
 		utils.AssertEqual(t, game.CurrentTurnIdx, currentTurnIdxAtStart)
 		utils.AssertEqual(t, game.CurrentPlayer, game.ActivePlayers[game.CurrentTurnIdx])
-
-		assert.NotEqual(t, playerAtStart, game.CurrentPlayer)
+		utils.AssertEqual(t, game.CurrentPlayer, playerAtStart)
 
 		for i := 0; i < numPlayers+1; i++ {
 			game.turn()
@@ -80,7 +86,7 @@ func TestGameTurn(t *testing.T) {
 func TestGameNext(t *testing.T) {
 	t.Run("game must have started", func(t *testing.T) {
 		t.Skip()
-		game, err := NewShed(twoPlayers())
+		game, err := NewShed(ShedOpts{Players: twoPlayers()})
 		utils.AssertNoError(t, err)
 		_, err = game.Next()
 		utils.AssertErrored(t, err)
@@ -88,8 +94,9 @@ func TestGameNext(t *testing.T) {
 
 	t.Run("game won't progress if waiting for a response", func(t *testing.T) {
 		t.Skip()
-		game := ExistingShed(ShedOpts{ExpectedCommand: protocol.PlaySeen})
-		err := game.Start(threePlayers())
+		game, err := ExistingShed(ShedOpts{ExpectedCommand: protocol.PlaySeen})
+		utils.AssertNoError(t, err)
+		err = game.Start(threePlayers())
 		utils.AssertNoError(t, err)
 
 		_, err = game.Next()
@@ -100,7 +107,7 @@ func TestGameNext(t *testing.T) {
 	t.Run("new game: players reorganise cards and stage switches", func(t *testing.T) {
 		t.Skip()
 		// Given a new game
-		game, err := NewShed(fourPlayers())
+		game, err := NewShed(ShedOpts{Players: fourPlayers()})
 		utils.AssertNoError(t, err)
 
 		// When Next is called
@@ -137,7 +144,7 @@ func TestGameNext(t *testing.T) {
 		lowValueCard := deck.NewCard(deck.Four, deck.Hearts)
 		pile := []deck.Card{lowValueCard}
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(ShedOpts{
 			Stage:         clearDeck,
 			Deck:          someDeck(1),
 			Pile:          pile,
@@ -148,6 +155,7 @@ func TestGameNext(t *testing.T) {
 				"p2": somePlayerCards(3),
 			},
 		})
+		utils.AssertNoError(t, err)
 
 		// When the current player takes their turn
 		msgs, err := game.Next()
@@ -187,19 +195,21 @@ func TestGameNext(t *testing.T) {
 func TestGameReceiveResponse(t *testing.T) {
 	t.Run("will return default message if game already over", func(t *testing.T) {
 		plrs := threePlayers()
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:           clearCards,
 			State:           gameOver,
-			ExpectedCommand: protocol.PlayHand,
 			Players:         plrs,
+			FinishedPlayers: plrs,
 			CurrentPlayer:   plrs[0],
-			Deck:            []deck.Card{deck.NewCard(deck.Four, deck.Spades)},
+			Deck:            deck.Deck{},
 			PlayerCards: map[string]*PlayerCards{
-				"p1": {Hand: someCards(3)},
-				"p2": {Hand: someCards(3)},
-				"p3": {Hand: someCards(3)},
+				"p1": NewPlayerCards(nil, nil, nil, nil),
+				"p2": NewPlayerCards(nil, nil, nil, nil),
+				"p3": NewPlayerCards(nil, nil, nil, nil),
 			},
-		})
-		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayHand)
+		}))
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.Null)
 		playerID := game.CurrentPlayer.PlayerID
 		msg, err := game.ReceiveResponse([]protocol.InboundMessage{{PlayerID: playerID, Command: protocol.PlayHand}})
 		utils.AssertNoError(t, err)
@@ -209,8 +219,9 @@ func TestGameReceiveResponse(t *testing.T) {
 
 	t.Run("handles unexpected response", func(t *testing.T) {
 		t.SkipNow()
-		game := ExistingShed(ShedOpts{Stage: 1, CurrentPlayer: threePlayers()[0]})
-		err := game.Start(threePlayers())
+		game, err := ExistingShed(ShedOpts{Stage: 1, CurrentPlayer: threePlayers()[0]})
+		utils.AssertNoError(t, err)
+		err = game.Start(threePlayers())
 		utils.AssertNoError(t, err)
 		utils.AssertEqual(t, game.AwaitingResponse(), protocol.Null)
 
@@ -220,7 +231,7 @@ func TestGameReceiveResponse(t *testing.T) {
 
 	t.Run("handles response from wrong player", func(t *testing.T) {
 		t.Skip()
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(ShedOpts{
 			Stage:           1,
 			ExpectedCommand: protocol.PlayHand,
 			CurrentPlayer:   threePlayers()[0],
@@ -231,6 +242,7 @@ func TestGameReceiveResponse(t *testing.T) {
 				"p3": {Hand: someCards(3)},
 			},
 		})
+		utils.AssertNoError(t, err)
 		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayHand)
 
 		msgs, err := game.ReceiveResponse([]protocol.InboundMessage{{PlayerID: "p2", Command: protocol.PlayHand}})
@@ -244,7 +256,7 @@ func TestGameReceiveResponse(t *testing.T) {
 
 	t.Run("handles response with incorrect command", func(t *testing.T) {
 		t.SkipNow()
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(ShedOpts{
 			Stage:           1,
 			ExpectedCommand: protocol.PlayHand,
 			CurrentPlayer:   threePlayers()[0],
@@ -255,6 +267,7 @@ func TestGameReceiveResponse(t *testing.T) {
 				"p3": {Hand: someCards(3)},
 			},
 		})
+		utils.AssertNoError(t, err)
 		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayHand)
 
 		msgs, err := game.ReceiveResponse([]protocol.InboundMessage{{PlayerID: "p1", Command: protocol.PlayUnseen}})
@@ -267,7 +280,7 @@ func TestGameReceiveResponse(t *testing.T) {
 	})
 
 	t.Run("expects multiple responses in stage 0", func(t *testing.T) {
-		game, err := NewShed(threePlayers())
+		game, err := NewShed(ShedOpts{Players: threePlayers()})
 		utils.AssertNoError(t, err)
 
 		_, err = game.Next()
@@ -300,7 +313,7 @@ func TestGameReceiveResponse(t *testing.T) {
 
 	t.Run("expects one card choice in stage 2 unseen", func(t *testing.T) {
 		t.SkipNow()
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(ShedOpts{
 			Stage:           clearCards,
 			ExpectedCommand: protocol.PlayUnseen,
 			CurrentPlayer:   threePlayers()[0],
@@ -311,6 +324,7 @@ func TestGameReceiveResponse(t *testing.T) {
 				"p3": {Seen: someCards(3)},
 			},
 		})
+		utils.AssertNoError(t, err)
 		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayUnseen)
 
 		msgs, err := game.ReceiveResponse([]protocol.InboundMessage{{
@@ -326,8 +340,8 @@ func TestGameReceiveResponse(t *testing.T) {
 		utils.AssertTrue(t, strings.Contains(msgs[0].Message, ErrPlayOneCard.Error()))
 	})
 
-	t.Run("sends error messages if game in bad state", func(t *testing.T) {
-		game := ExistingShed(ShedOpts{
+	t.Run("rejects a game in a bad state", func(t *testing.T) {
+		game, err := ExistingShed(ShedOpts{
 			Stage:           clearDeck,
 			ExpectedCommand: protocol.PlayUnseen, // this is impossible in clearDeck stage
 			CurrentPlayer:   threePlayers()[0],
@@ -338,23 +352,209 @@ func TestGameReceiveResponse(t *testing.T) {
 				"p3": {Seen: someCards(3)},
 			},
 		})
-		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayUnseen)
 
+		utils.AssertTrue(t, game == nil)
+		utils.AssertTrue(t, errors.Is(err, ErrInvalidGameState))
+
+		var stateErr *StateError
+		utils.AssertTrue(t, errors.As(err, &stateErr))
+		utils.AssertTrue(t, len(stateErr.Violations) > 0)
+	})
+
+	t.Run("rejects invalid rules", func(t *testing.T) {
+		rules := DefaultRules()
+		rules.UndoLimit = -1
+
+		game, err := ExistingShed(ShedOpts{Rules: rules})
+
+		utils.AssertTrue(t, game == nil)
+		utils.AssertTrue(t, errors.Is(err, ErrInvalidRules))
+	})
+}
+
+func TestGameDuplicateCards(t *testing.T) {
+	// Given a game for five players, played with two decks
+	plrs := []protocol.Player{{PlayerID: "p1"}, {PlayerID: "p2"}, {PlayerID: "p3"}, {PlayerID: "p4"}, {PlayerID: "p5"}}
+	queen := deck.NewCard(deck.Queen, deck.Hearts)
+	four := deck.NewCard(deck.Four, deck.Clubs)
+
+	// And a player holding two identical cards
+	playerCards := map[string]*PlayerCards{
+		"p1": NewPlayerCards([]deck.Card{queen, queen, four}, nil, cardsOf(deck.Clubs, deck.Ten, deck.Jack, deck.Queen), nil),
+	}
+	for i, suit := range []deck.Suit{deck.Clubs, deck.Diamonds, deck.Spades, deck.Hearts} {
+		playerCards[plrs[i+1].PlayerID] = NewPlayerCards(
+			cardsOf(suit, deck.Five, deck.Six, deck.Seven),
+			cardsOf(suit, deck.Eight, deck.Nine, deck.King),
+			cardsOf(suit, deck.Ace, deck.Two, deck.Three),
+			nil,
+		)
+	}
+	game, err := ExistingShed(burnTheRest(t, ShedOpts{
+		Stage:         clearCards,
+		Deck:          deck.Deck{},
+		Pile:          []deck.Card{deck.NewCard(deck.Jack, deck.Hearts)},
+		Players:       plrs,
+		CurrentPlayer: plrs[0],
+		PlayerCards:   playerCards,
+	}))
+	utils.AssertNoError(t, err)
+
+	msgs, err := game.Next()
+	utils.AssertNoError(t, err)
+	utils.AssertDeepEqual(t, getMoves(msgs, "p1"), []int{0, 1})
+
+	// which can be told apart by their IDs
+	hand := copyCards(game.PlayerCards["p1"].Hand)
+	utils.AssertTrue(t, hand[0].Matches(hand[1]))
+	utils.AssertTrue(t, hand[0].ID != hand[1].ID)
+
+	// When they play one of them
+	_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+		PlayerID: "p1",
+		Command:  protocol.PlayHand,
+		Decision: []int{0},
+	}})
+	utils.AssertNoError(t, err)
+
+	// Then only that one is played, and the rest of their hand is unchanged
+	utils.AssertDeepEqual(t, game.PlayerCards["p1"].Hand, []deck.Card{hand[1], hand[2]})
+	utils.AssertEqual(t, game.Pile[len(game.Pile)-1], hand[0])
+	utils.AssertNoError(t, validateStateMachine(game))
+}
+
+func TestGameCardIDs(t *testing.T) {
+	t.Run("every card has a unique ID", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: fourPlayers()})
+		utils.AssertNoError(t, err)
+
+		ids := map[int]bool{}
+		all := copyCards(game.Deck)
+		for _, pc := range game.PlayerCards {
+			all = append(all, pc.Hand...)
+			all = append(all, pc.Seen...)
+			all = append(all, pc.Unseen...)
+		}
+		for _, c := range all {
+			utils.AssertTrue(t, c.ID > 0)
+			utils.AssertTrue(t, !ids[c.ID])
+			ids[c.ID] = true
+		}
+	})
+
+	t.Run("players can reorganise by card ID", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers()})
+		utils.AssertNoError(t, err)
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+
+		// When each player chooses their hand by card ID
+		wantHands := map[string][]deck.Card{}
+		responses := []protocol.InboundMessage{}
+		for _, p := range game.PlayerInfo {
+			pc := game.PlayerCards[p.PlayerID]
+			wantHands[p.PlayerID] = []deck.Card{pc.Seen[2], pc.Hand[0], pc.Seen[0]}
+			responses = append(responses, protocol.InboundMessage{
+				PlayerID: p.PlayerID,
+				Command:  protocol.Reorg,
+				CardIDs:  []int{pc.Seen[2].ID, pc.Hand[0].ID, pc.Seen[0].ID},
+			})
+		}
+		_, err = game.ReceiveResponse(responses)
+		utils.AssertNoError(t, err)
+
+		// Then those cards make up their hand
+		for id, want := range wantHands {
+			utils.AssertDeepEqual(t, game.PlayerCards[id].Hand, want)
+		}
+	})
+
+	t.Run("players can play cards by ID", func(t *testing.T) {
+		plrs := twoPlayers()
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearCards,
+			Deck:          deck.Deck{},
+			Pile:          []deck.Card{deck.NewCard(deck.Four, deck.Hearts)},
+			Players:       plrs,
+			CurrentPlayer: plrs[0],
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards(cardsOf(deck.Clubs, deck.Four, deck.Five, deck.Six), nil, cardsOf(deck.Clubs, deck.Jack, deck.Queen, deck.King), nil),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+			},
+		}))
+		utils.AssertNoError(t, err)
+		msgs, err := game.Next()
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayHand)
+
+		hand := copyCards(game.PlayerCards["p1"].Hand)
+		chosen := hand[getMoves(msgs, "p1")[0]]
+
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p1",
+			Command:  protocol.PlayHand,
+			CardIDs:  []int{chosen.ID},
+		}})
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, game.Pile[len(game.Pile)-1], chosen)
+		utils.AssertEqual(t, len(game.PlayerCards["p1"].Hand), len(hand)-1)
+	})
+
+	t.Run("unknown card IDs are rejected", func(t *testing.T) {
+		plrs := twoPlayers()
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearCards,
+			Deck:          deck.Deck{},
+			Pile:          []deck.Card{deck.NewCard(deck.Four, deck.Hearts)},
+			Players:       plrs,
+			CurrentPlayer: plrs[0],
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards(cardsOf(deck.Clubs, deck.Four, deck.Five, deck.Six), nil, cardsOf(deck.Clubs, deck.Jack, deck.Queen, deck.King), nil),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+			},
+		}))
+		utils.AssertNoError(t, err)
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+
+		// a card belonging to someone else
 		msgs, err := game.ReceiveResponse([]protocol.InboundMessage{{
 			PlayerID: "p1",
-			Decision: []int{0, 1},
-			Command:  protocol.PlayUnseen,
+			Command:  protocol.PlayHand,
+			CardIDs:  []int{game.PlayerCards["p2"].Hand[0].ID},
 		}})
+		utils.AssertTrue(t, errors.Is(err, ErrUnknownCard))
+		utils.AssertEqual(t, len(msgs), 1)
+		utils.AssertEqual(t, msgs[0].Command, protocol.Error)
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayHand)
+	})
+
+	t.Run("hidden unseen cards keep their IDs", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers()})
+		utils.AssertNoError(t, err)
+
+		msgs, err := game.Next()
+		utils.AssertNoError(t, err)
 
-		utils.AssertErrored(t, err)
-		utils.AssertEqual(t, err, ErrInvalidGameState)
-		utils.AssertEqual(t, len(msgs), len(game.PlayerInfo))
 		for _, m := range msgs {
-			utils.AssertEqual(t, m.Command, protocol.Error)
-			utils.AssertTrue(t, strings.Contains(m.Message, ErrInvalidGameState.Error()))
+			unseen := game.PlayerCards[m.PlayerID].Unseen
+			for i, c := range m.Unseen {
+				utils.AssertEqual(t, c.Rank, deck.NullRank)
+				utils.AssertEqual(t, c.ID, unseen[i].ID)
+			}
 		}
 	})
-
 }
 
 func TestGameBurn(t *testing.T) {
@@ -385,7 +585,12 @@ func TestGameBurn(t *testing.T) {
 				Players:       ps1,
 				CurrentPlayer: ps1[1],
 				PlayerCards: map[string]*PlayerCards{
-					"p1": somePlayerCards(3),
+					"p1": NewPlayerCards(
+						cardsOf(deck.Clubs, deck.Four, deck.Five, deck.Jack),
+						cardsOf(deck.Clubs, deck.Ace, deck.Eight, deck.King),
+						cardsOf(deck.Clubs, deck.Two, deck.Three, deck.Nine),
+						nil,
+					),
 					"p2": {
 						Hand: []deck.Card{
 							deck.NewCard(deck.Ten, deck.Hearts),
@@ -402,7 +607,7 @@ func TestGameBurn(t *testing.T) {
 			expectedCommand: protocol.PlayHand,
 			opts: ShedOpts{
 				Stage: clearDeck,
-				Deck:  someDeck(4),
+				Deck:  cardsOf(deck.Hearts, deck.Seven, deck.Jack, deck.Queen, deck.King),
 				Pile: []deck.Card{
 					deck.NewCard(deck.Four, deck.Hearts),
 					deck.NewCard(deck.Two, deck.Spades),
@@ -420,7 +625,12 @@ func TestGameBurn(t *testing.T) {
 							deck.NewCard(deck.Five, deck.Diamonds),
 						},
 					},
-					"p2": somePlayerCards(3),
+					"p2": NewPlayerCards(
+						cardsOf(deck.Spades, deck.Five, deck.Jack, deck.Queen),
+						cardsOf(deck.Spades, deck.Ace, deck.Eight, deck.King),
+						cardsOf(deck.Spades, deck.Three, deck.Seven, deck.Nine),
+						nil,
+					),
 				},
 			},
 		},
@@ -429,7 +639,8 @@ func TestGameBurn(t *testing.T) {
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
 			// Given a game in stage
-			game := ExistingShed(tc.opts)
+			game, err := ExistingShed(burnTheRest(t, tc.opts))
+			utils.AssertNoError(t, err)
 
 			msgs, err := game.Next()
 			utils.AssertNoError(t, err)
@@ -451,11 +662,18 @@ func TestGameBurn(t *testing.T) {
 			utils.AssertEqual(t, len(msgs), len(game.PlayerInfo))
 			checkBurnMessages(t, msgs, game)
 
+			// And everyone is told the current player will play again
+			for _, m := range msgs {
+				utils.AssertEqual(t, m.NextTurn, game.CurrentPlayer)
+			}
+
 			// But the deck has not been burned yet
 			utils.AssertTrue(t, len(game.Pile) > 0)
 
 			// And when the current player acks
 			previousPlayerID := game.CurrentPlayer.PlayerID
+			burning := copyCards(game.Pile)
+			previouslyBurned := len(game.Burned)
 			msgs, err = game.ReceiveResponse([]protocol.InboundMessage{{
 				PlayerID: game.CurrentPlayer.PlayerID,
 				Command:  protocol.Burn,
@@ -464,6 +682,17 @@ func TestGameBurn(t *testing.T) {
 
 			// Then the selected card has been burned along with the pile
 			utils.AssertEqual(t, len(game.Pile), 0)
+			utils.AssertEqual(t, len(game.Burned), previouslyBurned+len(burning))
+			utils.AssertDeepEqual(t, game.lastBurned(), burning)
+			utils.AssertNoError(t, validateStateMachine(game))
+
+			// And players are told about the burned cards
+			msgs, err = game.Next()
+			utils.AssertNoError(t, err)
+			for _, m := range msgs {
+				utils.AssertEqual(t, m.BurnedCount, len(game.Burned))
+				utils.AssertDeepEqual(t, m.LastBurned, burning)
+			}
 			// utils.AssertEqual(t, containsCard(game.PlayerCards[game.CurrentPlayer.PlayerID].Hand, targetCard), false)
 
 			if len(game.Deck) == 0 {
@@ -472,13 +701,21 @@ func TestGameBurn(t *testing.T) {
 
 			// And the current player gets another turn
 			utils.AssertEqual(t, game.CurrentPlayer.PlayerID, previousPlayerID)
+
+			// And the burn is only announced once
+			msgs, err = game.ReceiveResponse(firstLegalResponses(game))
+			utils.AssertNoError(t, err)
+			utils.AssertTrue(t, len(msgs) > 0)
+			for _, m := range msgs {
+				utils.AssertEqual(t, len(m.LastBurned), 0)
+			}
 		})
 	}
 
 	t.Run("Burn on UnseenSuccess", func(t *testing.T) {
 		// Given a game
 		ps3 := twoPlayers()
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearCards,
 			Deck:          []deck.Card{},
 			Pile:          []deck.Card{deck.NewCard(deck.Four, deck.Diamonds)},
@@ -489,9 +726,15 @@ func TestGameBurn(t *testing.T) {
 					deck.NewCard(deck.Ten, deck.Diamonds),
 					deck.NewCard(deck.Seven, deck.Diamonds),
 				}, nil),
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Five, deck.Six, deck.Jack),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Three, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		expectedCommand := protocol.PlayUnseen
 		decision := []int{0}
@@ -545,6 +788,833 @@ func TestGameBurn(t *testing.T) {
 		// Then the current player gets another turn
 		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, previousPlayerID)
 	})
+
+	t.Run("who plays after a burn", func(t *testing.T) {
+		burnEndsTurn := DefaultRules()
+		burnEndsTurn.BurnEndsTurn = true
+
+		tt := []struct {
+			name      string
+			rules     Rules
+			stage     Stage
+			cards     *PlayerCards
+			cmd       protocol.Cmd
+			want      string
+			finishing bool
+		}{
+			{
+				name:  "burn ends the turn under house rules",
+				rules: burnEndsTurn,
+				stage: clearDeck,
+				cards: NewPlayerCards([]deck.Card{
+					deck.NewCard(deck.Ten, deck.Hearts),
+					deck.NewCard(deck.Four, deck.Hearts),
+					deck.NewCard(deck.Five, deck.Hearts),
+				}, nil, nil, nil),
+				cmd:  protocol.PlayHand,
+				want: "p2",
+			},
+			{
+				name:  "burn ends the turn from an unseen card under house rules",
+				rules: burnEndsTurn,
+				stage: clearCards,
+				cards: NewPlayerCards(nil, nil, []deck.Card{
+					deck.NewCard(deck.Ten, deck.Hearts),
+					deck.NewCard(deck.Four, deck.Hearts),
+				}, nil),
+				cmd:  protocol.PlayUnseen,
+				want: "p2",
+			},
+			{
+				name:  "player finishes on a burn",
+				stage: clearCards,
+				cards: NewPlayerCards([]deck.Card{
+					deck.NewCard(deck.Ten, deck.Hearts),
+				}, nil, nil, nil),
+				cmd:       protocol.PlayHand,
+				want:      "p2",
+				finishing: true,
+			},
+			{
+				name:  "player finishes on a burn from an unseen card",
+				stage: clearCards,
+				cards: NewPlayerCards(nil, nil, []deck.Card{
+					deck.NewCard(deck.Ten, deck.Hearts),
+				}, nil),
+				cmd:       protocol.PlayUnseen,
+				want:      "p2",
+				finishing: true,
+			},
+			{
+				name:  "player finishes on a burn under house rules",
+				rules: burnEndsTurn,
+				stage: clearCards,
+				cards: NewPlayerCards([]deck.Card{
+					deck.NewCard(deck.Ten, deck.Hearts),
+				}, nil, nil, nil),
+				cmd:       protocol.PlayHand,
+				want:      "p2",
+				finishing: true,
+			},
+		}
+
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				// Given a game where p1 is about to burn the pile
+				ps := threePlayers()
+				opts := ShedOpts{
+					Stage:         tc.stage,
+					Deck:          cardsOf(deck.Clubs, deck.Ace, deck.Four, deck.Five, deck.Six, deck.Eight, deck.Jack, deck.Queen, deck.King),
+					Pile:          []deck.Card{deck.NewCard(deck.Four, deck.Diamonds)},
+					Players:       ps,
+					CurrentPlayer: ps[0],
+					Rules:         tc.rules,
+					PlayerCards: map[string]*PlayerCards{
+						"p1": tc.cards,
+						"p2": NewPlayerCards(
+							cardsOf(deck.Diamonds, deck.Five, deck.Six, deck.Jack),
+							cardsOf(deck.Diamonds, deck.Ace, deck.Queen, deck.King),
+							cardsOf(deck.Diamonds, deck.Seven, deck.Eight, deck.Nine),
+							nil,
+						),
+						"p3": NewPlayerCards(
+							cardsOf(deck.Spades, deck.Four, deck.Five, deck.Six),
+							cardsOf(deck.Spades, deck.Jack, deck.Queen, deck.King),
+							cardsOf(deck.Spades, deck.Ace, deck.Eight, deck.Nine),
+							nil,
+						),
+					},
+				}
+				if tc.stage == clearCards {
+					opts.Deck = []deck.Card{}
+				}
+				game, err := ExistingShed(burnTheRest(t, opts))
+				utils.AssertNoError(t, err)
+
+				_, err = game.Next()
+				utils.AssertNoError(t, err)
+				utils.AssertEqual(t, game.AwaitingResponse(), tc.cmd)
+
+				// When they play the Ten
+				msgs, err := game.ReceiveResponse([]protocol.InboundMessage{{
+					PlayerID: "p1",
+					Command:  tc.cmd,
+					Decision: []int{0},
+				}})
+				utils.AssertNoError(t, err)
+				if tc.cmd == protocol.PlayUnseen {
+					msgs, err = game.ReceiveResponse([]protocol.InboundMessage{{
+						PlayerID: "p1",
+						Command:  protocol.UnseenSuccess,
+					}})
+					utils.AssertNoError(t, err)
+				}
+
+				// Then everyone is told who plays next
+				utils.AssertEqual(t, game.AwaitingResponse(), protocol.Burn)
+				checkBurnMessages(t, msgs, game)
+				for _, m := range msgs {
+					utils.AssertEqual(t, m.NextTurn.PlayerID, tc.want)
+				}
+
+				// And once the burn is acknowledged
+				msgs, err = game.ReceiveResponse([]protocol.InboundMessage{{
+					PlayerID: "p1",
+					Command:  protocol.Burn,
+				}})
+				utils.AssertNoError(t, err)
+				utils.AssertNoError(t, validateStateMachine(game))
+
+				// A finished player leaves the game
+				if tc.finishing {
+					utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayerFinished)
+					checkPlayerFinishedMessages(t, msgs, game)
+
+					_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+						PlayerID: "p1",
+						Command:  protocol.PlayerFinished,
+					}})
+					utils.AssertNoError(t, err)
+					utils.AssertTrue(t, sliceContainsPlayerID(game.FinishedPlayers, "p1"))
+				}
+
+				// And it is the next player's turn
+				utils.AssertEqual(t, game.AwaitingResponse(), protocol.Null)
+				utils.AssertEqual(t, game.CurrentPlayer.PlayerID, tc.want)
+			})
+		}
+	})
+}
+
+func TestGamePlayHandAndSeen(t *testing.T) {
+	gameWithMatchingCards := func() *shed {
+		ps := twoPlayers()
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearCards,
+			Deck:          []deck.Card{},
+			Pile:          []deck.Card{deck.NewCard(deck.Four, deck.Hearts)},
+			Players:       ps,
+			CurrentPlayer: ps[0],
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards(
+					[]deck.Card{deck.NewCard(deck.Jack, deck.Diamonds)},
+					[]deck.Card{
+						deck.NewCard(deck.King, deck.Hearts),
+						deck.NewCard(deck.Jack, deck.Spades),
+						deck.NewCard(deck.Jack, deck.Clubs),
+					},
+					cardsOf(deck.Hearts, deck.Five, deck.Six, deck.Jack),
+					nil,
+				),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Seven, deck.Eight, deck.Nine),
+					nil,
+				),
+			},
+		}))
+		utils.AssertNoError(t, err)
+		return game
+	}
+
+	t.Run("player is offered matching hand and seen cards", func(t *testing.T) {
+		game := gameWithMatchingCards()
+
+		msgs, err := game.Next()
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayHandAndSeen)
+		checkNextMessages(t, msgs, protocol.PlayHandAndSeen, game)
+		utils.AssertDeepEqual(t, getMoves(msgs, "p1"), []int{0, 2, 3})
+	})
+
+	t.Run("player plays hand and seen cards together", func(t *testing.T) {
+		game := gameWithMatchingCards()
+		_, err := game.Next()
+		utils.AssertNoError(t, err)
+
+		pc := game.PlayerCards["p1"]
+		toPlay := []deck.Card{pc.Hand[0], pc.Seen[1], pc.Seen[2]}
+
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p1",
+			Command:  protocol.PlayHandAndSeen,
+			Decision: []int{0, 2, 3},
+		}})
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.EndOfTurn)
+		utils.AssertEqual(t, len(pc.Hand), 0)
+		utils.AssertEqual(t, len(pc.Seen), 1)
+		utils.AssertDeepEqual(t, game.Pile[1:], toPlay)
+		utils.AssertNoError(t, validateStateMachine(game))
+	})
+
+	t.Run("player plays hand and seen cards by ID", func(t *testing.T) {
+		game := gameWithMatchingCards()
+		_, err := game.Next()
+		utils.AssertNoError(t, err)
+
+		pc := game.PlayerCards["p1"]
+		jackOfSpades := pc.Seen[1]
+
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p1",
+			Command:  protocol.PlayHandAndSeen,
+			CardIDs:  []int{pc.Hand[0].ID, jackOfSpades.ID},
+		}})
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, len(pc.Seen), 2)
+		utils.AssertEqual(t, game.Pile[len(game.Pile)-1], jackOfSpades)
+	})
+
+	tt := []struct {
+		name     string
+		decision []int
+		reason   protocol.MoveErrorReason
+	}{
+		{"seen cards without the hand", []int{2}, protocol.SeenBeforeHand},
+		{"unmatched seen card", []int{0, 1}, protocol.RankMismatch},
+		{"same card twice", []int{0, 2, 2}, protocol.DuplicateCard},
+		{"no cards", []int{}, protocol.NoCardsChosen},
+	}
+
+	for _, tc := range tt {
+		t.Run("rejects "+tc.name, func(t *testing.T) {
+			game := gameWithMatchingCards()
+			_, err := game.Next()
+			utils.AssertNoError(t, err)
+
+			msgs, err := game.ReceiveResponse([]protocol.InboundMessage{{
+				PlayerID: "p1",
+				Command:  protocol.PlayHandAndSeen,
+				Decision: tc.decision,
+			}})
+			utils.AssertTrue(t, errors.Is(err, ErrInvalidMove))
+			utils.AssertEqual(t, len(msgs), 1)
+			utils.AssertEqual(t, msgs[0].MoveError.Reason, tc.reason)
+			utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayHandAndSeen)
+			utils.AssertEqual(t, len(game.Pile), 1)
+		})
+	}
+}
+
+func TestGameMoveErrors(t *testing.T) {
+	gameAwaitingPlay := func() *shed {
+		ps := threePlayers()
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:           clearDeck,
+			ExpectedCommand: protocol.PlayHand,
+			Deck:            cardsOf(deck.Hearts, deck.Five, deck.Six, deck.Jack, deck.Queen),
+			Pile:            []deck.Card{deck.NewCard(deck.King, deck.Spades)},
+			Players:         ps,
+			CurrentPlayer:   ps[0],
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards([]deck.Card{
+					deck.NewCard(deck.Ace, deck.Clubs),
+					deck.NewCard(deck.Four, deck.Hearts),
+					deck.NewCard(deck.Ten, deck.Diamonds),
+				}, nil, nil, nil),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+				"p3": NewPlayerCards(
+					cardsOf(deck.Spades, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Spades, deck.Ace, deck.Jack, deck.Queen),
+					cardsOf(deck.Spades, deck.Seven, deck.Eight, deck.Nine),
+					nil,
+				),
+			},
+		}))
+		utils.AssertNoError(t, err)
+		return game
+	}
+
+	tt := []struct {
+		name   string
+		msg    protocol.InboundMessage
+		reason protocol.MoveErrorReason
+		card   *deck.Card
+	}{
+		{
+			name:   "message from another player",
+			msg:    protocol.InboundMessage{PlayerID: "p2", Command: protocol.PlayHand, Decision: []int{0}},
+			reason: protocol.NotYourTurn,
+		},
+		{
+			name:   "wrong command",
+			msg:    protocol.InboundMessage{PlayerID: "p1", Command: protocol.PlayUnseen, Decision: []int{0}},
+			reason: protocol.WrongCommand,
+		},
+		{
+			name:   "card that does not exist",
+			msg:    protocol.InboundMessage{PlayerID: "p1", Command: protocol.PlayHand, Decision: []int{3}},
+			reason: protocol.IndexOutOfRange,
+		},
+		{
+			name:   "card lower than the pile",
+			msg:    protocol.InboundMessage{PlayerID: "p1", Command: protocol.PlayHand, Decision: []int{1}},
+			reason: protocol.BelowTopCard,
+			card:   &deck.Card{Rank: deck.Four, Suit: deck.Hearts},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run("explains "+tc.name, func(t *testing.T) {
+			game := gameAwaitingPlay()
+
+			msgs, err := game.ReceiveResponse([]protocol.InboundMessage{tc.msg})
+			utils.AssertTrue(t, errors.Is(err, ErrInvalidMove))
+
+			// Only the sender is told why
+			utils.AssertEqual(t, len(msgs), 1)
+			utils.AssertEqual(t, msgs[0].PlayerID, tc.msg.PlayerID)
+			utils.AssertEqual(t, msgs[0].Command, protocol.Error)
+			utils.AssertEqual(t, msgs[0].MoveError.Reason, tc.reason)
+			utils.AssertNotEmptyString(t, msgs[0].MoveError.Message)
+			if tc.card != nil {
+				utils.AssertEqual(t, msgs[0].MoveError.Card.Rank, tc.card.Rank)
+				utils.AssertEqual(t, msgs[0].MoveError.Card.Suit, tc.card.Suit)
+			}
+
+			// And the game still awaits the current player's move
+			utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayHand)
+			utils.AssertEqual(t, len(game.Pile), 1)
+		})
+	}
+}
+
+func TestGameSnap(t *testing.T) {
+	snapRules := DefaultRules()
+	snapRules.Snap = true
+
+	// p1 is about to play a Nine, which p3 and p4 can both snap
+	gameWithSnaps := func(t *testing.T, rules Rules, pile []deck.Card) *shed {
+		t.Helper()
+		ps := fourPlayers()
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearDeck,
+			Deck:          cardsOf(deck.Diamonds, deck.Three, deck.Four, deck.Five, deck.Six, deck.Eight, deck.Jack, deck.Queen, deck.King),
+			Pile:          pile,
+			Players:       ps,
+			CurrentPlayer: ps[0],
+			Rules:         rules,
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards([]deck.Card{
+					deck.NewCard(deck.Nine, deck.Diamonds),
+					deck.NewCard(deck.Five, deck.Clubs),
+					deck.NewCard(deck.King, deck.Clubs),
+				}, nil, nil, nil),
+				"p2": NewPlayerCards([]deck.Card{
+					deck.NewCard(deck.Ace, deck.Hearts),
+					deck.NewCard(deck.Eight, deck.Hearts),
+					deck.NewCard(deck.Seven, deck.Hearts),
+				}, nil, nil, nil),
+				"p3": NewPlayerCards([]deck.Card{
+					deck.NewCard(deck.Nine, deck.Clubs),
+					deck.NewCard(deck.Four, deck.Spades),
+					deck.NewCard(deck.Six, deck.Spades),
+				}, nil, nil, nil),
+				"p4": NewPlayerCards([]deck.Card{
+					deck.NewCard(deck.Nine, deck.Spades),
+					deck.NewCard(deck.Jack, deck.Spades),
+					deck.NewCard(deck.Queen, deck.Spades),
+				}, nil, nil, nil),
+			},
+		}))
+		utils.AssertNoError(t, err)
+		return game
+	}
+
+	playNine := func(t *testing.T, game *shed) {
+		t.Helper()
+		_, err := game.Next()
+		utils.AssertNoError(t, err)
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p1",
+			Command:  protocol.PlayHand,
+			Decision: []int{0},
+		}})
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.ReplenishHand)
+	}
+
+	snap := func(playerID string) protocol.InboundMessage {
+		return protocol.InboundMessage{PlayerID: playerID, Command: protocol.Snap, Decision: []int{0}}
+	}
+
+	t.Run("play carries on from the player who snapped", func(t *testing.T) {
+		// Given p1 has just played a Nine
+		game := gameWithSnaps(t, snapRules, []deck.Card{deck.NewCard(deck.Four, deck.Hearts)})
+		playNine(t, game)
+		snapped := game.PlayerCards["p3"].Hand[0]
+
+		// When p3 snaps with their Nine
+		msgs, err := game.ReceiveResponse([]protocol.InboundMessage{snap("p3")})
+		utils.AssertNoError(t, err)
+
+		// Then it is on the pile, and p3 has replenished their hand
+		utils.AssertEqual(t, game.Pile[len(game.Pile)-1], snapped)
+		utils.AssertEqual(t, len(game.PlayerCards["p3"].Hand), 3)
+		utils.AssertNoError(t, validateStateMachine(game))
+
+		// And p3 becomes the current player, with p4 to follow
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p3")
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.ReplenishHand)
+		for _, m := range msgs {
+			utils.AssertEqual(t, m.NextTurn.PlayerID, "p4")
+		}
+
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p3",
+			Command:  protocol.ReplenishHand,
+		}})
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p4")
+	})
+
+	t.Run("the first player in turn order wins simultaneous snaps", func(t *testing.T) {
+		game := gameWithSnaps(t, snapRules, []deck.Card{deck.NewCard(deck.Four, deck.Hearts)})
+		playNine(t, game)
+		p4Hand := copyCards(game.PlayerCards["p4"].Hand)
+
+		_, err := game.ReceiveResponse([]protocol.InboundMessage{snap("p4"), snap("p3")})
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p3")
+		utils.AssertDeepEqual(t, game.PlayerCards["p4"].Hand, p4Hand)
+	})
+
+	t.Run("turn order is followed backwards when reversed", func(t *testing.T) {
+		game := gameWithSnaps(t, snapRules, []deck.Card{deck.NewCard(deck.Four, deck.Hearts)})
+		game.Direction = Backwards
+		playNine(t, game)
+
+		_, err := game.ReceiveResponse([]protocol.InboundMessage{snap("p3"), snap("p4")})
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p4")
+	})
+
+	t.Run("snaps cannot be taken back, however many are resolved at once", func(t *testing.T) {
+		for _, snaps := range [][]protocol.InboundMessage{
+			{snap("p3")},
+			{snap("p3"), snap("p4")},
+		} {
+			// Given p1 could take back the Nine they have just played
+			rules := snapRules
+			rules.UndoLimit = 3
+			game := gameWithSnaps(t, rules, []deck.Card{deck.NewCard(deck.Four, deck.Hearts)})
+			playNine(t, game)
+			utils.AssertTrue(t, game.canUndo())
+
+			// When the snaps are resolved
+			_, err := game.ReceiveResponse(snaps)
+			utils.AssertNoError(t, err)
+			after := game.Snapshot()
+
+			// Then p1 can no longer undo, and the snap stands
+			_, err = game.ReceiveResponse([]protocol.InboundMessage{{PlayerID: "p1", Command: protocol.Undo}})
+			utils.AssertTrue(t, errors.Is(err, ErrCannotUndo))
+			utils.AssertDeepEqual(t, game.Snapshot(), after)
+			utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p3")
+		}
+	})
+
+	t.Run("a snap can burn the pile", func(t *testing.T) {
+		rules := snapRules
+		rules.BurnCount = 3
+		game := gameWithSnaps(t, rules, []deck.Card{deck.NewCard(deck.Nine, deck.Hearts)})
+		playNine(t, game)
+
+		msgs, err := game.ReceiveResponse([]protocol.InboundMessage{snap("p4")})
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p4")
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.Burn)
+		checkBurnMessages(t, msgs, game)
+	})
+
+	tt := []struct {
+		name      string
+		rules     Rules
+		playFirst bool
+		msg       protocol.InboundMessage
+	}{
+		{
+			name:      "snaps not allowed",
+			rules:     DefaultRules(),
+			playFirst: true,
+			msg:       snap("p3"),
+		},
+		{
+			name:  "nothing to snap",
+			rules: snapRules,
+			msg:   snap("p3"),
+		},
+		{
+			name:      "current player snaps",
+			rules:     snapRules,
+			playFirst: true,
+			msg:       snap("p1"),
+		},
+		{
+			name:      "card does not match",
+			rules:     snapRules,
+			playFirst: true,
+			msg:       protocol.InboundMessage{PlayerID: "p3", Command: protocol.Snap, Decision: []int{1}},
+		},
+		{
+			name:      "no such card",
+			rules:     snapRules,
+			playFirst: true,
+			msg:       protocol.InboundMessage{PlayerID: "p3", Command: protocol.Snap, Decision: []int{7}},
+		},
+		{
+			name:      "no cards",
+			rules:     snapRules,
+			playFirst: true,
+			msg:       protocol.InboundMessage{PlayerID: "p3", Command: protocol.Snap},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run("rejects snap when "+tc.name, func(t *testing.T) {
+			game := gameWithSnaps(t, tc.rules, []deck.Card{deck.NewCard(deck.Four, deck.Hearts)})
+			if tc.playFirst {
+				playNine(t, game)
+			} else {
+				_, err := game.Next()
+				utils.AssertNoError(t, err)
+			}
+			before := game.Snapshot()
+
+			msgs, err := game.ReceiveResponse([]protocol.InboundMessage{tc.msg})
+
+			utils.AssertTrue(t, errors.Is(err, ErrInvalidSnap))
+			utils.AssertEqual(t, len(msgs), 1)
+			utils.AssertEqual(t, msgs[0].PlayerID, tc.msg.PlayerID)
+			utils.AssertDeepEqual(t, game.Snapshot(), before)
+		})
+	}
+}
+
+func TestGameTurnOrder(t *testing.T) {
+	eightsAndNines := DefaultRules()
+	eightsAndNines.Skip = []deck.Rank{deck.Eight}
+	eightsAndNines.Reverse = []deck.Rank{deck.Nine}
+
+	t.Run("turns go backwards when reversed", func(t *testing.T) {
+		ps := fourPlayers()
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearDeck,
+			Deck:          cardsOf(deck.Hearts, deck.Four, deck.Five, deck.Six, deck.Jack),
+			Players:       ps,
+			CurrentPlayer: ps[1],
+			Direction:     Backwards,
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards(
+					cardsOf(deck.Clubs, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Clubs, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Clubs, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+				"p3": NewPlayerCards(
+					cardsOf(deck.Spades, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Spades, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Spades, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+				"p4": NewPlayerCards(
+					cardsOf(deck.Hearts, deck.Ace, deck.Queen, deck.King),
+					cardsOf(deck.Hearts, deck.Seven, deck.Eight, deck.Nine),
+					cardsOf(deck.Hearts, deck.Two, deck.Three, deck.Ten),
+					nil,
+				),
+			},
+		}))
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, game.nextPlayer(), ps[0])
+		game.turn()
+		utils.AssertEqual(t, game.CurrentPlayer, ps[0])
+		game.turn()
+		utils.AssertEqual(t, game.CurrentPlayer, ps[3])
+	})
+
+	t.Run("skipped players miss their turn", func(t *testing.T) {
+		ps := fourPlayers()
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearDeck,
+			Deck:          cardsOf(deck.Hearts, deck.Four, deck.Five, deck.Six, deck.Jack),
+			Players:       ps,
+			CurrentPlayer: ps[2],
+			SkipCount:     1,
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards(
+					cardsOf(deck.Clubs, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Clubs, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Clubs, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+				"p3": NewPlayerCards(
+					cardsOf(deck.Spades, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Spades, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Spades, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+				"p4": NewPlayerCards(
+					cardsOf(deck.Hearts, deck.Ace, deck.Queen, deck.King),
+					cardsOf(deck.Hearts, deck.Seven, deck.Eight, deck.Nine),
+					cardsOf(deck.Hearts, deck.Two, deck.Three, deck.Ten),
+					nil,
+				),
+			},
+		}))
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, game.nextPlayer(), ps[0])
+		game.turn()
+		utils.AssertEqual(t, game.CurrentPlayer, ps[0])
+		utils.AssertEqual(t, game.SkipCount, 0)
+	})
+
+	tt := []struct {
+		name      string
+		rules     Rules
+		stage     Stage
+		direction Direction
+		hand      []deck.Card
+		decision  []int
+		want      string
+		wantDir   Direction
+	}{
+		{
+			name:     "eight skips the next player",
+			rules:    eightsAndNines,
+			stage:    clearDeck,
+			hand:     []deck.Card{deck.NewCard(deck.Eight, deck.Hearts), deck.NewCard(deck.Ace, deck.Hearts), deck.NewCard(deck.Four, deck.Clubs)},
+			decision: []int{0},
+			want:     "p3",
+		},
+		{
+			name:     "two eights skip two players",
+			rules:    eightsAndNines,
+			stage:    clearDeck,
+			hand:     []deck.Card{deck.NewCard(deck.Eight, deck.Hearts), deck.NewCard(deck.Eight, deck.Clubs), deck.NewCard(deck.Four, deck.Clubs)},
+			decision: []int{0, 1},
+			want:     "p4",
+		},
+		{
+			name:     "nine reverses direction",
+			rules:    eightsAndNines,
+			stage:    clearDeck,
+			hand:     []deck.Card{deck.NewCard(deck.Nine, deck.Hearts), deck.NewCard(deck.Ace, deck.Hearts), deck.NewCard(deck.Four, deck.Clubs)},
+			decision: []int{0},
+			want:     "p4",
+			wantDir:  Backwards,
+		},
+		{
+			name:      "two nines cancel out",
+			rules:     eightsAndNines,
+			stage:     clearDeck,
+			direction: Backwards,
+			hand:      []deck.Card{deck.NewCard(deck.Nine, deck.Hearts), deck.NewCard(deck.Nine, deck.Clubs), deck.NewCard(deck.Four, deck.Clubs)},
+			decision:  []int{0, 1},
+			want:      "p4",
+			wantDir:   Backwards,
+		},
+		{
+			name:     "eight is an ordinary card under the default rules",
+			stage:    clearDeck,
+			hand:     []deck.Card{deck.NewCard(deck.Eight, deck.Hearts), deck.NewCard(deck.Ace, deck.Hearts), deck.NewCard(deck.Four, deck.Clubs)},
+			decision: []int{0},
+			want:     "p2",
+		},
+		{
+			name:     "skip on the last card skips the player after the finisher",
+			rules:    eightsAndNines,
+			stage:    clearCards,
+			hand:     []deck.Card{deck.NewCard(deck.Eight, deck.Hearts)},
+			decision: []int{0},
+			want:     "p3",
+		},
+		{
+			name:      "finishing backwards passes to the previous player",
+			rules:     eightsAndNines,
+			stage:     clearCards,
+			direction: Backwards,
+			hand:      []deck.Card{deck.NewCard(deck.Four, deck.Hearts)},
+			decision:  []int{0},
+			want:      "p4",
+			wantDir:   Backwards,
+		},
+		{
+			name:     "reversing on the last card passes to the previous player",
+			rules:    eightsAndNines,
+			stage:    clearCards,
+			hand:     []deck.Card{deck.NewCard(deck.Nine, deck.Hearts)},
+			decision: []int{0},
+			want:     "p4",
+			wantDir:  Backwards,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given a game where p1 is about to play
+			ps := fourPlayers()
+			opts := ShedOpts{
+				Stage:         tc.stage,
+				Deck:          cardsOf(deck.Hearts, deck.Two, deck.Three, deck.Five, deck.Six, deck.Seven, deck.Jack, deck.Queen, deck.King),
+				Players:       ps,
+				CurrentPlayer: ps[0],
+				Direction:     tc.direction,
+				Rules:         tc.rules,
+				PlayerCards: map[string]*PlayerCards{
+					"p1": NewPlayerCards(tc.hand, nil, nil, nil),
+					"p2": NewPlayerCards(
+						cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+						cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+						cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+						nil,
+					),
+					"p3": NewPlayerCards(
+						cardsOf(deck.Spades, deck.Four, deck.Five, deck.Six),
+						cardsOf(deck.Spades, deck.Jack, deck.Queen, deck.King),
+						cardsOf(deck.Spades, deck.Ace, deck.Eight, deck.Nine),
+						nil,
+					),
+					"p4": NewPlayerCards(
+						cardsOf(deck.Clubs, deck.Five, deck.Six, deck.Seven),
+						cardsOf(deck.Clubs, deck.Jack, deck.Queen, deck.King),
+						cardsOf(deck.Clubs, deck.Ace, deck.Two, deck.Three),
+						nil,
+					),
+				},
+			}
+			if tc.stage == clearCards {
+				opts.Deck = []deck.Card{}
+			}
+			game, err := ExistingShed(burnTheRest(t, opts))
+			utils.AssertNoError(t, err)
+
+			_, err = game.Next()
+			utils.AssertNoError(t, err)
+			utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayHand)
+
+			// When they play their cards
+			msgs, err := game.ReceiveResponse([]protocol.InboundMessage{{
+				PlayerID: "p1",
+				Command:  protocol.PlayHand,
+				Decision: tc.decision,
+			}})
+			utils.AssertNoError(t, err)
+
+			// Then everyone is told who plays next
+			for _, m := range msgs {
+				utils.AssertEqual(t, m.NextTurn.PlayerID, tc.want)
+			}
+
+			// And once the player acks, it is that player's turn
+			_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+				PlayerID: "p1",
+				Command:  game.AwaitingResponse(),
+			}})
+			utils.AssertNoError(t, err)
+			utils.AssertEqual(t, game.CurrentPlayer.PlayerID, tc.want)
+			utils.AssertEqual(t, game.Direction, tc.wantDir)
+			utils.AssertEqual(t, game.SkipCount, 0)
+			utils.AssertNoError(t, validateStateMachine(game))
+		})
+	}
+
+	t.Run("games can be played with skips and reversals", func(t *testing.T) {
+		rules := eightsAndNines
+		rules.Reverse = append(rules.Reverse, deck.Queen)
+
+		game, err := NewShed(ShedOpts{Players: fourPlayers(), Rules: rules, Seed: 9})
+		utils.AssertNoError(t, err)
+
+		playGame(t, game, 3000)
+	})
 }
 
 func checkBaseMessage(t *testing.T, m protocol.OutboundMessage, game *shed) {
@@ -672,3 +1742,66 @@ func getNonBurnMove(cards []deck.Card, moves []int) int {
 	}
 	panic(fmt.Sprintf("all cards are a Ten: %v", cards))
 }
+
+// playGame plays a game using the first legal move available at each turn,
+// returning every message the game sent. It stops after maxSteps.
+func playGame(t *testing.T, game *shed, maxSteps int) []protocol.OutboundMessage {
+	t.Helper()
+
+	transcript := []protocol.OutboundMessage{}
+	for step := 0; step < maxSteps && !game.GameOver(); step++ {
+		var (
+			msgs []protocol.OutboundMessage
+			err  error
+		)
+
+		if game.AwaitingResponse() == protocol.Null {
+			msgs, err = game.Next()
+		} else {
+			msgs, err = game.ReceiveResponse(firstLegalResponses(game))
+		}
+		utils.AssertNoError(t, err)
+		utils.AssertNoError(t, validateStateMachine(game))
+
+		transcript = append(transcript, msgs...)
+	}
+
+	return transcript
+}
+
+func firstLegalResponses(game *shed) []protocol.InboundMessage {
+	cmd := game.AwaitingResponse()
+	playerID := game.CurrentPlayer.PlayerID
+
+	switch cmd {
+	case protocol.Reorg:
+		responses := []protocol.InboundMessage{}
+		for _, p := range game.PlayerInfo {
+			responses = append(responses, protocol.InboundMessage{
+				PlayerID: p.PlayerID,
+				Command:  protocol.Reorg,
+				Decision: []int{0, 1, 2},
+			})
+		}
+		return responses
+
+	case protocol.PlayHand, protocol.PlaySeen:
+		cards := game.PlayerCards[playerID].Hand
+		if cmd == protocol.PlaySeen {
+			cards = game.PlayerCards[playerID].Seen
+		}
+		moves := game.Rules.legalMoves(game.Pile, cards)
+		return []protocol.InboundMessage{{PlayerID: playerID, Command: cmd, Decision: moves[:1]}}
+
+	case protocol.PlayHandAndSeen:
+		pc := game.PlayerCards[playerID]
+		moves := game.Rules.legalHandAndSeenMoves(game.Pile, pc.Hand, pc.Seen)
+		return []protocol.InboundMessage{{PlayerID: playerID, Command: cmd, Decision: moves}}
+
+	case protocol.PlayUnseen:
+		return []protocol.InboundMessage{{PlayerID: playerID, Command: cmd, Decision: []int{0}}}
+	}
+
+	// acknowledgement
+	return []protocol.InboundMessage{{PlayerID: playerID, Command: cmd}}
+}
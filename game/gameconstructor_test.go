@@ -1,10 +1,11 @@
 package game
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/minaorangina/shed/deck"
-	"github.com/minaorangina/shed/internal"
 	utils "github.com/minaorangina/shed/internal"
 	"github.com/minaorangina/shed/protocol"
 	"github.com/stretchr/testify/assert"
@@ -14,7 +15,7 @@ func TestNewShedNewGame(t *testing.T) {
 	t.Run("game with no options sets up correctly", func(t *testing.T) {
 		t.Log("When a new game is created")
 		playerInfo := fourPlayers()
-		game, err := NewShed(playerInfo)
+		game, err := NewShed(ShedOpts{Players: playerInfo})
 		utils.AssertNoError(t, err)
 
 		t.Log("Then the players are initiated correctly")
@@ -41,6 +42,41 @@ func TestNewShedNewGame(t *testing.T) {
 	})
 }
 
+func TestNewShedManyPlayers(t *testing.T) {
+	for numPlayers := 5; numPlayers <= maxPlayers; numPlayers++ {
+		t.Run(fmt.Sprintf("%d players", numPlayers), func(t *testing.T) {
+			players := []protocol.Player{}
+			for i := 0; i < numPlayers; i++ {
+				players = append(players, protocol.Player{PlayerID: fmt.Sprintf("p%d", i+1)})
+			}
+
+			// When a game for more than four players is created
+			game, err := NewShed(ShedOpts{Players: players, Seed: int64(numPlayers)})
+			utils.AssertNoError(t, err)
+
+			// Then it is dealt from two decks
+			dealt := len(game.Deck)
+			for _, pc := range game.PlayerCards {
+				dealt += len(pc.Hand) + len(pc.Seen) + len(pc.Unseen)
+			}
+			utils.AssertEqual(t, dealt, 2*len(deck.New()))
+			utils.AssertNoError(t, validateStateMachine(game))
+
+			// And it can be played with duplicate cards
+			playGame(t, game, 2000)
+		})
+	}
+
+	t.Run("too many players", func(t *testing.T) {
+		players := []protocol.Player{}
+		for i := 0; i <= maxPlayers; i++ {
+			players = append(players, protocol.Player{PlayerID: fmt.Sprintf("p%d", i+1)})
+		}
+		_, err := NewShed(ShedOpts{Players: players})
+		utils.AssertEqual(t, err, ErrTooManyPlayers)
+	})
+}
+
 func TestNewShedExistingGame(t *testing.T) {
 	shouldSucceed := []struct {
 		name     string
@@ -114,24 +150,191 @@ func TestNewShedExistingGame(t *testing.T) {
 
 	for _, tc := range shouldSucceed {
 		t.Run(tc.name, func(t *testing.T) {
-			internal.ShouldNotPanic(t, func() {
-				ExistingShed(tc.gameOpts())
-			})
+			_, err := ExistingShed(tc.gameOpts())
+			utils.AssertNoError(t, err)
 		})
 	}
 }
 
 func TestNewShedExistingGameFailures(t *testing.T) {
+	// validOpts describes a game midway through clearing the deck
+	validOpts := func() ShedOpts {
+		d := deck.New()
+		plrs := threePlayers()
+
+		cards := map[string]*PlayerCards{}
+		for _, p := range plrs {
+			cards[p.PlayerID] = NewPlayerCards(d.Deal(3), d.Deal(3), d.Deal(3), nil)
+		}
+
+		return ShedOpts{
+			Deck:            d,
+			Pile:            d.Deal(2),
+			PlayerCards:     cards,
+			Players:         plrs,
+			CurrentPlayer:   plrs[0],
+			Stage:           clearDeck,
+			ExpectedCommand: protocol.PlayHand,
+		}
+	}
+
 	tt := []struct {
 		name     string
 		gameOpts func() ShedOpts
-	}{}
+	}{
+		{
+			name: "command impossible in stage",
+			gameOpts: func() ShedOpts {
+				opts := validOpts()
+				opts.ExpectedCommand = protocol.PlayUnseen
+				return opts
+			},
+		},
+		{
+			name: "duplicate cards",
+			gameOpts: func() ShedOpts {
+				opts := validOpts()
+				opts.Pile = append(opts.Pile, opts.Deck[0])
+				return opts
+			},
+		},
+		{
+			name: "more cards than a deck",
+			gameOpts: func() ShedOpts {
+				opts := validOpts()
+				opts.Pile = append(opts.Pile, deck.New()...)
+				return opts
+			},
+		},
+		{
+			name: "empty deck while clearing the deck",
+			gameOpts: func() ShedOpts {
+				opts := validOpts()
+				opts.Deck = deck.Deck{}
+				return opts
+			},
+		},
+		{
+			name: "deck not empty while clearing cards",
+			gameOpts: func() ShedOpts {
+				opts := validOpts()
+				opts.Stage = clearCards
+				return opts
+			},
+		},
+		{
+			name: "finished players while clearing the deck",
+			gameOpts: func() ShedOpts {
+				opts := validOpts()
+				opts.PlayerCards["p3"] = NewPlayerCards(nil, nil, nil, nil)
+				opts.FinishedPlayers = []protocol.Player{opts.Players[2]}
+				return opts
+			},
+		},
+		{
+			name: "current player has finished",
+			gameOpts: func() ShedOpts {
+				opts := validOpts()
+				opts.Deck = deck.Deck{}
+				opts.Stage = clearCards
+				opts.PlayerCards["p1"] = NewPlayerCards(nil, nil, nil, nil)
+				opts.FinishedPlayers = []protocol.Player{opts.Players[0]}
+				return opts
+			},
+		},
+		{
+			name: "cards held by an unknown player",
+			gameOpts: func() ShedOpts {
+				opts := validOpts()
+				opts.PlayerCards["p4"] = NewPlayerCards(nil, nil, nil, nil)
+				return opts
+			},
+		},
+		{
+			name: "no players",
+			gameOpts: func() ShedOpts {
+				opts := validOpts()
+				opts.Players = nil
+				return opts
+			},
+		},
+		{
+			name: "unknown stage",
+			gameOpts: func() ShedOpts {
+				opts := validOpts()
+				opts.Stage = Stage(99)
+				return opts
+			},
+		},
+	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			internal.ShouldPanic(t, func() {
-				ExistingShed(tc.gameOpts())
-			})
+			game, err := ExistingShed(tc.gameOpts())
+
+			var stateErr *StateError
+			utils.AssertTrue(t, errors.As(err, &stateErr))
+			utils.AssertTrue(t, errors.Is(err, ErrInvalidGameState))
+			utils.AssertTrue(t, game == nil)
+		})
+	}
+}
+
+func TestNewShedSeed(t *testing.T) {
+	t.Run("same seed deals the same cards and starting player", func(t *testing.T) {
+		game1, err := NewShed(ShedOpts{Players: fourPlayers(), Seed: 1234})
+		utils.AssertNoError(t, err)
+		game2, err := NewShed(ShedOpts{Players: fourPlayers(), Seed: 1234})
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, game1.Seed, int64(1234))
+		utils.AssertDeepEqual(t, game1.Deck, game2.Deck)
+		utils.AssertDeepEqual(t, game1.PlayerCards, game2.PlayerCards)
+		utils.AssertEqual(t, game1.CurrentPlayer, game2.CurrentPlayer)
+	})
+
+	t.Run("different seeds deal different cards", func(t *testing.T) {
+		game1, err := NewShed(ShedOpts{Players: fourPlayers(), Seed: 1})
+		utils.AssertNoError(t, err)
+		game2, err := NewShed(ShedOpts{Players: fourPlayers(), Seed: 2})
+		utils.AssertNoError(t, err)
+
+		utils.AssertNotDeepEqual(t, game1.PlayerCards, game2.PlayerCards)
+	})
+
+	t.Run("a seed is generated if none is given", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers()})
+		utils.AssertNoError(t, err)
+		assert.NotZero(t, game.Seed)
+	})
+
+	t.Run("seed is honoured when starting a new game", func(t *testing.T) {
+		game1, err := ExistingShed(ShedOpts{Seed: 99})
+		utils.AssertNoError(t, err)
+		utils.AssertNoError(t, game1.Start(threePlayers()))
+		game2, err := ExistingShed(ShedOpts{Seed: 99})
+		utils.AssertNoError(t, err)
+		utils.AssertNoError(t, game2.Start(threePlayers()))
+
+		utils.AssertDeepEqual(t, game1.PlayerCards, game2.PlayerCards)
+		utils.AssertEqual(t, game1.CurrentPlayer, game2.CurrentPlayer)
+	})
+}
+
+func TestGameIsReproducible(t *testing.T) {
+	for _, seed := range []int64{1, 42, 2021} {
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			game1, err := NewShed(ShedOpts{Players: threePlayers(), Seed: seed})
+			utils.AssertNoError(t, err)
+			game2, err := NewShed(ShedOpts{Players: threePlayers(), Seed: seed})
+			utils.AssertNoError(t, err)
+
+			transcript1 := playGame(t, game1, 2000)
+			transcript2 := playGame(t, game2, 2000)
+
+			utils.AssertTrue(t, len(transcript1) > 0)
+			utils.AssertDeepEqual(t, transcript1, transcript2)
+			utils.AssertDeepEqual(t, game1.FinishedPlayers, game2.FinishedPlayers)
 		})
 	}
 }
@@ -1,153 +1,448 @@
 package game
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+
 	"github.com/minaorangina/shed/deck"
+	"github.com/minaorangina/shed/protocol"
 )
 
 const (
-	minPlayers = 2
-	maxPlayers = 4
-	burnNum    = 4
+	minPlayers        = 2
+	maxPlayers        = 8
+	maxPlayersPerDeck = 4
+	burnNum           = 4
+	moveLimit         = 2000
 )
 
-var cardValues = map[deck.Rank]int{
-	deck.Four:  0,
-	deck.Five:  1,
-	deck.Six:   2,
-	deck.Eight: 4,
-	deck.Nine:  5,
-	deck.Jack:  6,
-	deck.Queen: 7,
-	deck.King:  8,
-	deck.Ace:   9,
-	// special powers
-	deck.Two:   9,
-	deck.Three: 9,
-	deck.Seven: 3,
-	deck.Ten:   9,
-}
-
-var sevenBeaters = map[deck.Rank]bool{
-	deck.Four:  true,
-	deck.Five:  true,
-	deck.Six:   true,
-	deck.Eight: false,
-	deck.Nine:  false,
-	deck.Jack:  false,
-	deck.Queen: false,
-	deck.King:  false,
-	deck.Ace:   false,
-	// special powers
-	deck.Two:   true,
-	deck.Three: true,
-	deck.Seven: true,
+// decksFor returns how many decks are needed for the number of players:
+// one deck for up to four players, and two for up to eight.
+func decksFor(numPlayers int) int {
+	if numPlayers <= maxPlayersPerDeck {
+		return 1
+	}
+	return (numPlayers + maxPlayersPerDeck - 1) / maxPlayersPerDeck
 }
 
-func getLegalMoves(pile, toPlay []deck.Card) []int {
-	pileWithoutThrees := []deck.Card{}
-	// Filter out Threes
-	for _, c := range pile {
-		if c.Rank != deck.Three {
-			pileWithoutThrees = append(pileWithoutThrees, c)
+var ErrInvalidRules = errors.New("invalid rules")
+
+// NoMoveLimit, as the MoveLimit of the rules, lets a game go on for as many turns as it takes
+const NoMoveLimit = -1
+
+// MoveError explains why a move breaks the rules.
+// It matches ErrInvalidMove when used with errors.Is.
+type MoveError struct {
+	Reason protocol.MoveErrorReason
+	Detail string
+	Card   *deck.Card // the card that could not be played, if any
+}
+
+func newMoveError(reason protocol.MoveErrorReason, card *deck.Card, format string, args ...interface{}) *MoveError {
+	return &MoveError{Reason: reason, Detail: fmt.Sprintf(format, args...), Card: card}
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidMove, e.Detail)
+}
+
+func (e *MoveError) Is(target error) bool {
+	return target == ErrInvalidMove
+}
+
+// toProtocol returns the error as sent to players
+func (e *MoveError) toProtocol() *protocol.MoveError {
+	return &protocol.MoveError{Reason: e.Reason, Message: e.Detail, Card: e.Card}
+}
+
+// Rules declares the powers of each rank, so that games can be played with house rules.
+// Every rank from Ace to King is either ranked, or has one of the Wild, Burn,
+// Transparent or Mirror powers. Jokers need a place too if the game has any.
+type Rules struct {
+	// Ranking orders the ranks without powers, from lowest to highest.
+	// Ranks with powers are treated as equal to the highest rank.
+	Ranking []deck.Rank `json:"ranking"`
+	// Wild ranks can be played on any card, and any card can be played on them.
+	Wild []deck.Rank `json:"wild"`
+	// Burn ranks can be played on any card, and burn the pile.
+	Burn []deck.Rank `json:"burn"`
+	// Transparent ranks can be played on any card, and are ignored when deciding what can be played next.
+	Transparent []deck.Rank `json:"transparent"`
+	// Mirror ranks can be played on any card, and take on the rank of the card they are played on.
+	// On an empty pile they act as wild.
+	Mirror []deck.Rank `json:"mirror"`
+	// LowerThan ranks must be followed by a card of the same rank or lower.
+	LowerThan []deck.Rank `json:"lowerThan"`
+	// BurnCount is how many cards of the same rank burn the pile. Zero disables this.
+	BurnCount int `json:"burnCount"`
+	// Skip ranks make the next player miss their turn, once for each card played.
+	Skip []deck.Rank `json:"skip,omitempty"`
+	// Reverse ranks reverse the direction of play, once for each card played.
+	Reverse []deck.Rank `json:"reverse,omitempty"`
+	// BurnEndsTurn passes play to the next player after a burn.
+	// Otherwise the player who burned the pile plays again.
+	BurnEndsTurn bool `json:"burnEndsTurn,omitempty"`
+	// Snap lets any other player play cards matching the top of the pile out of turn,
+	// while the player who has just played is yet to acknowledge their move.
+	// Play then carries on from whoever snapped.
+	Snap bool `json:"snap,omitempty"`
+	// FirstPlayer decides who takes the first turn.
+	FirstPlayer FirstPlayerPolicy `json:"firstPlayer,omitempty"`
+	// UndoLimit is how many moves can be taken back with Undo. Zero disables undo.
+	UndoLimit int `json:"undoLimit,omitempty"`
+	// MoveLimit is how many turns a game may last before it is ended as a stalemate.
+	// If zero, a game may last 2000 turns. NoMoveLimit disables this.
+	MoveLimit int `json:"moveLimit,omitempty"`
+	// RepeatLimit is how many times the same position may start a turn before the game
+	// is ended as a stalemate. Zero disables this.
+	RepeatLimit int `json:"repeatLimit,omitempty"`
+	// Stalemate decides the result of a game ended as a stalemate.
+	Stalemate StalemateResult `json:"stalemate,omitempty"`
+}
+
+// DefaultRules returns the standard rules of Shed:
+// Two is wild, Ten burns, Three is transparent, Seven must be followed by a lower card,
+// and four of a kind burns. Whoever burns the pile plays again.
+// Jokers, if played with, mirror the card beneath them.
+// A game still going after 2000 turns is drawn, so that no game goes on forever.
+func DefaultRules() Rules {
+	return Rules{
+		Ranking: []deck.Rank{
+			deck.Four, deck.Five, deck.Six, deck.Seven, deck.Eight,
+			deck.Nine, deck.Jack, deck.Queen, deck.King, deck.Ace,
+		},
+		Wild:        []deck.Rank{deck.Two},
+		Burn:        []deck.Rank{deck.Ten},
+		Transparent: []deck.Rank{deck.Three},
+		Mirror:      []deck.Rank{deck.Joker},
+		LowerThan:   []deck.Rank{deck.Seven},
+		BurnCount:   burnNum,
+		MoveLimit:   moveLimit,
+	}
+}
+
+// RulesOf returns the rules the game is played by,
+// or the default rules if it is not a game of Shed
+func RulesOf(g Game) Rules {
+	if s, ok := g.(*shed); ok {
+		return s.Rules
+	}
+	return DefaultRules()
+}
+
+// hasCardPowers reports whether any rank has been given a place or a power.
+// The other fields are options for how the game is played, and do not count.
+func (r Rules) hasCardPowers() bool {
+	return len(r.Ranking) > 0 || len(r.Wild) > 0 || len(r.Burn) > 0 ||
+		len(r.Transparent) > 0 || len(r.Mirror) > 0 || len(r.LowerThan) > 0 || r.BurnCount > 0
+}
+
+// orDefault returns the default rules if none have been set.
+// Otherwise it returns the rules, with the default card powers if none have been set,
+// the default move limit if none has been set, and the game's other options as they are.
+func (r Rules) orDefault() Rules {
+	if reflect.ValueOf(r).IsZero() {
+		return DefaultRules()
+	}
+	if r.MoveLimit == 0 {
+		r.MoveLimit = moveLimit
+	}
+	if r.hasCardPowers() {
+		return r
+	}
+	d := DefaultRules()
+	r.Ranking, r.Wild, r.Burn, r.Transparent, r.Mirror = d.Ranking, d.Wild, d.Burn, d.Transparent, d.Mirror
+	r.LowerThan, r.BurnCount = d.LowerThan, d.BurnCount
+	return r
+}
+
+func (r Rules) places() map[deck.Rank]int {
+	places := map[deck.Rank]int{}
+	for _, group := range [][]deck.Rank{r.Ranking, r.Wild, r.Burn, r.Transparent, r.Mirror} {
+		for _, rank := range group {
+			places[rank]++
+		}
+	}
+	return places
+}
+
+// Validate checks that every rank has exactly one place in the rules
+func (r Rules) Validate() error {
+	places := r.places()
+
+	for rank := deck.Ace; rank <= deck.King; rank++ {
+		if places[rank] != 1 {
+			return fmt.Errorf("%w: %s must be ranked or have exactly one power", ErrInvalidRules, rank)
+		}
+	}
+	if places[deck.Joker] > 1 {
+		return fmt.Errorf("%w: %s must be ranked or have exactly one power", ErrInvalidRules, deck.Joker)
+	}
+	for rank := range places {
+		if rank < deck.Ace || rank > deck.Joker {
+			return fmt.Errorf("%w: unknown rank %d", ErrInvalidRules, rank)
+		}
+	}
+	for _, rank := range r.LowerThan {
+		if !hasRank(r.Ranking, rank) {
+			return fmt.Errorf("%w: %s must be ranked to be played lower than", ErrInvalidRules, rank)
+		}
+	}
+	for _, rank := range append(append([]deck.Rank{}, r.Skip...), r.Reverse...) {
+		if rank < deck.Ace || rank > deck.Joker {
+			return fmt.Errorf("%w: unknown rank %d", ErrInvalidRules, rank)
+		}
+	}
+	if _, ok := firstPlayerPolicyNames[r.FirstPlayer]; !ok {
+		return fmt.Errorf("%w: unknown first player policy %d", ErrInvalidRules, r.FirstPlayer)
+	}
+	if r.BurnCount < 0 {
+		return fmt.Errorf("%w: burn count %d", ErrInvalidRules, r.BurnCount)
+	}
+	if r.UndoLimit < 0 {
+		return fmt.Errorf("%w: undo limit %d", ErrInvalidRules, r.UndoLimit)
+	}
+	if r.MoveLimit < NoMoveLimit {
+		return fmt.Errorf("%w: move limit %d", ErrInvalidRules, r.MoveLimit)
+	}
+	// every position starts a turn at least once
+	if r.RepeatLimit < 0 || r.RepeatLimit == 1 {
+		return fmt.Errorf("%w: repeat limit %d", ErrInvalidRules, r.RepeatLimit)
+	}
+	if _, ok := stalemateResultNames[r.Stalemate]; !ok {
+		return fmt.Errorf("%w: unknown stalemate result %d", ErrInvalidRules, r.Stalemate)
+	}
+
+	return nil
+}
+
+// validateFor checks the rules are valid for a game played with the given number of jokers
+func (r Rules) validateFor(jokers int) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if jokers > 0 && r.places()[deck.Joker] == 0 {
+		return fmt.Errorf("%w: %s must be ranked or have a power", ErrInvalidRules, deck.Joker)
+	}
+	return nil
+}
+
+// HasPower reports whether the rank can be played on any card
+func (r Rules) HasPower(rank deck.Rank) bool {
+	return hasRank(r.Wild, rank) || hasRank(r.Burn, rank) ||
+		hasRank(r.Transparent, rank) || hasRank(r.Mirror, rank)
+}
+
+// mirrored returns the pile as it is read by the rules,
+// with each mirror card taking on the rank of the card it was played on.
+func (r Rules) mirrored(pile []deck.Card) []deck.Card {
+	if len(r.Mirror) == 0 {
+		return pile
+	}
+
+	effective := make([]deck.Card, len(pile))
+	for i, c := range pile {
+		if hasRank(r.Mirror, c.Rank) && i > 0 {
+			c.Rank = effective[i-1].Rank
+		}
+		effective[i] = c
+	}
+	return effective
+}
+
+// Value is the rank's place in the Ranking, from zero.
+// Ranks with powers are valued the same as the highest rank.
+func (r Rules) Value(rank deck.Rank) int {
+	for i, ranked := range r.Ranking {
+		if ranked == rank {
+			return i
 		}
 	}
+	return len(r.Ranking) - 1
+}
+
+// visiblePile returns the pile as it is read by the rules, without transparent cards
+func (r Rules) visiblePile(pile []deck.Card) []deck.Card {
+	pileWithoutTransparent := []deck.Card{}
+	// Filter out transparent cards
+	for _, c := range r.mirrored(pile) {
+		if !hasRank(r.Transparent, c.Rank) {
+			pileWithoutTransparent = append(pileWithoutTransparent, c)
+		}
+	}
+	return pileWithoutTransparent
+}
+
+func (r Rules) legalMoves(pile, toPlay []deck.Card) []int {
+	pileWithoutTransparent := r.visiblePile(pile)
 
 	moves := map[int]struct{}{}
 
-	// Can play any card on an empty pile
-	if len(pileWithoutThrees) == 0 {
+	// Can play any card on an empty pile, or on a wild card (including an unmirrored mirror card)
+	if len(pileWithoutTransparent) == 0 ||
+		hasRank(r.Wild, pileWithoutTransparent[len(pileWithoutTransparent)-1].Rank) ||
+		hasRank(r.Mirror, pileWithoutTransparent[len(pileWithoutTransparent)-1].Rank) {
 		for i := range toPlay {
 			moves[i] = struct{}{}
 		}
-
 		return setToIntSlice(moves)
 	}
 
-	// Tens beat anything
+	topmostCard := pileWithoutTransparent[len(pileWithoutTransparent)-1]
+	topmostCardValue := r.Value(topmostCard.Rank)
+	mustPlayLower := hasRank(r.LowerThan, topmostCard.Rank)
+
 	for i, tp := range toPlay {
-		if tp.Rank == deck.Ten {
+		// Cards with powers beat anything
+		if r.HasPower(tp.Rank) {
 			moves[i] = struct{}{}
 			continue
 		}
+
+		tpValue := r.Value(tp.Rank)
+		if mustPlayLower && tpValue <= topmostCardValue {
+			moves[i] = struct{}{}
+		}
+		if !mustPlayLower && tpValue >= topmostCardValue {
+			moves[i] = struct{}{}
+		}
 	}
 
-	topmostCard := pileWithoutThrees[len(pileWithoutThrees)-1]
+	return setToIntSlice(moves)
+}
 
-	// Can play any card on a Two
-	if topmostCard.Rank == deck.Two {
-		for i := range toPlay {
-			moves[i] = struct{}{}
+// checkMove returns a *MoveError if playing the chosen cards onto the pile breaks the rules
+func (r Rules) checkMove(pile, cards []deck.Card, decision []int) error {
+	if len(decision) == 0 {
+		return newMoveError(protocol.NoCardsChosen, nil, "choose at least one card")
+	}
+
+	chosen := map[int]bool{}
+	for _, idx := range decision {
+		if idx < 0 || idx >= len(cards) {
+			return newMoveError(protocol.IndexOutOfRange, nil, "no card at position %d", idx)
 		}
-		return setToIntSlice(moves)
+		if chosen[idx] {
+			return newMoveError(protocol.DuplicateCard, &cards[idx], "%s chosen more than once", cards[idx])
+		}
+		chosen[idx] = true
 	}
 
-	// seven
-	if topmostCard.Rank == deck.Seven {
-		for i, tp := range toPlay {
-			if wins := sevenBeaters[tp.Rank]; wins {
-				moves[i] = struct{}{}
-			}
+	first := cards[decision[0]]
+	for _, idx := range decision[1:] {
+		if cards[idx].Rank != first.Rank {
+			return newMoveError(protocol.RankMismatch, &cards[idx],
+				"%s and %s cannot be played together", first, cards[idx])
 		}
-		return setToIntSlice(moves)
 	}
 
-	for i, tp := range toPlay {
-		// skip tens (already checked)
-		if tp.Rank == deck.Ten {
-			continue
+	if len(r.legalMoves(pile, []deck.Card{first})) == 0 {
+		visible := r.visiblePile(pile)
+		top := visible[len(visible)-1]
+		if hasRank(r.LowerThan, top.Rank) {
+			return newMoveError(protocol.LowerThanSeven, &first,
+				"%s must be followed by a card of the same rank or lower", top)
 		}
+		return newMoveError(protocol.BelowTopCard, &first, "%s is lower than %s", first, top)
+	}
 
-		tpValue := cardValues[tp.Rank]
-		topmostCardValue := cardValues[topmostCard.Rank]
-		if tpValue >= topmostCardValue {
-			moves[i] = struct{}{}
+	return nil
+}
+
+// legalHandAndSeenMoves returns the cards that can be played when a player plays
+// the last of their hand together with matching seen cards, indexed into hand followed by seen.
+// It returns nil unless every hand card shares a rank that can be played,
+// and at least one seen card shares that rank too.
+func (r Rules) legalHandAndSeenMoves(pile, hand, seen []deck.Card) []int {
+	if len(hand) == 0 {
+		return nil
+	}
+	rank := hand[0].Rank
+	for _, c := range hand {
+		if c.Rank != rank {
+			return nil
 		}
 	}
+	if len(r.legalMoves(pile, hand[:1])) == 0 {
+		return nil
+	}
 
-	return setToIntSlice(moves)
+	moves := []int{}
+	for i := range hand {
+		moves = append(moves, i)
+	}
+	for i, c := range seen {
+		if c.Rank == rank {
+			moves = append(moves, len(hand)+i)
+		}
+	}
+	if len(moves) == len(hand) {
+		return nil
+	}
+
+	return moves
 }
 
-func isBurn(pile []deck.Card) bool {
+// turnEffects returns how many players are skipped, and how many times the direction
+// of play is reversed, by the top numPlayed cards of the pile.
+func (r Rules) turnEffects(pile []deck.Card, numPlayed int) (skips, reversals int) {
+	if numPlayed > len(pile) {
+		numPlayed = len(pile)
+	}
+	for _, c := range r.mirrored(pile)[len(pile)-numPlayed:] {
+		if hasRank(r.Skip, c.Rank) {
+			skips++
+		}
+		if hasRank(r.Reverse, c.Rank) {
+			reversals++
+		}
+	}
+	return skips, reversals
+}
+
+func (r Rules) isBurn(pile []deck.Card) bool {
 	if len(pile) == 0 {
 		return false
 	}
+	pile = r.mirrored(pile)
 
 	// Here, the most recently played card is at index len-1
 	topCard := pile[len(pile)-1]
-	if topCard.Rank == deck.Ten {
+	if hasRank(r.Burn, topCard.Rank) {
 		return true
 	}
 
-	if len(pile) < burnNum {
+	if r.BurnCount == 0 || len(pile) < r.BurnCount {
 		return false
 	}
 
-	// Check for Three burn
-	numThrees := 0
-	for _, c := range pile[len(pile)-burnNum:] {
-		if c.Rank == deck.Three {
-			numThrees++
+	// Check for a burn of transparent cards
+	numTransparent := 0
+	for _, c := range pile[len(pile)-r.BurnCount:] {
+		if c.Rank == topCard.Rank && hasRank(r.Transparent, c.Rank) {
+			numTransparent++
 		}
 	}
-	if numThrees == burnNum {
+	if numTransparent == r.BurnCount {
 		return true
 	}
 
-	// Take the topmost cards, excluding Threes
-	// Will be 4 cards or fewer
+	// Take the topmost cards, excluding transparent cards
+	// Will be BurnCount cards or fewer
 	topCards := []deck.Card{}
 	for i := len(pile) - 1; i >= 0; i-- {
-		if len(topCards) == burnNum {
+		if len(topCards) == r.BurnCount {
 			break
 		}
 		card := pile[i]
-		if card.Rank != deck.Three {
+		if !hasRank(r.Transparent, card.Rank) {
 			topCards = append(topCards, card)
 		}
 	}
 
-	// Reject fewer than 4 cards
-	if len(topCards) < burnNum {
+	// Reject fewer than BurnCount cards
+	if len(topCards) < r.BurnCount {
 		return false
 	}
 
@@ -162,3 +457,22 @@ func isBurn(pile []deck.Card) bool {
 
 	return true
 }
+
+// getLegalMoves returns the legal moves under the default rules
+func getLegalMoves(pile, toPlay []deck.Card) []int {
+	return DefaultRules().legalMoves(pile, toPlay)
+}
+
+// isBurn reports whether the pile burns under the default rules
+func isBurn(pile []deck.Card) bool {
+	return DefaultRules().isBurn(pile)
+}
+
+func hasRank(ranks []deck.Rank, rank deck.Rank) bool {
+	for _, r := range ranks {
+		if r == rank {
+			return true
+		}
+	}
+	return false
+}
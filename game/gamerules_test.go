@@ -1,10 +1,12 @@
 package game
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/minaorangina/shed/deck"
 	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
 )
 
 type legalMoveTest struct {
@@ -627,6 +629,109 @@ func TestLegalMoves(t *testing.T) {
 	})
 }
 
+func TestLegalHandAndSeenMoves(t *testing.T) {
+	tt := []struct {
+		name             string
+		pile, hand, seen []deck.Card
+		moves            []int
+	}{
+		{
+			name:  "last hand card matches a seen card",
+			pile:  []deck.Card{deck.NewCard(deck.Four, deck.Hearts)},
+			hand:  []deck.Card{deck.NewCard(deck.Jack, deck.Diamonds)},
+			seen:  []deck.Card{deck.NewCard(deck.King, deck.Hearts), deck.NewCard(deck.Jack, deck.Spades)},
+			moves: []int{0, 2},
+		},
+		{
+			name: "matching hand cards and several matching seen cards",
+			pile: []deck.Card{},
+			hand: []deck.Card{deck.NewCard(deck.Five, deck.Diamonds), deck.NewCard(deck.Five, deck.Clubs)},
+			seen: []deck.Card{
+				deck.NewCard(deck.Five, deck.Hearts),
+				deck.NewCard(deck.Queen, deck.Hearts),
+				deck.NewCard(deck.Five, deck.Spades),
+			},
+			moves: []int{0, 1, 2, 4},
+		},
+		{
+			name: "hand cards of different ranks",
+			pile: []deck.Card{},
+			hand: []deck.Card{deck.NewCard(deck.Five, deck.Diamonds), deck.NewCard(deck.Six, deck.Clubs)},
+			seen: []deck.Card{deck.NewCard(deck.Five, deck.Hearts)},
+		},
+		{
+			name: "no matching seen cards",
+			pile: []deck.Card{},
+			hand: []deck.Card{deck.NewCard(deck.Five, deck.Diamonds)},
+			seen: []deck.Card{deck.NewCard(deck.Six, deck.Hearts)},
+		},
+		{
+			name: "hand card cannot be played",
+			pile: []deck.Card{deck.NewCard(deck.Ace, deck.Hearts)},
+			hand: []deck.Card{deck.NewCard(deck.Five, deck.Diamonds)},
+			seen: []deck.Card{deck.NewCard(deck.Five, deck.Hearts)},
+		},
+		{
+			name: "empty hand",
+			pile: []deck.Card{},
+			seen: []deck.Card{deck.NewCard(deck.Five, deck.Hearts)},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			moves := DefaultRules().legalHandAndSeenMoves(tc.pile, tc.hand, tc.seen)
+			utils.AssertDeepEqual(t, moves, tc.moves)
+		})
+	}
+}
+
+func TestCheckMove(t *testing.T) {
+	sevenOnThree := []deck.Card{deck.NewCard(deck.Seven, deck.Clubs), deck.NewCard(deck.Three, deck.Hearts)}
+	cards := []deck.Card{
+		deck.NewCard(deck.Nine, deck.Clubs),
+		deck.NewCard(deck.Nine, deck.Hearts),
+		deck.NewCard(deck.Five, deck.Spades),
+	}
+
+	tt := []struct {
+		name     string
+		pile     []deck.Card
+		decision []int
+		reason   protocol.MoveErrorReason
+	}{
+		{name: "legal move", pile: []deck.Card{deck.NewCard(deck.Four, deck.Clubs)}, decision: []int{0, 1}},
+		{name: "no cards chosen", decision: []int{}, reason: protocol.NoCardsChosen},
+		{name: "index out of range", decision: []int{0, 3}, reason: protocol.IndexOutOfRange},
+		{name: "negative index", decision: []int{-1}, reason: protocol.IndexOutOfRange},
+		{name: "same card twice", decision: []int{1, 1}, reason: protocol.DuplicateCard},
+		{name: "different ranks", decision: []int{0, 2}, reason: protocol.RankMismatch},
+		{name: "higher than a Seven", pile: sevenOnThree, decision: []int{0}, reason: protocol.LowerThanSeven},
+		{name: "lower card on a Seven", pile: sevenOnThree, decision: []int{2}},
+		{
+			name:     "lower than the top card",
+			pile:     []deck.Card{deck.NewCard(deck.King, deck.Clubs)},
+			decision: []int{0, 1},
+			reason:   protocol.BelowTopCard,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := DefaultRules().checkMove(tc.pile, cards, tc.decision)
+			if tc.reason == "" {
+				utils.AssertNoError(t, err)
+				return
+			}
+
+			var moveErr *MoveError
+			utils.AssertTrue(t, errors.As(err, &moveErr))
+			utils.AssertEqual(t, moveErr.Reason, tc.reason)
+			utils.AssertTrue(t, errors.Is(err, ErrInvalidMove))
+		})
+	}
+}
+
 func TestGameIsBurn(t *testing.T) {
 	tt := []struct {
 		name string
@@ -776,3 +881,424 @@ func TestGameIsBurn(t *testing.T) {
 		})
 	}
 }
+
+func TestRules(t *testing.T) {
+	// houseRules: Eight is wild, Jack burns, Two is transparent,
+	// Nine must be followed by a lower card, and three of a kind burns
+	houseRules := func() Rules {
+		return Rules{
+			Ranking: []deck.Rank{
+				deck.Three, deck.Four, deck.Five, deck.Six, deck.Seven, deck.Nine,
+				deck.Ten, deck.Queen, deck.King, deck.Ace,
+			},
+			Wild:        []deck.Rank{deck.Eight},
+			Burn:        []deck.Rank{deck.Jack},
+			Transparent: []deck.Rank{deck.Two},
+			LowerThan:   []deck.Rank{deck.Nine},
+			BurnCount:   3,
+		}
+	}
+
+	t.Run("default rules are valid", func(t *testing.T) {
+		utils.AssertNoError(t, DefaultRules().Validate())
+		utils.AssertNoError(t, houseRules().Validate())
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		tt := []struct {
+			name  string
+			rules func() Rules
+		}{
+			{
+				name: "rank missing",
+				rules: func() Rules {
+					r := DefaultRules()
+					r.Wild = nil
+					return r
+				},
+			},
+			{
+				name: "rank ranked and powered",
+				rules: func() Rules {
+					r := DefaultRules()
+					r.Burn = append(r.Burn, deck.Ace)
+					return r
+				},
+			},
+			{
+				name: "lower-than rank not ranked",
+				rules: func() Rules {
+					r := DefaultRules()
+					r.LowerThan = []deck.Rank{deck.Two}
+					return r
+				},
+			},
+			{
+				name: "negative burn count",
+				rules: func() Rules {
+					r := DefaultRules()
+					r.BurnCount = -1
+					return r
+				},
+			},
+			{
+				name: "unknown skip rank",
+				rules: func() Rules {
+					r := DefaultRules()
+					r.Skip = []deck.Rank{deck.Rank(42)}
+					return r
+				},
+			},
+			{
+				name: "negative undo limit",
+				rules: func() Rules {
+					r := DefaultRules()
+					r.UndoLimit = -1
+					return r
+				},
+			},
+			{
+				name: "negative move limit",
+				rules: func() Rules {
+					r := DefaultRules()
+					r.MoveLimit = NoMoveLimit - 1
+					return r
+				},
+			},
+			{
+				name: "repeat limit of one",
+				rules: func() Rules {
+					r := DefaultRules()
+					r.RepeatLimit = 1
+					return r
+				},
+			},
+			{
+				name: "unknown stalemate result",
+				rules: func() Rules {
+					r := DefaultRules()
+					r.Stalemate = StalemateResult(42)
+					return r
+				},
+			},
+		}
+
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				err := tc.rules().Validate()
+				utils.AssertTrue(t, errors.Is(err, ErrInvalidRules))
+
+				_, err = NewShed(ShedOpts{Players: twoPlayers(), Rules: tc.rules()})
+				utils.AssertTrue(t, errors.Is(err, ErrInvalidRules))
+			})
+		}
+	})
+
+	t.Run("legal moves follow house rules", func(t *testing.T) {
+		tt := []legalMoveTest{
+			{
+				name: "anything can be played on a wild Eight",
+				pile: []deck.Card{deck.NewCard(deck.Eight, deck.Clubs)},
+				toPlay: []deck.Card{
+					deck.NewCard(deck.Three, deck.Spades),
+					deck.NewCard(deck.Ace, deck.Hearts),
+				},
+				moves: []int{0, 1},
+			},
+			{
+				name: "transparent Two is ignored",
+				pile: []deck.Card{
+					deck.NewCard(deck.King, deck.Clubs),
+					deck.NewCard(deck.Two, deck.Clubs),
+				},
+				toPlay: []deck.Card{
+					deck.NewCard(deck.Three, deck.Spades),
+					deck.NewCard(deck.Ace, deck.Hearts),
+				},
+				moves: []int{1},
+			},
+			{
+				name: "Nine must be followed by a lower card",
+				pile: []deck.Card{deck.NewCard(deck.Nine, deck.Clubs)},
+				toPlay: []deck.Card{
+					deck.NewCard(deck.Three, deck.Spades),
+					deck.NewCard(deck.Nine, deck.Hearts),
+					deck.NewCard(deck.Ten, deck.Hearts),
+					deck.NewCard(deck.Jack, deck.Hearts),
+				},
+				moves: []int{0, 1, 3},
+			},
+			{
+				name: "Seven has no power",
+				pile: []deck.Card{deck.NewCard(deck.Seven, deck.Clubs)},
+				toPlay: []deck.Card{
+					deck.NewCard(deck.Four, deck.Spades),
+					deck.NewCard(deck.Ten, deck.Hearts),
+				},
+				moves: []int{1},
+			},
+		}
+
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				utils.AssertDeepEqual(t, houseRules().legalMoves(tc.pile, tc.toPlay), tc.moves)
+			})
+		}
+	})
+
+	t.Run("burns follow house rules", func(t *testing.T) {
+		rules := houseRules()
+
+		utils.AssertTrue(t, rules.isBurn([]deck.Card{deck.NewCard(deck.Jack, deck.Clubs)}))
+		utils.AssertTrue(t, !rules.isBurn([]deck.Card{deck.NewCard(deck.Ten, deck.Clubs)}))
+		utils.AssertTrue(t, rules.isBurn([]deck.Card{
+			deck.NewCard(deck.Five, deck.Clubs),
+			deck.NewCard(deck.Five, deck.Hearts),
+			deck.NewCard(deck.Two, deck.Hearts),
+			deck.NewCard(deck.Five, deck.Spades),
+		}))
+	})
+
+	t.Run("options without card powers keep the default powers", func(t *testing.T) {
+		for _, rules := range []Rules{{UndoLimit: 3}, {Snap: true}, {FirstPlayer: CreatorFirstPlayer}} {
+			game, err := NewShed(ShedOpts{Players: twoPlayers(), Rules: rules})
+			utils.AssertNoError(t, err)
+
+			want := DefaultRules()
+			want.UndoLimit, want.Snap, want.FirstPlayer = rules.UndoLimit, rules.Snap, rules.FirstPlayer
+			utils.AssertDeepEqual(t, game.Rules, want)
+		}
+	})
+
+	t.Run("rules without a move limit keep the default limit", func(t *testing.T) {
+		withPowers := DefaultRules()
+		withPowers.MoveLimit = 0
+
+		for _, rules := range []Rules{{Snap: true}, withPowers} {
+			game, err := NewShed(ShedOpts{Players: twoPlayers(), Rules: rules})
+			utils.AssertNoError(t, err)
+			utils.AssertEqual(t, game.Rules.MoveLimit, DefaultRules().MoveLimit)
+		}
+
+		rules := DefaultRules()
+		rules.MoveLimit = NoMoveLimit
+		game, err := NewShed(ShedOpts{Players: twoPlayers(), Rules: rules})
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.Rules.MoveLimit, NoMoveLimit)
+	})
+
+	t.Run("game is played by its rules", func(t *testing.T) {
+		// Given a game played by house rules, where Jack burns
+		plrs := twoPlayers()
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearCards,
+			Deck:          deck.Deck{},
+			Pile:          []deck.Card{deck.NewCard(deck.King, deck.Diamonds)},
+			Players:       plrs,
+			CurrentPlayer: plrs[0],
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards([]deck.Card{
+					deck.NewCard(deck.Jack, deck.Hearts),
+					deck.NewCard(deck.Four, deck.Hearts),
+				}, nil, cardsOf(deck.Hearts, deck.Five, deck.Six, deck.Queen), nil),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Jack, deck.Queen),
+					cardsOf(deck.Diamonds, deck.Seven, deck.Eight, deck.Nine),
+					nil,
+				),
+			},
+			Rules: houseRules(),
+		}))
+		utils.AssertNoError(t, err)
+
+		// When the player plays their only legal card
+		msgs, err := game.Next()
+		utils.AssertNoError(t, err)
+		utils.AssertDeepEqual(t, getMoves(msgs, "p1"), []int{0})
+
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p1",
+			Command:  protocol.PlayHand,
+			Decision: []int{0},
+		}})
+		utils.AssertNoError(t, err)
+
+		// Then the pile burns
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.Burn)
+	})
+}
+
+func TestJokers(t *testing.T) {
+	joker := deck.NewJoker()
+
+	t.Run("legal moves with a mirroring joker", func(t *testing.T) {
+		tt := []legalMoveTest{
+			{
+				name: "joker can be played on anything",
+				pile: []deck.Card{deck.NewCard(deck.Ace, deck.Clubs)},
+				toPlay: []deck.Card{
+					deck.NewCard(deck.Four, deck.Spades),
+					joker,
+				},
+				moves: []int{1},
+			},
+			{
+				name: "joker on a King acts as a King",
+				pile: []deck.Card{deck.NewCard(deck.King, deck.Clubs), joker},
+				toPlay: []deck.Card{
+					deck.NewCard(deck.Queen, deck.Spades),
+					deck.NewCard(deck.King, deck.Hearts),
+					deck.NewCard(deck.Ace, deck.Hearts),
+				},
+				moves: []int{1, 2},
+			},
+			{
+				name: "joker on a Seven acts as a Seven",
+				pile: []deck.Card{deck.NewCard(deck.Seven, deck.Clubs), joker},
+				toPlay: []deck.Card{
+					deck.NewCard(deck.Five, deck.Spades),
+					deck.NewCard(deck.Eight, deck.Hearts),
+				},
+				moves: []int{0},
+			},
+			{
+				name: "joker on a Two is wild",
+				pile: []deck.Card{deck.NewCard(deck.Two, deck.Clubs), joker},
+				toPlay: []deck.Card{
+					deck.NewCard(deck.Four, deck.Spades),
+					deck.NewCard(deck.Ace, deck.Hearts),
+				},
+				moves: []int{0, 1},
+			},
+			{
+				name: "joker on a Three is transparent",
+				pile: []deck.Card{
+					deck.NewCard(deck.Queen, deck.Clubs),
+					deck.NewCard(deck.Three, deck.Clubs),
+					joker,
+				},
+				toPlay: []deck.Card{
+					deck.NewCard(deck.Four, deck.Spades),
+					deck.NewCard(deck.King, deck.Hearts),
+				},
+				moves: []int{1},
+			},
+			{
+				name: "joker on an empty pile is wild",
+				pile: []deck.Card{joker},
+				toPlay: []deck.Card{
+					deck.NewCard(deck.Four, deck.Spades),
+					deck.NewCard(deck.Ace, deck.Hearts),
+				},
+				moves: []int{0, 1},
+			},
+			{
+				name: "joker on a joker mirrors the card beneath",
+				pile: []deck.Card{deck.NewCard(deck.Nine, deck.Clubs), joker, joker},
+				toPlay: []deck.Card{
+					deck.NewCard(deck.Eight, deck.Spades),
+					deck.NewCard(deck.Jack, deck.Hearts),
+				},
+				moves: []int{1},
+			},
+		}
+
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				utils.AssertDeepEqual(t, getLegalMoves(tc.pile, tc.toPlay), tc.moves)
+			})
+		}
+	})
+
+	t.Run("burns with a mirroring joker", func(t *testing.T) {
+		tt := []struct {
+			name string
+			pile []deck.Card
+			want bool
+		}{
+			{
+				"joker completes four of a kind",
+				[]deck.Card{
+					deck.NewCard(deck.Six, deck.Clubs),
+					deck.NewCard(deck.Six, deck.Hearts),
+					deck.NewCard(deck.Six, deck.Spades),
+					joker,
+				},
+				true,
+			},
+			{
+				"joker in the middle of four of a kind",
+				[]deck.Card{
+					deck.NewCard(deck.Six, deck.Clubs),
+					joker,
+					deck.NewCard(deck.Six, deck.Hearts),
+					deck.NewCard(deck.Six, deck.Spades),
+				},
+				true,
+			},
+			{
+				"three of a kind and a joker on something else",
+				[]deck.Card{
+					deck.NewCard(deck.Six, deck.Clubs),
+					deck.NewCard(deck.Six, deck.Hearts),
+					deck.NewCard(deck.Six, deck.Spades),
+					deck.NewCard(deck.Eight, deck.Spades),
+					joker,
+				},
+				false,
+			},
+			{
+				"four jokers",
+				[]deck.Card{joker, joker, joker, joker},
+				true,
+			},
+			{
+				"lone joker",
+				[]deck.Card{joker},
+				false,
+			},
+		}
+
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				utils.AssertEqual(t, isBurn(tc.pile), tc.want)
+			})
+		}
+	})
+
+	t.Run("jokers can be wild instead", func(t *testing.T) {
+		rules := DefaultRules()
+		rules.Mirror = nil
+		rules.Wild = append(rules.Wild, deck.Joker)
+		utils.AssertNoError(t, rules.Validate())
+
+		pile := []deck.Card{deck.NewCard(deck.King, deck.Clubs), joker}
+		toPlay := []deck.Card{deck.NewCard(deck.Four, deck.Spades)}
+		utils.AssertDeepEqual(t, rules.legalMoves(pile, toPlay), []int{0})
+	})
+
+	t.Run("games with jokers need rules for them", func(t *testing.T) {
+		rules := DefaultRules()
+		rules.Mirror = nil
+
+		_, err := NewShed(ShedOpts{Players: twoPlayers(), Rules: rules, Jokers: 2})
+		utils.AssertTrue(t, errors.Is(err, ErrInvalidRules))
+
+		_, err = NewShed(ShedOpts{Players: twoPlayers(), Rules: rules})
+		utils.AssertNoError(t, err)
+	})
+
+	t.Run("games can be played with jokers", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: threePlayers(), Jokers: 4, Seed: 5})
+		utils.AssertNoError(t, err)
+
+		dealt := len(game.Deck)
+		for _, pc := range game.PlayerCards {
+			dealt += len(pc.Hand) + len(pc.Seen) + len(pc.Unseen)
+		}
+		utils.AssertEqual(t, dealt, len(deck.New())+4)
+
+		playGame(t, game, 2000)
+	})
+}
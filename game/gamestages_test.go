@@ -1,6 +1,7 @@
 package game
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
@@ -12,7 +13,7 @@ import (
 func TestGameStageZero(t *testing.T) {
 	t.Run("players asked to reorganise their hand", func(t *testing.T) {
 		t.Log("When a new game starts")
-		game, err := NewShed(threePlayers())
+		game, err := NewShed(ShedOpts{Players: threePlayers()})
 		utils.AssertNoError(t, err)
 
 		msgs, err := game.Next()
@@ -31,7 +32,7 @@ func TestGameStageZero(t *testing.T) {
 
 	t.Run("reorganised cards handled correctly", func(t *testing.T) {
 		t.Log("Given a new game")
-		game, err := NewShed(threePlayers())
+		game, err := NewShed(ShedOpts{Players: threePlayers()})
 		utils.AssertNoError(t, err)
 
 		t.Log("When Next is called")
@@ -92,14 +93,93 @@ func TestGameStageZero(t *testing.T) {
 		utils.AssertNoError(t, err)
 		utils.AssertNotEmptyString(t, game.CurrentPlayer.PlayerID)
 	})
+
+	tt := []struct {
+		name     string
+		p2       protocol.InboundMessage
+		p2Reason protocol.MoveErrorReason
+	}{
+		{
+			name:     "too few cards",
+			p2:       protocol.InboundMessage{PlayerID: "p2", Command: protocol.Reorg, Decision: []int{0, 1}},
+			p2Reason: protocol.WrongCardCount,
+		},
+		{
+			name:     "too many cards",
+			p2:       protocol.InboundMessage{PlayerID: "p2", Command: protocol.Reorg, Decision: []int{0, 1, 2, 3}},
+			p2Reason: protocol.WrongCardCount,
+		},
+		{
+			name:     "card out of range",
+			p2:       protocol.InboundMessage{PlayerID: "p2", Command: protocol.Reorg, Decision: []int{0, 1, 6}},
+			p2Reason: protocol.IndexOutOfRange,
+		},
+		{
+			name:     "same card twice",
+			p2:       protocol.InboundMessage{PlayerID: "p2", Command: protocol.Reorg, Decision: []int{4, 1, 4}},
+			p2Reason: protocol.DuplicateCard,
+		},
+		{
+			name:     "wrong command",
+			p2:       protocol.InboundMessage{PlayerID: "p2", Command: protocol.PlayHand, Decision: []int{0, 1, 2}},
+			p2Reason: protocol.WrongCommand,
+		},
+		{
+			name:     "unknown player",
+			p2:       protocol.InboundMessage{PlayerID: "p9", Command: protocol.Reorg, Decision: []int{0, 1, 2}},
+			p2Reason: protocol.NotYourTurn,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run("rejects reorganisation with "+tc.name, func(t *testing.T) {
+			// Given a game awaiting reorganised cards
+			game, err := NewShed(ShedOpts{Players: twoPlayers()})
+			utils.AssertNoError(t, err)
+			_, err = game.Next()
+			utils.AssertNoError(t, err)
+			before := game.Snapshot()
+
+			// When one player sends an invalid reorganisation
+			msgs, err := game.ReceiveResponse([]protocol.InboundMessage{
+				{PlayerID: "p1", Command: protocol.Reorg, Decision: []int{3, 4, 5}},
+				tc.p2,
+			})
+
+			// Then that player is told why
+			utils.AssertTrue(t, errors.Is(err, ErrInvalidMove))
+			utils.AssertEqual(t, len(msgs), 1)
+			utils.AssertEqual(t, msgs[0].PlayerID, tc.p2.PlayerID)
+			utils.AssertEqual(t, msgs[0].MoveError.Reason, tc.p2Reason)
+
+			// And no one's cards are reorganised
+			utils.AssertDeepEqual(t, game.Snapshot(), before)
+			utils.AssertEqual(t, game.AwaitingResponse(), protocol.Reorg)
+		})
+	}
+
+	t.Run("rejects two reorganisations from one player", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers()})
+		utils.AssertNoError(t, err)
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{
+			{PlayerID: "p1", Command: protocol.Reorg, Decision: []int{3, 4, 5}},
+			{PlayerID: "p1", Command: protocol.Reorg, Decision: []int{0, 1, 2}},
+		})
+		utils.AssertTrue(t, errors.Is(err, ErrGameUnexpectedResponse))
+		utils.AssertEqual(t, game.Stage, preGame)
+	})
 }
 
 func TestGameStageZeroToOne(t *testing.T) {
 	// Given a new game
-	game := ExistingShed(ShedOpts{})
+	game, err := ExistingShed(ShedOpts{})
+	utils.AssertNoError(t, err)
 
 	// When the game has started and Next is called
-	err := game.Start(twoPlayers())
+	err = game.Start(twoPlayers())
 	utils.AssertNoError(t, err)
 	_, err = game.Next()
 	utils.AssertNoError(t, err)
@@ -198,17 +278,23 @@ func TestGameStageOne(t *testing.T) {
 
 		pc := NewPlayerCards(hand, nil, nil, nil)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearDeck,
-			Deck:          someDeck(4),
+			Deck:          cardsOf(deck.Hearts, deck.Four, deck.Five, deck.Six, deck.Jack),
 			Pile:          pile,
 			Players:       twoPlayers(),
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Jack),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Seven, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		oldHand := game.PlayerCards[game.CurrentPlayer.PlayerID].Hand
 		oldHandSize, oldPileSize, oldDeckSize := len(oldHand), len(game.Pile), len(game.Deck)
@@ -286,17 +372,23 @@ func TestGameStageOne(t *testing.T) {
 
 		pc := NewPlayerCards(hand, nil, nil, nil)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearDeck,
-			Deck:          someDeck(4),
+			Deck:          cardsOf(deck.Hearts, deck.Five, deck.Six, deck.Jack, deck.Queen),
 			Pile:          pile,
 			Players:       twoPlayers(),
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Jack),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Seven, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		oldHand := game.PlayerCards[game.CurrentPlayer.PlayerID].Hand
 		oldHandSize, oldPileSize, oldDeckSize := len(oldHand), len(game.Pile), len(game.Deck)
@@ -370,17 +462,23 @@ func TestGameStageOne(t *testing.T) {
 		// And a player with two cards of the same value in their hand
 		pc := NewPlayerCards(append(targetCards, deck.NewCard(deck.Eight, deck.Hearts)), nil, nil, nil)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearDeck,
-			Deck:          someDeck(4),
+			Deck:          cardsOf(deck.Hearts, deck.Five, deck.Six, deck.Jack, deck.Queen),
 			Pile:          pile,
 			Players:       twoPlayers(),
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Seven, deck.Eight),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		oldHand := game.PlayerCards[game.CurrentPlayer.PlayerID].Hand
 		oldHandSize := len(oldHand)
@@ -435,17 +533,23 @@ func TestGameStageOne(t *testing.T) {
 			nil, nil, nil,
 		)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearDeck,
-			Deck:          someDeck(4),
+			Deck:          cardsOf(deck.Hearts, deck.Five, deck.Six, deck.Jack, deck.Queen),
 			Pile:          pile,
 			Players:       twoPlayers(),
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		oldHand := game.PlayerCards[game.CurrentPlayer.PlayerID].Hand
 		oldHandSize := len(oldHand)
@@ -471,12 +575,14 @@ func TestGameStageOne(t *testing.T) {
 
 		// Then the game returns an error
 		utils.AssertErrored(t, err)
-		utils.AssertEqual(t, err, ErrInvalidMove)
+		utils.AssertTrue(t, errors.Is(err, ErrInvalidMove))
 
-		// And players are sent error messages
+		// And the player is told the ranks do not match
 		utils.AssertTrue(t, len(msgs) > 0)
 		for _, m := range msgs {
 			utils.AssertEqual(t, m.Command, protocol.Error)
+			utils.AssertNotNil(t, m.MoveError)
+			utils.AssertEqual(t, m.MoveError.Reason, protocol.RankMismatch)
 		}
 
 		newHand := game.PlayerCards[game.CurrentPlayer.PlayerID].Hand
@@ -505,17 +611,23 @@ func TestGameStageOne(t *testing.T) {
 			deck.NewCard(deck.Six, deck.Diamonds),
 		}
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearDeck,
-			Deck:          someDeck(4),
+			Deck:          cardsOf(deck.Hearts, deck.Five, deck.Six, deck.Jack, deck.Queen),
 			Pile:          []deck.Card{highValueCard},
 			Players:       twoPlayers(),
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": {Hand: deck.Deck(lowValueCards)},
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Jack),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Seven, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		oldHandSize := len(game.PlayerCards[game.CurrentPlayer.PlayerID].Hand)
 		oldPileSize := len(game.Pile)
@@ -563,6 +675,128 @@ func TestGameStageOne(t *testing.T) {
 		utils.AssertTrue(t, game.CurrentPlayer.PlayerID != previousPlayerID)
 	})
 
+	t.Run("player chooses to pick up pile", func(t *testing.T) {
+		// Given a game with a low-value card on the pile
+		pile := []deck.Card{
+			deck.NewCard(deck.Five, deck.Spades),
+			deck.NewCard(deck.Four, deck.Clubs),
+		}
+
+		// and a player with cards they could play
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearDeck,
+			Deck:          cardsOf(deck.Hearts, deck.Four, deck.Five, deck.Six, deck.Jack),
+			Pile:          pile,
+			Players:       twoPlayers(),
+			CurrentPlayer: twoPlayers()[0],
+			PlayerCards: map[string]*PlayerCards{
+				"p1": {Hand: []deck.Card{
+					deck.NewCard(deck.Ace, deck.Hearts),
+					deck.NewCard(deck.King, deck.Clubs),
+					deck.NewCard(deck.Queen, deck.Diamonds),
+				}},
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Jack, deck.King),
+					cardsOf(deck.Diamonds, deck.Seven, deck.Eight, deck.Nine),
+					nil,
+				),
+			},
+		}))
+		utils.AssertNoError(t, err)
+
+		oldHand := copyCards(game.PlayerCards["p1"].Hand)
+		oldPile := copyCards(game.Pile)
+		oldDeckSize := len(game.Deck)
+
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayHand)
+		utils.AssertTrue(t, game.AcceptsCommand(protocol.PickUpPile))
+
+		// when the player picks up the pile instead of playing
+		msgs, err := game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p1",
+			Command:  protocol.PickUpPile,
+		}})
+		utils.AssertNoError(t, err)
+
+		// then the current player's hand includes the cards from the pile
+		utils.AssertDeepEqual(t, game.PlayerCards["p1"].Hand, append(oldHand, oldPile...))
+		// and the pile is now empty
+		utils.AssertEqual(t, len(game.Pile), 0)
+		// and the deck is unchanged
+		utils.AssertEqual(t, len(game.Deck), oldDeckSize)
+
+		// then everyone is informed
+		utils.AssertEqual(t, len(msgs), len(game.PlayerInfo))
+
+		// and the current player's protocol.OutboundMessage has the expected content
+		utils.AssertTrue(t, msgs[0].ShouldRespond)
+		utils.AssertEqual(t, msgs[0].Command, protocol.PickUpPile)
+
+		// and the other players' protocol.OutboundMessages have the expected content
+		utils.AssertEqual(t, msgs[1].ShouldRespond, false)
+		utils.AssertEqual(t, msgs[1].Command, protocol.PickUpPile)
+		utils.AssertEqual(t, len(msgs[1].Pile), 0)
+
+		// and the current player's response is handled correctly
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PickUpPile)
+		utils.AssertTrue(t, game.AcceptsCommand(protocol.PickUpPile))
+		response, err := game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p1",
+			Command:  protocol.PickUpPile,
+		}})
+		utils.AssertNoError(t, err)
+		utils.AssertDeepEqual(t, response, []protocol.OutboundMessage(nil))
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.Null)
+
+		// and the next player is up
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p2")
+		utils.AssertNoError(t, validateStateMachine(game))
+	})
+
+	t.Run("player cannot pick up an empty pile", func(t *testing.T) {
+		// Given a game with an empty pile
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearDeck,
+			Deck:          cardsOf(deck.Hearts, deck.Four, deck.Five, deck.Six, deck.Jack),
+			Pile:          []deck.Card{},
+			Players:       twoPlayers(),
+			CurrentPlayer: twoPlayers()[0],
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards(
+					cardsOf(deck.Clubs, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Clubs, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Clubs, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+			},
+		}))
+		utils.AssertNoError(t, err)
+
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.AcceptsCommand(protocol.PickUpPile), false)
+
+		// when the player tries to pick it up
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p1",
+			Command:  protocol.PickUpPile,
+		}})
+
+		// then they are told they cannot, and must still play
+		utils.AssertErrored(t, err)
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayHand)
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p1")
+	})
+
 	t.Run("not enough cards in deck", func(t *testing.T) {
 		// Given a game in stage 1 with one card left on the deck
 		lowValueCard := deck.NewCard(deck.Four, deck.Hearts)
@@ -577,17 +811,23 @@ func TestGameStageOne(t *testing.T) {
 			nil, nil, nil,
 		)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearDeck,
-			Deck:          someDeck(1),
+			Deck:          cardsOf(deck.Hearts, deck.Five),
 			Pile:          []deck.Card{lowValueCard},
 			Players:       twoPlayers(),
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Seven, deck.Eight),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		oldHand := game.PlayerCards[game.CurrentPlayer.PlayerID].Hand
 		oldHandSize, oldPileSize := len(oldHand), len(game.Pile)
@@ -651,21 +891,27 @@ func TestGameStageOne(t *testing.T) {
 
 		// and a player with 4 cards in their hand
 		pc := NewPlayerCards(
-			someCards(4),
+			cardsOf(deck.Clubs, deck.Five, deck.Six, deck.Jack, deck.Queen),
 			nil, nil, nil,
 		)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearDeck,
-			Deck:          someDeck(1),
+			Deck:          cardsOf(deck.Hearts, deck.Five),
 			Pile:          []deck.Card{lowValueCard},
 			Players:       twoPlayers(),
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		// When the player takes their turn
 		msgs, err := game.Next()
@@ -717,7 +963,7 @@ func TestGameStageTwo(t *testing.T) {
 			nil, nil, nil,
 		)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearCards,
 			Deck:          deck.Deck{},
 			Pile:          pile,
@@ -725,9 +971,15 @@ func TestGameStageTwo(t *testing.T) {
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Jack),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Seven, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		// When a player takes their turn
 		msgs, err := game.Next()
@@ -788,10 +1040,10 @@ func TestGameStageTwo(t *testing.T) {
 				deck.NewCard(deck.Nine, deck.Clubs),
 				deck.NewCard(deck.Six, deck.Diamonds),
 			},
-			someCards(3),
+			cardsOf(deck.Clubs, deck.Two, deck.Three, deck.Four),
 			nil,
 		)
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearCards,
 			Deck:          deck.Deck{},
 			Pile:          pile,
@@ -799,9 +1051,15 @@ func TestGameStageTwo(t *testing.T) {
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Jack),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Seven, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		// When the player starts their turn
 		msgs, err := game.Next()
@@ -882,10 +1140,11 @@ func TestGameStageTwo(t *testing.T) {
 				deck.NewCard(deck.Nine, deck.Clubs),
 				deck.NewCard(deck.Six, deck.Diamonds),
 			},
-			nil, nil,
+			cardsOf(deck.Clubs, deck.Two, deck.Three, deck.Four),
+			nil,
 		)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearCards,
 			Deck:          deck.Deck{},
 			Pile:          pile,
@@ -893,9 +1152,15 @@ func TestGameStageTwo(t *testing.T) {
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Jack),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Seven, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 		oldHandSize := len(game.PlayerCards[game.CurrentPlayer.PlayerID].Hand)
 		oldPileSize := len(game.Pile)
 		oldSeenSize := len(game.PlayerCards[game.CurrentPlayer.PlayerID].Seen)
@@ -941,6 +1206,67 @@ func TestGameStageTwo(t *testing.T) {
 		utils.AssertTrue(t, game.CurrentPlayer.PlayerID != previousPlayerID)
 	})
 
+	t.Run("stage 2: player chooses to pick up pile instead of playing seen cards", func(t *testing.T) {
+		// Given a game in stage 2 with a player who has only seen and unseen cards
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearCards,
+			Deck:          deck.Deck{},
+			Pile:          []deck.Card{deck.NewCard(deck.Six, deck.Hearts)},
+			Players:       threePlayers(),
+			CurrentPlayer: threePlayers()[1],
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards(
+					cardsOf(deck.Clubs, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Clubs, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Clubs, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+				"p2": NewPlayerCards(nil, []deck.Card{
+					deck.NewCard(deck.King, deck.Spades),
+					deck.NewCard(deck.Ace, deck.Spades),
+				}, cardsOf(deck.Hearts, deck.Four, deck.Five, deck.Jack), nil),
+				"p3": NewPlayerCards(
+					cardsOf(deck.Spades, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Spades, deck.Eight, deck.Jack, deck.Queen),
+					cardsOf(deck.Spades, deck.Three, deck.Seven, deck.Nine),
+					nil,
+				),
+			},
+		}))
+		utils.AssertNoError(t, err)
+
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlaySeen)
+
+		// When they pick up the pile
+		msgs, err := game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p2",
+			Command:  protocol.PickUpPile,
+		}})
+		utils.AssertNoError(t, err)
+
+		// Then the pile is in their hand, and their seen cards are untouched
+		utils.AssertEqual(t, len(game.PlayerCards["p2"].Hand), 1)
+		utils.AssertEqual(t, len(game.PlayerCards["p2"].Seen), 2)
+		utils.AssertEqual(t, len(game.Pile), 0)
+
+		// And everyone is told
+		for _, m := range msgs {
+			utils.AssertEqual(t, m.Command, protocol.PickUpPile)
+			utils.AssertEqual(t, m.ShouldRespond, m.PlayerID == "p2")
+		}
+
+		// And once they acknowledge, it's the next player's turn
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p2",
+			Command:  protocol.PickUpPile,
+		}})
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p3")
+		utils.AssertNoError(t, validateStateMachine(game))
+	})
+
 	t.Run("stage 2: player only unseen cards", func(t *testing.T) {
 
 		// Given a game in stage 2, with a low-value card on the pile
@@ -956,14 +1282,10 @@ func TestGameStageTwo(t *testing.T) {
 				deck.NewCard(deck.Nine, deck.Clubs),
 				deck.NewCard(deck.Six, deck.Diamonds),
 			},
-			map[deck.Card]bool{
-				chosenCard:                            false,
-				deck.NewCard(deck.Nine, deck.Clubs):   false,
-				deck.NewCard(deck.Six, deck.Diamonds): false,
-			},
+			nil,
 		)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearCards,
 			Deck:          deck.Deck{},
 			Pile:          pile,
@@ -971,9 +1293,17 @@ func TestGameStageTwo(t *testing.T) {
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Jack),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Seven, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
+		chosenCard = game.PlayerCards["p1"].Unseen[0] // now with its ID
+
 		// When the player takes their turn
 		msgs, err := game.Next()
 		utils.AssertNoError(t, err)
@@ -1006,8 +1336,8 @@ func TestGameStageTwo(t *testing.T) {
 		// but their chosen card is now visible
 		utils.AssertEqual(t, newUnseenSize, oldUnseenSize)
 		utils.AssertEqual(t, newHandSize, 0)
-		for card, visible := range game.PlayerCards["p1"].UnseenVisibility {
-			if card == chosenCard {
+		for id, visible := range game.PlayerCards["p1"].UnseenVisibility {
+			if id == chosenCard.ID {
 				utils.AssertTrue(t, visible)
 			} else {
 				utils.AssertEqual(t, visible, false)
@@ -1051,14 +1381,10 @@ func TestGameStageTwo(t *testing.T) {
 				deck.NewCard(deck.Nine, deck.Clubs),
 				deck.NewCard(deck.Six, deck.Diamonds),
 			},
-			map[deck.Card]bool{
-				chosenCard:                            false,
-				deck.NewCard(deck.Nine, deck.Clubs):   false,
-				deck.NewCard(deck.Six, deck.Diamonds): false,
-			},
+			nil,
 		)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearCards,
 			Deck:          deck.Deck{},
 			Pile:          pile,
@@ -1066,9 +1392,16 @@ func TestGameStageTwo(t *testing.T) {
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Jack),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Seven, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
+		chosenCard = game.PlayerCards["p1"].Unseen[0] // now with its ID
 
 		// When the player takes their turn
 		msgs, err := game.Next()
@@ -1107,7 +1440,7 @@ func TestGameStageTwo(t *testing.T) {
 		utils.AssertEqual(t, oldPileSize, newPileSize)
 		utils.AssertDeepEqual(t, oldHand, newHand)
 		// And the player's chosen card has been flipped
-		utils.AssertTrue(t, game.PlayerCards[playerID].UnseenVisibility[chosenCard])
+		utils.AssertTrue(t, game.PlayerCards[playerID].UnseenVisibility[chosenCard.ID])
 
 		// check which messages are sent out
 
@@ -1147,7 +1480,7 @@ func TestGameStageTwo(t *testing.T) {
 		// And a player with one remaining Unseen card
 		pc := NewPlayerCards(nil, nil, []deck.Card{highValueCard}, nil)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearCards,
 			Deck:          deck.Deck{},
 			Pile:          pile,
@@ -1155,10 +1488,21 @@ func TestGameStageTwo(t *testing.T) {
 			CurrentPlayer: threePlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
-				"p3": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+				"p3": NewPlayerCards(
+					cardsOf(deck.Spades, deck.Five, deck.Six, deck.Jack),
+					cardsOf(deck.Spades, deck.Eight, deck.Queen, deck.King),
+					cardsOf(deck.Spades, deck.Three, deck.Seven, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		// When the player takes a legal turn
 		msgs, err := game.Next()
@@ -1222,7 +1566,7 @@ func TestGameStageTwo(t *testing.T) {
 			nil, nil, nil,
 		)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearCards,
 			Deck:          deck.Deck{},
 			Pile:          pile,
@@ -1230,10 +1574,21 @@ func TestGameStageTwo(t *testing.T) {
 			CurrentPlayer: threePlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
-				"p3": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
+				"p3": NewPlayerCards(
+					cardsOf(deck.Spades, deck.Five, deck.Six, deck.Jack),
+					cardsOf(deck.Spades, deck.Eight, deck.Queen, deck.King),
+					cardsOf(deck.Spades, deck.Three, deck.Seven, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		// When the player takes a legal turn
 		msgs, err := game.Next()
@@ -1285,7 +1640,7 @@ func TestGameStageTwoToGameOver(t *testing.T) {
 		// And a player with one remaining Unseen card
 		pc := NewPlayerCards(nil, nil, []deck.Card{highValueCard}, nil)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearCards,
 			Deck:          deck.Deck{},
 			Pile:          pile,
@@ -1293,9 +1648,15 @@ func TestGameStageTwoToGameOver(t *testing.T) {
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		// When the player takes a legal turn
 		msgs, err := game.Next()
@@ -1356,7 +1717,7 @@ func TestGameStageTwoToGameOver(t *testing.T) {
 		// And a player with one remaining Hand card
 		pc := NewPlayerCards([]deck.Card{highValueCard}, nil, nil, nil)
 
-		game := ExistingShed(ShedOpts{
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
 			Stage:         clearCards,
 			Deck:          deck.Deck{},
 			Pile:          pile,
@@ -1364,9 +1725,15 @@ func TestGameStageTwoToGameOver(t *testing.T) {
 			CurrentPlayer: twoPlayers()[0],
 			PlayerCards: map[string]*PlayerCards{
 				"p1": pc,
-				"p2": somePlayerCards(3),
+				"p2": NewPlayerCards(
+					cardsOf(deck.Diamonds, deck.Four, deck.Five, deck.Six),
+					cardsOf(deck.Diamonds, deck.Jack, deck.Queen, deck.King),
+					cardsOf(deck.Diamonds, deck.Ace, deck.Eight, deck.Nine),
+					nil,
+				),
 			},
-		})
+		}))
+		utils.AssertNoError(t, err)
 
 		// When the player takes a legal turn
 		msgs, err := game.Next()
@@ -1,5 +1,13 @@
 package game
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minaorangina/shed/deck"
+	"github.com/minaorangina/shed/protocol"
+)
+
 // Stage represents the game-specific stages.
 type Stage int
 
@@ -17,6 +25,39 @@ const (
 	gameOver
 )
 
+// Direction is the order in which players take their turns
+type Direction int
+
+const (
+	Forwards Direction = iota
+	Backwards
+)
+
+// step is how far the turn index moves to reach the next player
+func (d Direction) step() int {
+	if d == Backwards {
+		return -1
+	}
+	return 1
+}
+
+func (d Direction) reversed() Direction {
+	if d == Backwards {
+		return Forwards
+	}
+	return Backwards
+}
+
+func (d Direction) String() string {
+	switch d {
+	case Forwards:
+		return "forwards"
+	case Backwards:
+		return "backwards"
+	}
+	return fmt.Sprintf("Direction(%d)", int(d))
+}
+
 type PlayerCardState int
 
 const (
@@ -26,15 +67,357 @@ const (
 	empty
 )
 
-func validateStateMachine(s *shed) bool {
-	// stages to validate
-	// pre game
-	// clear deck
-	// - all players have three cards or more
-	// - deck has at least one card
-	// - no finished players
-	// - expected commands: playhand, replenishhand, endofturn, skipturn, burn etc
-	// clear cards
-	// end game?
-	return false
+// Invariant names a rule that every valid game state obeys.
+//
+// The stage invariant allows for two states that the game passes through:
+//   - While clearing the deck, the deck is empty only once the current player has taken the last card
+//     and is yet to acknowledge their move (ReplenishHand) or a burn (Burn). Their cards are on the pile.
+//     The stage changes once they acknowledge it.
+//   - Players finish while clearing the deck only when the game has been ended as a stalemate.
+type Invariant string
+
+const (
+	InvariantPlayers      Invariant = "players"
+	InvariantCards        Invariant = "cards"
+	InvariantConservation Invariant = "card conservation"
+	InvariantStage        Invariant = "stage"
+	InvariantCommand      Invariant = "expected command"
+	InvariantTurnOrder    Invariant = "turn order"
+)
+
+// Violation describes one way in which a game state breaks an invariant
+type Violation struct {
+	Invariant Invariant
+	Detail    string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Invariant, v.Detail)
+}
+
+// StateError describes every invariant broken by an invalid game state.
+// It matches ErrInvalidGameState when used with errors.Is.
+type StateError struct {
+	Violations []Violation
+}
+
+func (e *StateError) Error() string {
+	details := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		details = append(details, v.String())
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidGameState, strings.Join(details, "; "))
+}
+
+func (e *StateError) Is(target error) bool {
+	return target == ErrInvalidGameState
+}
+
+// commandsByStage lists the commands a game may await in each stage
+var commandsByStage = map[Stage][]protocol.Cmd{
+	preGame: {protocol.Null, protocol.Reorg},
+	clearDeck: {
+		protocol.Null, protocol.PlayHand, protocol.SkipTurn,
+		protocol.ReplenishHand, protocol.Burn, protocol.PickUpPile,
+	},
+	clearCards: {
+		protocol.Null, protocol.PlayHand, protocol.PlaySeen, protocol.PlayUnseen,
+		protocol.SkipTurn, protocol.EndOfTurn, protocol.Burn,
+		protocol.UnseenSuccess, protocol.UnseenFailure, protocol.PlayerFinished,
+		protocol.PlayHandAndSeen, protocol.PickUpPile,
+	},
+}
+
+// Validate checks a game of Shed against every invariant,
+// returning a *StateError listing all violations, or nil if the state is valid.
+// Games other than Shed are not checked.
+func Validate(g Game) error {
+	if s, ok := g.(*shed); ok {
+		return validateStateMachine(s)
+	}
+	return nil
+}
+
+// validateStateMachine checks the game against every invariant,
+// returning a *StateError listing all violations, or nil if the state is valid.
+func validateStateMachine(s *shed) error {
+	if s == nil {
+		return ErrNilGame
+	}
+
+	v := &validator{}
+
+	v.checkPlayers(s)
+	v.checkCards(s)
+	v.checkStage(s)
+	v.checkCommand(s)
+	v.checkTurnOrder(s)
+
+	if len(v.violations) > 0 {
+		return &StateError{Violations: v.violations}
+	}
+	return nil
+}
+
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) add(inv Invariant, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{
+		Invariant: inv,
+		Detail:    fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) checkPlayers(s *shed) {
+	if len(s.PlayerInfo) < minPlayers || len(s.PlayerInfo) > maxPlayers {
+		v.add(InvariantPlayers, "%d players, want between %d and %d",
+			len(s.PlayerInfo), minPlayers, maxPlayers)
+	}
+
+	known := map[string]bool{}
+	for _, p := range s.PlayerInfo {
+		if known[p.PlayerID] {
+			v.add(InvariantPlayers, "player %s appears more than once", p.PlayerID)
+		}
+		known[p.PlayerID] = true
+
+		if s.PlayerCards[p.PlayerID] == nil {
+			v.add(InvariantPlayers, "player %s has no cards", p.PlayerID)
+		}
+	}
+
+	for id := range s.PlayerCards {
+		if !known[id] {
+			v.add(InvariantPlayers, "cards held by unknown player %s", id)
+		}
+	}
+
+	// every player is either active or finished, never both
+	status := map[string]int{}
+	for _, p := range s.ActivePlayers {
+		status[p.PlayerID]++
+	}
+	for i, p := range s.FinishedPlayers {
+		status[p.PlayerID]++
+
+		// once the game is over, the last player is left holding their cards,
+		// unless the game was ended as a stalemate, when everyone yet to finish still has theirs
+		isLoser := s.gameOver || s.gamePlay == gameOver
+		isLoser = isLoser && (i == len(s.FinishedPlayers)-1 || s.endReason != "")
+		if pc := s.PlayerCards[p.PlayerID]; pc != nil && numCards(pc) > 0 && !isLoser {
+			v.add(InvariantPlayers, "finished player %s still has cards", p.PlayerID)
+		}
+	}
+	for id, n := range status {
+		if !known[id] {
+			v.add(InvariantPlayers, "unknown player %s is in play", id)
+		} else if n > 1 {
+			v.add(InvariantPlayers, "player %s is listed more than once", id)
+		}
+	}
+	for id := range known {
+		if status[id] == 0 {
+			v.add(InvariantPlayers, "player %s is neither active nor finished", id)
+		}
+	}
+
+	if s.gameOver || s.gamePlay == gameOver {
+		if len(s.ActivePlayers) > 0 {
+			v.add(InvariantPlayers, "game is over but %d players are active", len(s.ActivePlayers))
+		}
+		return
+	}
+
+	if !sliceContainsPlayerID(s.ActivePlayers, s.CurrentPlayer.PlayerID) {
+		v.add(InvariantPlayers, "current player %q is not active", s.CurrentPlayer.PlayerID)
+	}
+}
+
+func (v *validator) checkTurnOrder(s *shed) {
+	if s.Direction != Forwards && s.Direction != Backwards {
+		v.add(InvariantTurnOrder, "unknown direction %d", s.Direction)
+	}
+	if s.SkipCount < 0 {
+		v.add(InvariantTurnOrder, "skip count %d is negative", s.SkipCount)
+	}
+	if s.playerRepeatsTurn && s.ExpectedCommand != protocol.Burn {
+		v.add(InvariantTurnOrder, "player repeats their turn without a burn")
+	}
+}
+
+func (v *validator) checkCards(s *shed) {
+	counts := map[deck.Card]int{}
+	ids := map[int]bool{}
+	total := 0
+
+	count := func(where string, cards []deck.Card) {
+		for _, c := range cards {
+			isJoker := c.Rank == deck.Joker && c.Suit == deck.NullSuit
+			isSuited := c.Rank >= deck.Ace && c.Rank <= deck.King && c.Suit >= deck.Clubs && c.Suit <= deck.Spades
+			if !isJoker && !isSuited {
+				v.add(InvariantCards, "%s holds an invalid card %v", where, c)
+				continue
+			}
+			counts[c.Face()]++
+			total++
+
+			if c.ID <= 0 {
+				v.add(InvariantCards, "%s holds %v without an ID", where, c)
+			} else if ids[c.ID] {
+				v.add(InvariantCards, "card ID %d is used more than once", c.ID)
+			}
+			ids[c.ID] = true
+		}
+	}
+
+	count("deck", s.Deck)
+	count("pile", s.Pile)
+	count("burned pile", s.Burned)
+	for _, p := range s.PlayerInfo {
+		pc := s.PlayerCards[p.PlayerID]
+		if pc == nil {
+			continue
+		}
+		count(fmt.Sprintf("player %s's hand", p.PlayerID), pc.Hand)
+		count(fmt.Sprintf("player %s's seen cards", p.PlayerID), pc.Seen)
+		count(fmt.Sprintf("player %s's unseen cards", p.PlayerID), pc.Unseen)
+
+		if len(pc.Seen) > numCardsInGroup || len(pc.Unseen) > numCardsInGroup {
+			v.add(InvariantCards, "player %s has %d seen and %d unseen cards, want at most %d of each",
+				p.PlayerID, len(pc.Seen), len(pc.Unseen), numCardsInGroup)
+		}
+		if len(pc.Unseen) < numCardsInGroup && len(pc.Seen) > 0 {
+			v.add(InvariantCards, "player %s has played unseen cards before seen cards", p.PlayerID)
+		}
+	}
+
+	// a game for more players is played with several decks, holding several of each card
+	shoe := map[deck.Card]int{}
+	for _, c := range s.newShoe() {
+		shoe[c.Face()]++
+	}
+	for c, n := range counts {
+		if n > shoe[c] {
+			v.add(InvariantCards, "%v appears %d times, want at most %d", c, n, shoe[c])
+		}
+	}
+
+	if want := len(s.newShoe()); total != want {
+		v.add(InvariantConservation, "game holds %d cards, want %d", total, want)
+	}
+	if s.lastBurnedCount < 0 || s.lastBurnedCount > len(s.Burned) {
+		v.add(InvariantCards, "last burn of %d cards, but %d cards burned", s.lastBurnedCount, len(s.Burned))
+	}
+}
+
+func (v *validator) checkStage(s *shed) {
+	switch s.Stage {
+	case preGame:
+		if len(s.Pile) > 0 {
+			v.add(InvariantStage, "pile has %d cards before play has begun", len(s.Pile))
+		}
+		if len(s.FinishedPlayers) > 0 {
+			v.add(InvariantStage, "players have finished before play has begun")
+		}
+		for _, p := range s.PlayerInfo {
+			pc := s.PlayerCards[p.PlayerID]
+			if pc == nil {
+				continue
+			}
+			if len(pc.Hand) != numCardsInGroup || len(pc.Seen) != numCardsInGroup || len(pc.Unseen) != numCardsInGroup {
+				v.add(InvariantStage, "player %s has %d/%d/%d cards before play has begun, want %d of each",
+					p.PlayerID, len(pc.Hand), len(pc.Seen), len(pc.Unseen), numCardsInGroup)
+			}
+		}
+
+	case clearDeck:
+		// the deck may run out mid-turn; the stage changes once the turn is acknowledged
+		ranOut := (s.ExpectedCommand == protocol.ReplenishHand || s.ExpectedCommand == protocol.Burn) && len(s.Pile) > 0
+		if len(s.Deck) == 0 && !ranOut {
+			v.add(InvariantStage, "deck is empty while clearing the deck")
+		}
+		// a stalemate can end the game before anyone has finished
+		stalemate := s.endReason != "" && (s.gameOver || s.gamePlay == gameOver)
+		if len(s.FinishedPlayers) > 0 && !stalemate {
+			v.add(InvariantStage, "players have finished while clearing the deck")
+		}
+
+	case clearCards:
+		if len(s.Deck) > 0 {
+			v.add(InvariantStage, "deck has %d cards while clearing cards", len(s.Deck))
+		}
+
+	default:
+		v.add(InvariantStage, "unknown stage %d", s.Stage)
+	}
+}
+
+func (v *validator) checkCommand(s *shed) {
+	if s.gameOver || s.gamePlay == gameOver {
+		if s.ExpectedCommand != protocol.Null {
+			v.add(InvariantCommand, "game is over but awaiting %s", s.ExpectedCommand)
+		}
+		return
+	}
+
+	allowed := false
+	for _, cmd := range commandsByStage[s.Stage] {
+		if cmd == s.ExpectedCommand {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		v.add(InvariantCommand, "%s cannot be awaited in stage %d", s.ExpectedCommand, s.Stage)
+	}
+
+	awaitingUnseen := s.ExpectedCommand == protocol.UnseenSuccess || s.ExpectedCommand == protocol.UnseenFailure
+	if awaitingUnseen && s.unseenDecision == nil {
+		v.add(InvariantCommand, "awaiting %s with no unseen card played", s.ExpectedCommand)
+	}
+	if !awaitingUnseen && s.unseenDecision != nil {
+		v.add(InvariantCommand, "unseen card played but awaiting %s", s.ExpectedCommand)
+	}
+
+	pc := s.PlayerCards[s.CurrentPlayer.PlayerID]
+	if pc == nil {
+		return
+	}
+
+	if awaitingUnseen && s.unseenDecision != nil {
+		for _, idx := range s.unseenDecision.Decision {
+			if idx < 0 || idx >= len(pc.Unseen) {
+				v.add(InvariantCommand, "unseen card %d played, but player has %d", idx, len(pc.Unseen))
+			}
+		}
+	}
+
+	switch s.ExpectedCommand {
+	case protocol.PlaySeen:
+		if len(pc.Hand) > 0 || len(pc.Seen) == 0 {
+			v.add(InvariantCommand, "awaiting %s but player %s has %d hand and %d seen cards",
+				s.ExpectedCommand, s.CurrentPlayer.PlayerID, len(pc.Hand), len(pc.Seen))
+		}
+	case protocol.PlayHandAndSeen:
+		if len(pc.Hand) == 0 || len(pc.Seen) == 0 {
+			v.add(InvariantCommand, "awaiting %s but player %s has %d hand and %d seen cards",
+				s.ExpectedCommand, s.CurrentPlayer.PlayerID, len(pc.Hand), len(pc.Seen))
+		}
+	case protocol.PlayUnseen:
+		if len(pc.Hand) > 0 || len(pc.Seen) > 0 || len(pc.Unseen) == 0 {
+			v.add(InvariantCommand, "awaiting %s but player %s still has hand or seen cards, or no unseen cards",
+				s.ExpectedCommand, s.CurrentPlayer.PlayerID)
+		}
+	case protocol.PlayerFinished:
+		if numCards(pc) > 0 {
+			v.add(InvariantCommand, "awaiting %s but player %s still has cards",
+				s.ExpectedCommand, s.CurrentPlayer.PlayerID)
+		}
+	}
+}
+
+func numCards(pc *PlayerCards) int {
+	return len(pc.Hand) + len(pc.Seen) + len(pc.Unseen)
 }
@@ -0,0 +1,142 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/minaorangina/shed/deck"
+	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
+)
+
+func TestValidateStateMachine(t *testing.T) {
+	t.Run("a newly dealt game is valid", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: fourPlayers()})
+		utils.AssertNoError(t, err)
+
+		utils.AssertNoError(t, validateStateMachine(game))
+	})
+
+	t.Run("reports every violation", func(t *testing.T) {
+		// Given a game that breaks several invariants at once
+		game, err := NewShed(ShedOpts{Players: twoPlayers()})
+		utils.AssertNoError(t, err)
+
+		game.Stage = clearCards                                 // the deck is not empty
+		game.ExpectedCommand = protocol.Reorg                   // and Reorg is impossible in clearCards
+		game.Pile = []deck.Card{game.PlayerCards["p1"].Hand[0]} // and a card is duplicated, making 53
+
+		// When it is validated
+		err = validateStateMachine(game)
+
+		// Then every violation is described
+		utils.AssertErrored(t, err)
+		utils.AssertTrue(t, errors.Is(err, ErrInvalidGameState))
+
+		var stateErr *StateError
+		utils.AssertTrue(t, errors.As(err, &stateErr))
+
+		invariants := map[Invariant]bool{}
+		for _, v := range stateErr.Violations {
+			invariants[v.Invariant] = true
+		}
+		utils.AssertEqual(t, len(invariants), 4)
+		utils.AssertTrue(t, invariants[InvariantConservation])
+		utils.AssertTrue(t, invariants[InvariantStage])
+		utils.AssertTrue(t, invariants[InvariantCommand])
+		utils.AssertTrue(t, invariants[InvariantCards])
+	})
+
+	t.Run("a pending unseen card must be awaiting its outcome", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers()})
+		utils.AssertNoError(t, err)
+
+		game.unseenDecision = &protocol.InboundMessage{
+			PlayerID: game.CurrentPlayer.PlayerID,
+			Command:  protocol.PlayUnseen,
+			Decision: []int{0},
+		}
+
+		err = validateStateMachine(game)
+		utils.AssertTrue(t, errors.Is(err, ErrInvalidGameState))
+	})
+
+	t.Run("the deck runs out while clearing the deck only until the move is acknowledged", func(t *testing.T) {
+		for _, cmd := range []protocol.Cmd{protocol.ReplenishHand, protocol.Burn, protocol.PlayHand} {
+			t.Run(cmd.String(), func(t *testing.T) {
+				game, err := NewShed(ShedOpts{Players: twoPlayers(), Seed: 7})
+				utils.AssertNoError(t, err)
+
+				// Given a card has been played, and the deck has run out
+				game.Stage = clearDeck
+				game.ExpectedCommand = cmd
+				game.Pile, game.Burned, game.Deck = game.Deck[:1], game.Deck[1:], deck.Deck{}
+
+				// Then the state is valid only while their move is yet to be acknowledged
+				err = validateStateMachine(game)
+				if cmd == protocol.PlayHand {
+					utils.AssertTrue(t, errors.Is(err, ErrInvalidGameState))
+				} else {
+					utils.AssertNoError(t, err)
+				}
+			})
+		}
+	})
+
+	t.Run("players finish while clearing the deck only in a stalemate", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers(), Seed: 7})
+		utils.AssertNoError(t, err)
+		game.Stage = clearDeck
+
+		// A stalemate ends the game, with everyone finished
+		game.endStalemate(protocol.MoveLimitReached)
+		utils.AssertNoError(t, validateStateMachine(game))
+
+		// But players cannot finish while the game goes on
+		game.gamePlay = gameInProgress
+		game.ActivePlayers = game.FinishedPlayers[1:]
+		game.FinishedPlayers = game.FinishedPlayers[:1]
+		game.CurrentPlayer = game.ActivePlayers[0]
+		game.CurrentTurnIdx = 0
+		utils.AssertTrue(t, errors.Is(validateStateMachine(game), ErrInvalidGameState))
+	})
+
+	t.Run("the loser still holds their cards once the game is over", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: threePlayers(), Seed: 7})
+		utils.AssertNoError(t, err)
+
+		for step := 0; step < 3000 && !game.GameOver(); step++ {
+			if game.AwaitingResponse() == protocol.Null {
+				_, err = game.Next()
+			} else {
+				_, err = game.ReceiveResponse(firstLegalResponses(game))
+			}
+			utils.AssertNoError(t, err)
+		}
+		utils.AssertTrue(t, game.GameOver())
+
+		loser := game.FinishedPlayers[len(game.FinishedPlayers)-1]
+		utils.AssertTrue(t, numCards(game.PlayerCards[loser.PlayerID]) > 0)
+		utils.AssertNoError(t, validateStateMachine(game))
+	})
+
+	t.Run("game stays valid throughout play", func(t *testing.T) {
+		for _, seed := range []int64{7, 99, 31337} {
+			t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+				game, err := NewShed(ShedOpts{Players: threePlayers(), Seed: seed})
+				utils.AssertNoError(t, err)
+
+				for step := 0; step < 500 && !game.GameOver(); step++ {
+					if game.AwaitingResponse() == protocol.Null {
+						_, err = game.Next()
+					} else {
+						_, err = game.ReceiveResponse(firstLegalResponses(game))
+					}
+					utils.AssertNoError(t, err)
+					utils.AssertNoError(t, validateStateMachine(game))
+				}
+			})
+		}
+	})
+}
@@ -1,25 +1,31 @@
 package game
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/minaorangina/shed/protocol"
 )
 
 func (s *shed) buildBaseMessage(playerID string) protocol.OutboundMessage {
-	playerCards := s.PlayerCards[playerID]
-	publicUnseen := s.mapUnseenToPublicUnseen(playerID)
-
-	return protocol.OutboundMessage{
+	msg := protocol.OutboundMessage{
 		PlayerID:    playerID,
 		CurrentTurn: s.CurrentPlayer,
 		NextTurn:    s.nextPlayer(),
-		Hand:        playerCards.Hand,
-		Seen:        playerCards.Seen,
-		Unseen:      publicUnseen,
 		Pile:        s.Pile,
 		DeckCount:   len(s.Deck),
+		BurnedCount: len(s.Burned),
+		LastBurned:  s.lastBurned(),
+	}
+
+	// messages can be sent to unknown players, e.g. to reject their move
+	if playerCards, ok := s.PlayerCards[playerID]; ok {
+		msg.Hand = playerCards.Hand
+		msg.Seen = playerCards.Seen
+		msg.Unseen = s.mapUnseenToPublicUnseen(playerID)
 	}
+
+	return msg
 }
 
 func (s *shed) buildOpponents(playerID string) []protocol.Opponent {
@@ -38,6 +44,22 @@ func (s *shed) buildOpponents(playerID string) []protocol.Opponent {
 	return opponents
 }
 
+// ViewOf returns what the player can see of the game: the table, their own cards,
+// and their opponents' face-up cards. Anyone who is not playing sees only the table.
+// It returns false if g is not a game of Shed.
+func ViewOf(g Game, playerID string) (protocol.OutboundMessage, bool) {
+	s, ok := g.(*shed)
+	if !ok {
+		return protocol.OutboundMessage{}, false
+	}
+
+	msg := s.buildBaseMessage(playerID)
+	msg.Opponents = s.buildOpponents(playerID)
+	msg.FinishedPlayers = s.FinishedPlayers
+
+	return msg, true
+}
+
 func (s *shed) buildReorgMessages() []protocol.OutboundMessage {
 	msgs := []protocol.OutboundMessage{}
 
@@ -96,6 +118,56 @@ func (s *shed) buildSkipTurnMessages(currentPlayerCmd protocol.Cmd) []protocol.O
 	return toSend
 }
 
+func (s *shed) buildPickUpPileMessage(playerID string) protocol.OutboundMessage {
+	msg := s.buildBaseMessage(playerID)
+	msg.Command = protocol.PickUpPile
+	msg.Message = fmt.Sprintf("%s picks up the pile!", s.CurrentPlayer.Name)
+	msg.Opponents = s.buildOpponents(playerID)
+
+	return msg
+}
+
+func (s *shed) buildPickUpPileMessages() []protocol.OutboundMessage {
+	currentPlayerMsg := s.buildPickUpPileMessage(s.CurrentPlayer.PlayerID)
+	currentPlayerMsg.Message = "You pick up the pile!"
+	currentPlayerMsg.ShouldRespond = true
+
+	toSend := []protocol.OutboundMessage{currentPlayerMsg}
+	for _, info := range s.PlayerInfo {
+		if info.PlayerID != s.CurrentPlayer.PlayerID {
+			toSend = append(toSend, s.buildPickUpPileMessage(info.PlayerID))
+		}
+	}
+
+	return toSend
+}
+
+func (s *shed) buildUndoMessages(mover protocol.Player) []protocol.OutboundMessage {
+	toSend := []protocol.OutboundMessage{}
+	for _, info := range s.PlayerInfo {
+		msg := s.buildBaseMessage(info.PlayerID)
+		msg.Command = protocol.Undo
+		msg.Message = fmt.Sprintf("%s's last move was undone.", mover.Name)
+		msg.Opponents = s.buildOpponents(info.PlayerID)
+		toSend = append(toSend, msg)
+	}
+
+	return toSend
+}
+
+func (s *shed) buildUndoRequestedMessages(requester, mover protocol.Player, votes int) []protocol.OutboundMessage {
+	toSend := []protocol.OutboundMessage{}
+	for _, info := range s.PlayerInfo {
+		msg := s.buildBaseMessage(info.PlayerID)
+		msg.Command = protocol.UndoRequested
+		msg.Message = fmt.Sprintf("%s wants to undo %s's last move. %d of %d players agree.",
+			requester.Name, mover.Name, votes, len(s.PlayerInfo))
+		toSend = append(toSend, msg)
+	}
+
+	return toSend
+}
+
 func (s *shed) buildTurnMessage(playerID string) protocol.OutboundMessage {
 	msg := s.buildBaseMessage(playerID)
 	msg.Command = protocol.Turn
@@ -114,6 +186,8 @@ func (s *shed) buildTurnMessages(currentPlayerCmd protocol.Cmd, moves []int) []p
 		toPlay = "face-up"
 	case protocol.PlayUnseen:
 		toPlay = "face-down"
+	case protocol.PlayHandAndSeen:
+		toPlay = "hand and matching face-up"
 	}
 
 	displayMsg := "It's your turn!"
@@ -199,17 +273,29 @@ func (s *shed) buildPlayerFinishedMessages() []protocol.OutboundMessage {
 }
 
 func (s *shed) buildGameOverMessages() []protocol.OutboundMessage {
+	drawn := s.drawnPlayers()
 	toSend := []protocol.OutboundMessage{}
 	for _, info := range s.PlayerInfo {
 		msg := s.buildBaseMessage(info.PlayerID)
 		msg.Command = protocol.GameOver
 		msg.FinishedPlayers = s.FinishedPlayers
+		msg.EndReason = s.endReason
+		msg.Drawn = drawn
 
 		var result string
 		leagueTable := "Results:\n"
-		ordinalNumbers := []string{"1st", "2nd", "3rd", "4th"}
+		ordinalNumbers := []string{"1st", "2nd", "3rd", "4th", "5th", "6th", "7th", "8th"}
 
 		for position, finshedPlayer := range s.FinishedPlayers {
+			if sliceContainsPlayerID(drawn, finshedPlayer.PlayerID) {
+				// drawn players share the first place that is left
+				leagueTable += fmt.Sprintf("=%s - %s\n", ordinalNumbers[len(s.FinishedPlayers)-len(drawn)], finshedPlayer.Name)
+				if info.PlayerID == finshedPlayer.PlayerID {
+					result = "drew"
+				}
+				continue
+			}
+
 			leagueTable += fmt.Sprintf("%s - %s\n", ordinalNumbers[position], finshedPlayer.Name)
 
 			if info.PlayerID == finshedPlayer.PlayerID {
@@ -225,6 +311,12 @@ func (s *shed) buildGameOverMessages() []protocol.OutboundMessage {
 		}
 
 		msg.Message = fmt.Sprintf("Game over! You %s\n%s", result, leagueTable)
+		switch s.endReason {
+		case protocol.RepeatedPosition:
+			msg.Message = "The same position kept coming round, so the game has ended.\n" + msg.Message
+		case protocol.MoveLimitReached:
+			msg.Message = "The game reached its move limit, so it has ended.\n" + msg.Message
+		}
 		toSend = append(toSend, msg)
 	}
 
@@ -234,7 +326,7 @@ func (s *shed) buildGameOverMessages() []protocol.OutboundMessage {
 func (s *shed) buildBurnMessage(playerID string) protocol.OutboundMessage {
 	msg := s.buildBaseMessage(playerID)
 	msg.Command = protocol.Burn
-	msg.Message = fmt.Sprintf("Burn for %s!", s.CurrentPlayer.Name)
+	msg.Message = fmt.Sprintf("Burn for %s! %s plays next.", s.CurrentPlayer.Name, msg.NextTurn.Name)
 
 	return msg
 }
@@ -266,6 +358,11 @@ func (s *shed) buildErrorMessage(playerID string, err error) protocol.OutboundMe
 	msg := s.buildBaseMessage(playerID)
 	msg.Command = protocol.Error
 	msg.Message = fmt.Sprintf("game error: %q", err.Error())
+
+	var moveErr *MoveError
+	if errors.As(err, &moveErr) {
+		msg.MoveError = moveErr.toProtocol()
+	}
 	msg.ShouldRespond = s.AwaitingResponse() != protocol.Null && s.CurrentPlayer.PlayerID == playerID
 
 	return msg
@@ -18,7 +18,7 @@ func TestBuildBaseMessage(t *testing.T) {
 			Name:     "Helena",
 		},
 	}
-	shed, err := NewShed(players)
+	shed, err := NewShed(ShedOpts{Players: players})
 	require.NoError(t, err)
 
 	msgs, err := shed.Next()
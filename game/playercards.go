@@ -4,12 +4,12 @@ import "github.com/minaorangina/shed/deck"
 
 type PlayerCards struct {
 	Hand, Seen, Unseen []deck.Card
-	UnseenVisibility   map[deck.Card]bool
+	UnseenVisibility   map[int]bool // keyed by card ID
 }
 
 func NewPlayerCards(
 	hand, seen, unseen []deck.Card,
-	unseenVisibility map[deck.Card]bool,
+	unseenVisibility map[int]bool,
 ) *PlayerCards {
 	if hand == nil {
 		hand = []deck.Card{}
@@ -21,9 +21,9 @@ func NewPlayerCards(
 		unseen = []deck.Card{}
 	}
 	if unseenVisibility == nil {
-		unseenVisibility = map[deck.Card]bool{}
+		unseenVisibility = map[int]bool{}
 		for _, c := range unseen {
-			unseenVisibility[c] = false
+			unseenVisibility[c.ID] = false
 		}
 	}
 
@@ -0,0 +1,228 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/minaorangina/shed/deck"
+	"github.com/minaorangina/shed/protocol"
+)
+
+var (
+	// ErrReplayDiverged is returned when replaying a game does not reproduce its event log,
+	// e.g. because the log has been altered, or the rules of the game have changed since.
+	ErrReplayDiverged = errors.New("replay diverged from the event log")
+	// ErrReplayFinished is returned when every event in the log has been replayed
+	ErrReplayFinished = errors.New("replay has reached the end of the event log")
+)
+
+// maxActionsPerEvent stops a replay that is no longer producing events
+const maxActionsPerEvent = 1000
+
+// Replay steps through a recorded game, one event at a time.
+// A game is recorded by its Snapshot, which holds its seed, players and rules,
+// from which it is dealt again, and its event log, from which every move is made again.
+// Each event replayed is checked against the log.
+type Replay struct {
+	record Snapshot
+	game   *shed
+}
+
+// NewReplay sets up a recorded game, ready to be dealt
+func NewReplay(record Snapshot) (*Replay, error) {
+	if err := checkVersion(record.Version); err != nil {
+		return nil, err
+	}
+	if err := record.Rules.validateFor(record.Jokers); err != nil {
+		return nil, err
+	}
+	if len(record.PlayerInfo) < minPlayers || len(record.PlayerInfo) > maxPlayers {
+		return nil, fmt.Errorf("cannot replay a game of %d players", len(record.PlayerInfo))
+	}
+
+	game := newShed(ShedOpts{
+		Seed:          record.Seed,
+		Rules:         record.Rules,
+		Jokers:        record.Jokers,
+		Creator:       record.Creator,
+		PreviousLoser: record.PreviousLoser,
+	})
+
+	return &Replay{record: record, game: game}, nil
+}
+
+// ReplayTo reconstructs a recorded game as it was just after the event numbered seq
+func ReplayTo(record Snapshot, seq int) (*shed, error) {
+	r, err := NewReplay(record)
+	if err != nil {
+		return nil, err
+	}
+	for r.Seq() < seq {
+		if err := r.Step(); err != nil {
+			return nil, err
+		}
+	}
+	return r.Game(), nil
+}
+
+// Game returns the game as replayed so far
+func (r *Replay) Game() *shed {
+	return r.game
+}
+
+// Seq returns the number of the last event replayed, or zero if none have been
+func (r *Replay) Seq() int {
+	return len(r.game.events)
+}
+
+// Len returns the number of events in the log
+func (r *Replay) Len() int {
+	return len(r.record.Events)
+}
+
+// Events returns the recorded events replayed so far, oldest first
+func (r *Replay) Events() []Event {
+	if r.Seq() > r.Len() {
+		return copyEvents(r.record.Events)
+	}
+	return copyEvents(r.record.Events[:r.Seq()])
+}
+
+// Step replays the game until it produces the next event in the log.
+// Events that happen together, like the deal, are replayed together.
+func (r *Replay) Step() error {
+	start := r.Seq()
+	if start >= r.Len() {
+		return ErrReplayFinished
+	}
+
+	for actions := 0; r.Seq() == start; actions++ {
+		if actions == maxActionsPerEvent {
+			return fmt.Errorf("%w: nothing happened after event %d", ErrReplayDiverged, start)
+		}
+		if err := r.act(); err != nil {
+			return err
+		}
+	}
+
+	return r.check(start)
+}
+
+// act takes the game one step further, as the log says the players did
+func (r *Replay) act() error {
+	g := r.game
+	if len(g.PlayerInfo) == 0 { // not yet dealt
+		return g.Start(copyPlayers(r.record.PlayerInfo))
+	}
+	if g.GameOver() {
+		return fmt.Errorf("%w: the game ended after event %d", ErrReplayDiverged, r.Seq())
+	}
+	if g.ExpectedCommand == protocol.Null {
+		_, err := g.Next()
+		return err
+	}
+
+	msgs, err := r.response()
+	if err != nil {
+		return err
+	}
+	if _, err := g.ReceiveResponse(msgs); err != nil {
+		return fmt.Errorf("%w: event %d: %v", ErrReplayDiverged, r.Seq()+1, err)
+	}
+	return nil
+}
+
+// response returns the messages the players sent to cause the next event in the log
+func (r *Replay) response() ([]protocol.InboundMessage, error) {
+	g := r.game
+	next := r.record.Events[r.Seq()]
+
+	// undos and snaps can happen while the game waits for something else
+	switch next.Kind {
+	case EventUndone:
+		// a move its mover could no longer take back alone was undone by everyone's vote
+		if len(g.history) > 0 && !g.history[len(g.history)-1].solo {
+			votes := []protocol.InboundMessage{}
+			for _, p := range g.PlayerInfo {
+				votes = append(votes, protocol.InboundMessage{PlayerID: p.PlayerID, Command: protocol.Undo})
+			}
+			return votes, nil
+		}
+		return []protocol.InboundMessage{{PlayerID: next.PlayerID, Command: protocol.Undo}}, nil
+	case EventSnapped:
+		return []protocol.InboundMessage{{
+			PlayerID: next.PlayerID, Command: protocol.Snap, CardIDs: cardIDs(next.Cards),
+		}}, nil
+	}
+
+	playerID := g.CurrentPlayer.PlayerID
+	switch g.ExpectedCommand {
+	case protocol.Reorg:
+		msgs := []protocol.InboundMessage{}
+		for _, e := range r.record.Events[r.Seq():] {
+			if e.Kind != EventReorganised {
+				break
+			}
+			msgs = append(msgs, protocol.InboundMessage{
+				PlayerID: e.PlayerID, Command: protocol.Reorg, CardIDs: cardIDs(e.Cards),
+			})
+		}
+		return msgs, nil
+
+	case protocol.PlayHand, protocol.PlaySeen, protocol.PlayHandAndSeen, protocol.PlayUnseen:
+		switch next.Kind {
+		case EventPickedUpPile:
+			return []protocol.InboundMessage{{PlayerID: playerID, Command: protocol.PickUpPile}}, nil
+
+		case EventUnseenSuccess, EventUnseenFailure:
+			return []protocol.InboundMessage{{
+				PlayerID: playerID, Command: protocol.PlayUnseen, CardIDs: cardIDs(next.Cards),
+			}}, nil
+
+		case EventPlayed:
+			ids := cardIDs(next.Cards)
+			cmd := protocol.PlayHand
+			if next.Group == SeenGroup {
+				cmd = protocol.PlaySeen
+			}
+			// hand and seen cards played together are logged as two events
+			if g.ExpectedCommand == protocol.PlayHandAndSeen && r.Seq()+1 < r.Len() {
+				ids = append(ids, cardIDs(r.record.Events[r.Seq()+1].Cards)...)
+				cmd = protocol.PlayHandAndSeen
+			}
+			return []protocol.InboundMessage{{PlayerID: playerID, Command: cmd, CardIDs: ids}}, nil
+		}
+
+		return nil, fmt.Errorf("%w: event %d: %s waiting for %s, but the log has %s",
+			ErrReplayDiverged, next.Seq, playerID, g.ExpectedCommand, next.Kind)
+	}
+
+	// everything else is an acknowledgement
+	return []protocol.InboundMessage{{PlayerID: playerID, Command: g.ExpectedCommand}}, nil
+}
+
+// check compares the events replayed since start with the log
+func (r *Replay) check(start int) error {
+	for i := start; i < r.Seq(); i++ {
+		if i >= r.Len() {
+			return fmt.Errorf("%w: event %d is not in the log", ErrReplayDiverged, i+1)
+		}
+
+		got, want := r.game.events[i], r.record.Events[i]
+		if got.Kind != want.Kind || got.PlayerID != want.PlayerID || got.Group != want.Group ||
+			!reflect.DeepEqual(got.Cards, want.Cards) {
+			return fmt.Errorf("%w: event %d was %s %s %s %v, want %s %s %s %v", ErrReplayDiverged, i+1,
+				got.PlayerID, got.Kind, got.Group, got.Cards, want.PlayerID, want.Kind, want.Group, want.Cards)
+		}
+	}
+	return nil
+}
+
+func cardIDs(cards []deck.Card) []int {
+	ids := []int{}
+	for _, c := range cards {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
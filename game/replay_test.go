@@ -0,0 +1,211 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/minaorangina/shed/deck"
+	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
+)
+
+func TestReplay(t *testing.T) {
+	rules := DefaultRules()
+	rules.Snap = true
+	rules.UndoLimit = 2
+	rules.Skip = []deck.Rank{deck.Eight}
+	rules.Reverse = []deck.Rank{deck.Nine}
+
+	t.Run("replays whole games exactly", func(t *testing.T) {
+		for seed := int64(1); seed <= 7; seed++ {
+			players := []protocol.Player{}
+			for i := 0; i < 2+int(seed)%3; i++ {
+				players = append(players, protocol.Player{PlayerID: fmt.Sprintf("p%d", i+1)})
+			}
+
+			t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+				// Given a game played to the end, with snaps and undos
+				game, err := NewShed(ShedOpts{Players: players, Rules: rules, Seed: seed})
+				utils.AssertNoError(t, err)
+				playGameWithSnapsAndUndos(t, game, seed, 3000)
+				utils.AssertTrue(t, game.GameOver())
+
+				// When it is replayed from its saved record
+				r, err := NewReplay(savedRecord(t, game))
+				utils.AssertNoError(t, err)
+				for r.Seq() < r.Len() {
+					utils.AssertNoError(t, r.Step())
+				}
+
+				// Then it ends exactly as it did
+				utils.AssertDeepEqual(t, r.Game().state(), game.state())
+				utils.AssertEqual(t, len(r.Events()), len(game.Events()))
+				utils.AssertTrue(t, errors.Is(r.Step(), ErrReplayFinished))
+			})
+		}
+	})
+
+	t.Run("replays a move undone by every player's vote", func(t *testing.T) {
+		// Given a move that its mover can no longer take back alone
+		game, err := NewShed(ShedOpts{Players: threePlayers(), Rules: rules, Seed: 3})
+		utils.AssertNoError(t, err)
+		playUntil(t, game, func(s *shed) bool {
+			return len(s.history) > 0 && !s.history[len(s.history)-1].solo
+		})
+
+		// When every player votes to undo it, and the game is played to the end
+		votes := []protocol.InboundMessage{}
+		for _, p := range game.PlayerInfo {
+			votes = append(votes, protocol.InboundMessage{PlayerID: p.PlayerID, Command: protocol.Undo})
+		}
+		_, err = game.ReceiveResponse(votes)
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.events[len(game.events)-1].Kind, EventUndone)
+		playGame(t, game, 3000)
+
+		// Then its replay ends exactly as it did
+		r, err := NewReplay(savedRecord(t, game))
+		utils.AssertNoError(t, err)
+		for r.Seq() < r.Len() {
+			utils.AssertNoError(t, r.Step())
+		}
+		utils.AssertDeepEqual(t, r.Game().state(), game.state())
+	})
+
+	t.Run("reconstructs the game at any event", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: threePlayers(), Seed: 5})
+		utils.AssertNoError(t, err)
+		playGame(t, game, 3000)
+		record := savedRecord(t, game)
+
+		for _, seq := range []int{1, 9, 12, 40, len(record.Events) / 2, len(record.Events)} {
+			// Given the same game, played only up to the event
+			original, err := NewShed(ShedOpts{Players: threePlayers(), Seed: 5})
+			utils.AssertNoError(t, err)
+			playUntil(t, original, func(s *shed) bool { return len(s.events) >= seq })
+
+			// When the game is replayed up to the event
+			replayed, err := ReplayTo(record, seq)
+			utils.AssertNoError(t, err)
+
+			// Then it is exactly as it was
+			utils.AssertDeepEqual(t, replayed.state(), original.state())
+		}
+	})
+
+	t.Run("rejects an altered event log", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers(), Seed: 5})
+		utils.AssertNoError(t, err)
+		playGame(t, game, 3000)
+
+		alterations := map[string]func(events []Event){
+			"dealt a different card": func(events []Event) {
+				events[0].Cards[0] = events[3].Cards[0]
+			},
+			"played a different card": func(events []Event) {
+				for i, e := range events {
+					if e.Kind == EventPlayed && e.Group == HandGroup {
+						events[i].Cards = []deck.Card{events[len(events)-1-i].Cards[0]}
+						return
+					}
+				}
+			},
+			"a player went missing": func(events []Event) {
+				for i, e := range events {
+					if e.Kind == EventPlayed && e.PlayerID == "p2" {
+						events[i].PlayerID = "p1"
+						return
+					}
+				}
+			},
+		}
+
+		for name, alter := range alterations {
+			t.Run(name, func(t *testing.T) {
+				record := savedRecord(t, game)
+				alter(record.Events)
+
+				r, err := NewReplay(record)
+				utils.AssertNoError(t, err)
+
+				for err == nil {
+					err = r.Step()
+				}
+				utils.AssertTrue(t, errors.Is(err, ErrReplayDiverged))
+			})
+		}
+	})
+
+	t.Run("rejects records it cannot replay", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers(), Seed: 5})
+		utils.AssertNoError(t, err)
+
+		record := game.Snapshot()
+		record.Version = SnapshotVersion + 1
+		_, err = NewReplay(record)
+		utils.AssertTrue(t, errors.Is(err, ErrSnapshotVersion))
+
+		// a version 1 record has no event log to replay
+		record = game.Snapshot()
+		record.Version = 1
+		_, err = NewReplay(record)
+		utils.AssertTrue(t, errors.Is(err, ErrSnapshotVersion))
+
+		record = game.Snapshot()
+		record.PlayerInfo = record.PlayerInfo[:1]
+		_, err = NewReplay(record)
+		utils.AssertErrored(t, err)
+	})
+}
+
+// savedRecord returns the game's record as it would be loaded from storage
+func savedRecord(t *testing.T, game *shed) Snapshot {
+	t.Helper()
+
+	data, err := json.Marshal(game)
+	utils.AssertNoError(t, err)
+
+	var record Snapshot
+	utils.AssertNoError(t, json.Unmarshal(data, &record))
+	return record
+}
+
+// playGameWithSnapsAndUndos plays like playGame, but other players snap whenever they can,
+// and moves are sometimes undone
+func playGameWithSnapsAndUndos(t *testing.T, game *shed, seed int64, maxSteps int) {
+	t.Helper()
+	rng := rand.New(rand.NewSource(seed))
+
+	for step := 0; step < maxSteps && !game.GameOver(); step++ {
+		if game.canUndo() && rng.Intn(10) == 0 {
+			mover := game.history[len(game.history)-1].mover
+			_, err := game.ReceiveResponse([]protocol.InboundMessage{{PlayerID: mover.PlayerID, Command: protocol.Undo}})
+			utils.AssertNoError(t, err)
+			continue
+		}
+
+		if snap, ok := someSnap(game); ok {
+			_, err := game.ReceiveResponse([]protocol.InboundMessage{snap})
+			utils.AssertNoError(t, err)
+			continue
+		}
+
+		playGame(t, game, 1)
+	}
+}
+
+// someSnap returns a snap that another player could make now, if there is one
+func someSnap(game *shed) (protocol.InboundMessage, bool) {
+	for _, p := range game.ActivePlayers {
+		for i := range game.PlayerCards[p.PlayerID].Hand {
+			snap := protocol.InboundMessage{PlayerID: p.PlayerID, Command: protocol.Snap, Decision: []int{i}}
+			if game.checkSnap(snap) == nil {
+				return snap, true
+			}
+		}
+	}
+	return protocol.InboundMessage{}, false
+}
@@ -0,0 +1,297 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/minaorangina/shed/deck"
+	"github.com/minaorangina/shed/protocol"
+)
+
+// ErrCannotSearch is returned when a game cannot be searched for a player's decision
+var ErrCannotSearch = errors.New("cannot search for a decision")
+
+const (
+	defaultSearchSamples = 20
+	// maxPlayoutSteps stops a playout that is going round in circles
+	maxPlayoutSteps = 2000
+)
+
+// SearchOpts configures a search for a player's decision
+type SearchOpts struct {
+	// Samples is how many imagined games each possible decision is played out in. Defaults to 20.
+	Samples int
+	// Seed seeds the search's randomness. The same seed and game always produce the same decision.
+	Seed int64
+}
+
+// Search chooses the decision the game is waiting for from the player,
+// for a Reorg, PlayHand, PlaySeen, PlayHandAndSeen or PlayUnseen.
+//
+// It imagines a number of games that the player cannot tell apart from this one:
+// every card the player cannot see is dealt again, at random, into the places it could be.
+// Each possible decision is made in each imagined game, which is then played to the end,
+// and the decision that leads to the best finishing positions is chosen.
+func Search(g Game, playerID string, opts SearchOpts) ([]int, error) {
+	s, ok := g.(*shed)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T is not a game of Shed", ErrCannotSearch, g)
+	}
+	if opts.Samples <= 0 {
+		opts.Samples = defaultSearchSamples
+	}
+
+	candidates, err := s.candidateDecisions(playerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	scores := make([]float64, len(candidates))
+	for i := 0; i < opts.Samples; i++ {
+		// every decision is tried in the same imagined game, so that they are compared fairly
+		imagined := s.determinise(playerID, rng)
+		for j, decision := range candidates {
+			scores[j] += imagined.Clone().playout(playerID, decision)
+		}
+	}
+
+	best := 0
+	for i, score := range scores {
+		if score > scores[best] {
+			best = i
+		}
+	}
+	return candidates[best], nil
+}
+
+// candidateDecisions returns every decision worth considering for the player
+func (s *shed) candidateDecisions(playerID string) ([][]int, error) {
+	pc, ok := s.PlayerCards[playerID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown player %s", ErrCannotSearch, playerID)
+	}
+
+	cmd := s.ExpectedCommand
+	if cmd != protocol.Reorg && s.CurrentPlayer.PlayerID != playerID {
+		return nil, fmt.Errorf("%w: it is not %s's turn", ErrCannotSearch, playerID)
+	}
+
+	switch cmd {
+	case protocol.Reorg:
+		// every way of choosing three of the six cards to hold
+		candidates := [][]int{}
+		for a := 0; a < 2*numCardsInGroup; a++ {
+			for b := a + 1; b < 2*numCardsInGroup; b++ {
+				for c := b + 1; c < 2*numCardsInGroup; c++ {
+					candidates = append(candidates, []int{a, b, c})
+				}
+			}
+		}
+		return candidates, nil
+
+	case protocol.PlayHand, protocol.PlaySeen:
+		cards := pc.Hand
+		if cmd == protocol.PlaySeen {
+			cards = pc.Seen
+		}
+
+		// for each rank that can be played, play one card, or all of them
+		byRank := map[deck.Rank][]int{}
+		ranks := []deck.Rank{}
+		for _, m := range s.Rules.legalMoves(s.Pile, cards) {
+			r := cards[m].Rank
+			if _, ok := byRank[r]; !ok {
+				ranks = append(ranks, r)
+			}
+			byRank[r] = append(byRank[r], m)
+		}
+
+		candidates := [][]int{}
+		for _, r := range ranks {
+			candidates = append(candidates, byRank[r][:1])
+			if len(byRank[r]) > 1 {
+				candidates = append(candidates, byRank[r])
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("%w: %s has no legal moves", ErrCannotSearch, playerID)
+		}
+		return candidates, nil
+
+	case protocol.PlayHandAndSeen:
+		return [][]int{s.Rules.legalHandAndSeenMoves(s.Pile, pc.Hand, pc.Seen)}, nil
+
+	case protocol.PlayUnseen:
+		// there is nothing to tell unseen cards apart
+		return [][]int{{0}}, nil
+	}
+
+	return nil, fmt.Errorf("%w: the game is waiting for %s", ErrCannotSearch, cmd)
+}
+
+// determinise returns a copy of the game in which every card the player cannot see
+// has been dealt again, at random: the deck, everyone's unseen cards that have not been turned over,
+// and the other players' hands, apart from cards they are known to have picked up from the pile.
+// The copy keeps no history, and is played by the rules without undo.
+func (s *shed) determinise(playerID string, rng *rand.Rand) *shed {
+	d := s.Clone()
+	d.events = nil
+	d.Rules.UndoLimit = 0
+	d.seedRand(rng.Int63(), 0)
+
+	pickedUp := map[int]string{}
+	for _, e := range s.events {
+		if e.Kind == EventPickedUpPile {
+			for _, c := range e.Cards {
+				pickedUp[c.ID] = e.PlayerID
+			}
+		}
+	}
+
+	// hidden holds a pointer to every place a hidden card could be
+	hidden := []*deck.Card{}
+	for i := range d.Deck {
+		hidden = append(hidden, &d.Deck[i])
+	}
+	for _, p := range d.PlayerInfo {
+		id, pc := p.PlayerID, d.PlayerCards[p.PlayerID]
+		if id != playerID {
+			for i, c := range pc.Hand {
+				if pickedUp[c.ID] != id {
+					hidden = append(hidden, &pc.Hand[i])
+				}
+			}
+		}
+		for i, c := range pc.Unseen {
+			if !pc.UnseenVisibility[c.ID] {
+				hidden = append(hidden, &pc.Unseen[i])
+			}
+		}
+	}
+
+	cards := make([]deck.Card, len(hidden))
+	for i, place := range hidden {
+		cards[i] = *place
+	}
+	rng.Shuffle(len(cards), func(i, j int) { cards[i], cards[j] = cards[j], cards[i] })
+	for i, place := range hidden {
+		*place = cards[i]
+	}
+
+	// unseen cards dealt again have not been turned over
+	for _, pc := range d.PlayerCards {
+		visibility := map[int]bool{}
+		for _, c := range pc.Unseen {
+			visibility[c.ID] = s.unseenVisible(c.ID)
+		}
+		pc.UnseenVisibility = visibility
+	}
+
+	return d
+}
+
+// unseenVisible reports whether the card is an unseen card that has been turned over
+func (s *shed) unseenVisible(cardID int) bool {
+	for _, pc := range s.PlayerCards {
+		if pc.UnseenVisibility[cardID] {
+			return true
+		}
+	}
+	return false
+}
+
+// playout makes the player's decision, then plays the game to the end,
+// returning how well the player did: one for finishing first, down to zero for losing
+func (s *shed) playout(playerID string, decision []int) float64 {
+	msgs := []protocol.InboundMessage{{PlayerID: playerID, Command: s.ExpectedCommand, Decision: decision}}
+	if s.ExpectedCommand == protocol.Reorg {
+		msgs = s.playoutResponses()
+		for i := range msgs {
+			if msgs[i].PlayerID == playerID {
+				msgs[i].Decision = decision
+			}
+		}
+	}
+
+	_, err := s.ReceiveResponse(msgs)
+	for step := 0; err == nil && step < maxPlayoutSteps && !s.GameOver(); step++ {
+		if s.AwaitingResponse() == protocol.Null {
+			_, err = s.Next()
+		} else {
+			_, err = s.ReceiveResponse(s.playoutResponses())
+		}
+	}
+
+	return s.score(playerID)
+}
+
+// playoutResponses answers the game as every player does in a playout:
+// keeping the cards they were dealt, and playing every card of the lowest rank they can
+func (s *shed) playoutResponses() []protocol.InboundMessage {
+	cmd := s.ExpectedCommand
+	playerID := s.CurrentPlayer.PlayerID
+	pc := s.PlayerCards[playerID]
+
+	switch cmd {
+	case protocol.Reorg:
+		responses := []protocol.InboundMessage{}
+		for _, p := range s.PlayerInfo {
+			responses = append(responses, protocol.InboundMessage{PlayerID: p.PlayerID, Command: cmd, Decision: []int{0, 1, 2}})
+		}
+		return responses
+
+	case protocol.PlayHand, protocol.PlaySeen:
+		cards := pc.Hand
+		if cmd == protocol.PlaySeen {
+			cards = pc.Seen
+		}
+		moves := s.Rules.legalMoves(s.Pile, cards)
+		lowest := moves[0]
+		for _, m := range moves {
+			if s.Rules.Value(cards[m].Rank) < s.Rules.Value(cards[lowest].Rank) {
+				lowest = m
+			}
+		}
+		decision := []int{}
+		for _, m := range moves {
+			if cards[m].Rank == cards[lowest].Rank {
+				decision = append(decision, m)
+			}
+		}
+		return []protocol.InboundMessage{{PlayerID: playerID, Command: cmd, Decision: decision}}
+
+	case protocol.PlayHandAndSeen:
+		moves := s.Rules.legalHandAndSeenMoves(s.Pile, pc.Hand, pc.Seen)
+		return []protocol.InboundMessage{{PlayerID: playerID, Command: cmd, Decision: moves}}
+
+	case protocol.PlayUnseen:
+		return []protocol.InboundMessage{{PlayerID: playerID, Command: cmd, Decision: []int{0}}}
+	}
+
+	// acknowledgement
+	return []protocol.InboundMessage{{PlayerID: playerID, Command: cmd}}
+}
+
+// score is one for finishing first, down to zero for finishing last.
+// Players yet to finish share the places that are left.
+func (s *shed) score(playerID string) float64 {
+	last := float64(len(s.PlayerInfo) - 1)
+	if last <= 0 {
+		return 0
+	}
+
+	// the loser is the last of the finished players once the game is over,
+	// unless the game was drawn, when the players who drew share the places that are left
+	drawn := s.drawnPlayers()
+	if i := indexOfPlayerID(s.FinishedPlayers, playerID); i >= 0 && indexOfPlayerID(drawn, playerID) < 0 {
+		return 1 - float64(i)/last
+	}
+
+	place := float64(len(s.FinishedPlayers)-len(drawn)) + float64(len(s.ActivePlayers)+len(drawn)-1)/2
+	return 1 - place/last
+}
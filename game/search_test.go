@@ -0,0 +1,174 @@
+package game
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/minaorangina/shed/deck"
+	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
+)
+
+func TestClone(t *testing.T) {
+	t.Run("copies the game exactly", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: threePlayers(), Seed: 4})
+		utils.AssertNoError(t, err)
+		playUntil(t, game, func(s *shed) bool { return len(s.events) >= 40 })
+
+		clone := game.Clone()
+
+		utils.AssertDeepEqual(t, clone.state(), game.state())
+		utils.AssertDeepEqual(t, clone.Events(), game.Events())
+	})
+
+	t.Run("can be played without changing the original", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: threePlayers(), Seed: 4})
+		utils.AssertNoError(t, err)
+		playUntil(t, game, func(s *shed) bool { return len(s.events) >= 40 })
+		before := game.Snapshot()
+
+		clone := game.Clone()
+		playGame(t, clone, 3000)
+		utils.AssertTrue(t, clone.GameOver())
+
+		utils.AssertDeepEqual(t, game.Snapshot(), before)
+	})
+}
+
+func TestDeterminise(t *testing.T) {
+	game, err := NewShed(ShedOpts{Players: threePlayers(), Seed: 6})
+	utils.AssertNoError(t, err)
+	playUntil(t, game, func(s *shed) bool {
+		return len(s.Deck) < 10 && containsEvent(s.events, EventPickedUpPile, "p2")
+	})
+
+	rng := rand.New(rand.NewSource(1))
+	imagined := game.determinise("p1", rng)
+
+	// Then everything p1 can see is unchanged
+	utils.AssertDeepEqual(t, imagined.PlayerCards["p1"].Hand, game.PlayerCards["p1"].Hand)
+	utils.AssertDeepEqual(t, imagined.Pile, game.Pile)
+	utils.AssertDeepEqual(t, imagined.Burned, game.Burned)
+	utils.AssertEqual(t, imagined.CurrentPlayer, game.CurrentPlayer)
+	utils.AssertEqual(t, imagined.ExpectedCommand, game.ExpectedCommand)
+	for id, pc := range game.PlayerCards {
+		utils.AssertDeepEqual(t, imagined.PlayerCards[id].Seen, pc.Seen)
+		utils.AssertEqual(t, len(imagined.PlayerCards[id].Hand), len(pc.Hand))
+		utils.AssertEqual(t, len(imagined.PlayerCards[id].Unseen), len(pc.Unseen))
+	}
+	utils.AssertEqual(t, len(imagined.Deck), len(game.Deck))
+
+	// And cards p2 picked up from the pile stay in their hand
+	for _, c := range game.PlayerCards["p2"].Hand {
+		for _, e := range game.events {
+			if e.Kind == EventPickedUpPile && e.PlayerID == "p2" && containsCard(e.Cards, c) {
+				utils.AssertTrue(t, containsCard(imagined.PlayerCards["p2"].Hand, c))
+			}
+		}
+	}
+
+	// And the cards p1 cannot see are dealt differently, but are the same cards
+	hidden := func(s *shed) []deck.Card {
+		cards := append(copyCards(s.Deck), s.PlayerCards["p2"].Hand...)
+		cards = append(cards, s.PlayerCards["p3"].Hand...)
+		for _, pc := range s.PlayerCards {
+			cards = append(cards, pc.Unseen...)
+		}
+		return cards
+	}
+	utils.AssertNotDeepEqual(t, hidden(imagined), hidden(game))
+	utils.AssertDeepEqual(t, sortedCards(hidden(imagined)), sortedCards(hidden(game)))
+	utils.AssertNoError(t, validateStateMachine(imagined))
+}
+
+func TestSearch(t *testing.T) {
+	t.Run("finds the move that wins", func(t *testing.T) {
+		// Given p1 can finish by playing both of their cards, but p2 can finish after either one
+		ps := twoPlayers()
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearCards,
+			Deck:          deck.Deck{},
+			Pile:          []deck.Card{deck.NewCard(deck.Five, deck.Hearts)},
+			Players:       ps,
+			CurrentPlayer: ps[0],
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards([]deck.Card{
+					deck.NewCard(deck.Six, deck.Clubs),
+					deck.NewCard(deck.Six, deck.Spades),
+				}, nil, nil, nil),
+				"p2": NewPlayerCards([]deck.Card{deck.NewCard(deck.Ace, deck.Hearts)}, nil, nil, nil),
+			},
+		}))
+		utils.AssertNoError(t, err)
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+
+		// When p1 searches for their move
+		decision, err := Search(game, "p1", SearchOpts{Seed: 1})
+
+		// Then they play both cards
+		utils.AssertNoError(t, err)
+		utils.AssertDeepEqual(t, decision, []int{0, 1})
+	})
+
+	t.Run("chooses a legal decision, the same way every time", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: threePlayers(), Seed: 2})
+		utils.AssertNoError(t, err)
+
+		for step := 0; step < 150 && !game.GameOver(); step++ {
+			if game.AwaitingResponse() == protocol.Null {
+				_, err = game.Next()
+				utils.AssertNoError(t, err)
+				continue
+			}
+
+			msgs := firstLegalResponses(game)
+			if isDecision(game.ExpectedCommand) {
+				playerID := msgs[0].PlayerID
+				decision, err := Search(game, playerID, SearchOpts{Samples: 2, Seed: int64(step)})
+				utils.AssertNoError(t, err)
+
+				again, err := Search(game, playerID, SearchOpts{Samples: 2, Seed: int64(step)})
+				utils.AssertNoError(t, err)
+				utils.AssertDeepEqual(t, again, decision)
+
+				msgs[0].Decision = decision
+			}
+
+			// the game accepts the decision
+			_, err = game.ReceiveResponse(msgs)
+			utils.AssertNoError(t, err)
+		}
+	})
+
+	t.Run("rejects a search when the player has nothing to decide", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers(), Seed: 1})
+		utils.AssertNoError(t, err)
+
+		// the game has not started
+		_, err = Search(game, "p1", SearchOpts{})
+		utils.AssertTrue(t, errors.Is(err, ErrCannotSearch))
+
+		// the player is not in the game
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+		_, err = Search(game, "p9", SearchOpts{})
+		utils.AssertTrue(t, errors.Is(err, ErrCannotSearch))
+	})
+}
+
+func isDecision(cmd protocol.Cmd) bool {
+	switch cmd {
+	case protocol.Reorg, protocol.PlayHand, protocol.PlaySeen, protocol.PlayHandAndSeen, protocol.PlayUnseen:
+		return true
+	}
+	return false
+}
+
+func sortedCards(cards []deck.Card) []deck.Card {
+	sorted := copyCards(cards)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
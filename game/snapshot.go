@@ -0,0 +1,313 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/minaorangina/shed/deck"
+	"github.com/minaorangina/shed/protocol"
+)
+
+// SnapshotVersion is the version of the Snapshot format produced by this package.
+// Version 1 snapshots hold neither the rules, the burned cards, the undo history nor the event log,
+// so a game cannot be restored or replayed from them.
+const SnapshotVersion = 2
+
+// ErrSnapshotVersion is returned for a Snapshot in a format this package cannot read
+var ErrSnapshotVersion = errors.New("unsupported snapshot version")
+
+// Snapshot is a complete, serialisable record of the state of a game of Shed,
+// from which the game can be restored exactly.
+type Snapshot struct {
+	Version           int                            `json:"version"`
+	Seed              int64                          `json:"seed"`
+	Rules             Rules                          `json:"rules"`
+	Jokers            int                            `json:"jokers"`
+	Creator           string                         `json:"creator,omitempty"`
+	PreviousLoser     string                         `json:"previousLoser,omitempty"`
+	RandDraws         uint64                         `json:"randDraws"`
+	Deck              deck.Deck                      `json:"deck"`
+	Pile              []deck.Card                    `json:"pile"`
+	Burned            []deck.Card                    `json:"burned"`
+	LastBurnedCount   int                            `json:"lastBurnedCount"`
+	PlayerCards       map[string]PlayerCardsSnapshot `json:"playerCards"`
+	PlayerInfo        []protocol.Player              `json:"playerInfo"`
+	ActivePlayers     []protocol.Player              `json:"activePlayers"`
+	FinishedPlayers   []protocol.Player              `json:"finishedPlayers"`
+	CurrentTurnIdx    int                            `json:"currentTurnIdx"`
+	CurrentPlayer     protocol.Player                `json:"currentPlayer"`
+	Direction         Direction                      `json:"direction"`
+	SkipCount         int                            `json:"skipCount"`
+	PlayerRepeatsTurn bool                           `json:"playerRepeatsTurn"`
+	Stage             Stage                          `json:"stage"`
+	GamePlay          GamePlayState                  `json:"gamePlay"`
+	ExpectedCommand   protocol.Cmd                   `json:"expectedCommand"`
+	GameOver          bool                           `json:"gameOver"`
+	UnseenDecision    *protocol.InboundMessage       `json:"unseenDecision,omitempty"`
+	FirstTurnMessage  string                         `json:"firstTurnMessage,omitempty"`
+	Turns             int                            `json:"turns,omitempty"`
+	TurnCounted       bool                           `json:"turnCounted,omitempty"`
+	Positions         map[uint64]int                 `json:"positions,omitempty"`
+	EndReason         protocol.GameOverReason        `json:"endReason,omitempty"`
+	History           []UndoEntrySnapshot            `json:"history,omitempty"`
+	Events            []Event                        `json:"events,omitempty"`
+}
+
+// UndoEntrySnapshot is the serialisable form of a move that can be undone.
+// Game is the state before the move, without its own history or events.
+type UndoEntrySnapshot struct {
+	Mover protocol.Player `json:"mover"`
+	Game  Snapshot        `json:"game"`
+	Turn  int             `json:"turn"`
+	Solo  bool            `json:"solo,omitempty"`
+}
+
+// PlayerCardsSnapshot is the serialisable form of PlayerCards.
+// UnseenVisibility runs parallel to Unseen.
+type PlayerCardsSnapshot struct {
+	Hand             []deck.Card `json:"hand"`
+	Seen             []deck.Card `json:"seen"`
+	Unseen           []deck.Card `json:"unseen"`
+	UnseenVisibility []bool      `json:"unseenVisibility"`
+}
+
+// Snapshot captures the full state of the game, including its undo history and event log
+func (s *shed) Snapshot() Snapshot {
+	snap := s.state()
+	for _, entry := range s.history {
+		snap.History = append(snap.History, UndoEntrySnapshot{
+			Mover: entry.mover,
+			Game:  entry.snapshot,
+			Turn:  entry.turn,
+			Solo:  entry.solo,
+		})
+	}
+	snap.Events = copyEvents(s.events)
+	return snap
+}
+
+// state captures the state of the game without its undo history, which holds earlier states,
+// or its event log, which only ever grows
+func (s *shed) state() Snapshot {
+	snap := Snapshot{
+		Version:           SnapshotVersion,
+		Seed:              s.Seed,
+		Rules:             s.Rules,
+		Jokers:            s.Jokers,
+		Creator:           s.Creator,
+		PreviousLoser:     s.PreviousLoser,
+		Deck:              copyCards(s.Deck),
+		Pile:              copyCards(s.Pile),
+		Burned:            copyCards(s.Burned),
+		LastBurnedCount:   s.lastBurnedCount,
+		PlayerCards:       map[string]PlayerCardsSnapshot{},
+		PlayerInfo:        copyPlayers(s.PlayerInfo),
+		ActivePlayers:     copyPlayers(s.ActivePlayers),
+		FinishedPlayers:   copyPlayers(s.FinishedPlayers),
+		CurrentTurnIdx:    s.CurrentTurnIdx,
+		CurrentPlayer:     s.CurrentPlayer,
+		Direction:         s.Direction,
+		SkipCount:         s.SkipCount,
+		PlayerRepeatsTurn: s.playerRepeatsTurn,
+		Stage:             s.Stage,
+		GamePlay:          s.gamePlay,
+		ExpectedCommand:   s.ExpectedCommand,
+		GameOver:          s.gameOver,
+		FirstTurnMessage:  s.firstTurnMsg,
+		Turns:             s.turns,
+		TurnCounted:       s.turnCounted,
+		Positions:         copyPositions(s.positions),
+		EndReason:         s.endReason,
+	}
+
+	if s.src != nil {
+		snap.RandDraws = s.src.draws
+	}
+
+	for id, pc := range s.PlayerCards {
+		visibility := []bool{}
+		for _, c := range pc.Unseen {
+			visibility = append(visibility, pc.UnseenVisibility[c.ID])
+		}
+
+		snap.PlayerCards[id] = PlayerCardsSnapshot{
+			Hand:             copyCards(pc.Hand),
+			Seen:             copyCards(pc.Seen),
+			Unseen:           copyCards(pc.Unseen),
+			UnseenVisibility: visibility,
+		}
+	}
+
+	if s.unseenDecision != nil {
+		decision := *s.unseenDecision
+		decision.Decision = append([]int{}, s.unseenDecision.Decision...)
+		snap.UnseenDecision = &decision
+	}
+
+	return snap
+}
+
+// Clone returns a copy of the game that can be played on without changing the original.
+// It is cheaper than taking a Snapshot and restoring it, and does not copy the undo history.
+func (s *shed) Clone() *shed {
+	c := *s
+	c.Deck = copyCards(s.Deck)
+	c.Pile = copyCards(s.Pile)
+	c.Burned = copyCards(s.Burned)
+	c.PlayerInfo = copyPlayers(s.PlayerInfo)
+	c.ActivePlayers = copyPlayers(s.ActivePlayers)
+	c.FinishedPlayers = copyPlayers(s.FinishedPlayers)
+	c.history = nil
+	c.positions = copyPositions(s.positions)
+	// events are never changed once they have happened, so their cards can be shared
+	c.events = append([]Event(nil), s.events...)
+
+	c.PlayerCards = map[string]*PlayerCards{}
+	for id, pc := range s.PlayerCards {
+		visibility := map[int]bool{}
+		for cardID, visible := range pc.UnseenVisibility {
+			visibility[cardID] = visible
+		}
+		c.PlayerCards[id] = NewPlayerCards(copyCards(pc.Hand), copyCards(pc.Seen), copyCards(pc.Unseen), visibility)
+	}
+
+	if s.unseenDecision != nil {
+		decision := *s.unseenDecision
+		decision.Decision = append([]int{}, s.unseenDecision.Decision...)
+		c.unseenDecision = &decision
+	}
+
+	if s.src != nil {
+		c.seedRand(s.Seed, s.src.draws)
+	}
+
+	return &c
+}
+
+// SnapshotOf returns the Snapshot of the game, or false if it is not a game of Shed
+func SnapshotOf(g Game) (Snapshot, bool) {
+	if s, ok := g.(*shed); ok {
+		return s.Snapshot(), true
+	}
+	return Snapshot{}, false
+}
+
+// CloneOf returns a copy of the game that can be read or played on without changing the original,
+// or nil if it is not a game of Shed
+func CloneOf(g Game) Game {
+	if s, ok := g.(*shed); ok {
+		return s.Clone()
+	}
+	return nil
+}
+
+// MarshalJSON serialises the game as a Snapshot
+func (s *shed) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Snapshot())
+}
+
+// checkVersion returns an error matching ErrSnapshotVersion unless a Snapshot
+// of the given version can be read by this package
+func checkVersion(version int) error {
+	switch {
+	case version == SnapshotVersion:
+		return nil
+	case version > 0 && version < SnapshotVersion:
+		return fmt.Errorf("%w %d: it was saved before games kept their rules and history, and cannot be read", ErrSnapshotVersion, version)
+	}
+	return fmt.Errorf("%w %d (want %d)", ErrSnapshotVersion, version, SnapshotVersion)
+}
+
+// Restore reconstructs a game of Shed from a Snapshot.
+// It returns a *StateError if the snapshot does not describe a valid game.
+func Restore(snap Snapshot) (*shed, error) {
+	if err := checkVersion(snap.Version); err != nil {
+		return nil, err
+	}
+	if err := snap.Rules.validateFor(snap.Jokers); err != nil {
+		return nil, err
+	}
+
+	s := &shed{
+		Deck:              copyCards(snap.Deck),
+		Pile:              copyCards(snap.Pile),
+		Burned:            copyCards(snap.Burned),
+		lastBurnedCount:   snap.LastBurnedCount,
+		PlayerCards:       map[string]*PlayerCards{},
+		PlayerInfo:        copyPlayers(snap.PlayerInfo),
+		ActivePlayers:     copyPlayers(snap.ActivePlayers),
+		FinishedPlayers:   copyPlayers(snap.FinishedPlayers),
+		CurrentTurnIdx:    snap.CurrentTurnIdx,
+		CurrentPlayer:     snap.CurrentPlayer,
+		Direction:         snap.Direction,
+		SkipCount:         snap.SkipCount,
+		playerRepeatsTurn: snap.PlayerRepeatsTurn,
+		Stage:             snap.Stage,
+		gamePlay:          snap.GamePlay,
+		ExpectedCommand:   snap.ExpectedCommand,
+		gameOver:          snap.GameOver,
+		Rules:             snap.Rules,
+		Jokers:            snap.Jokers,
+		Creator:           snap.Creator,
+		PreviousLoser:     snap.PreviousLoser,
+		firstTurnMsg:      snap.FirstTurnMessage,
+		turns:             snap.Turns,
+		turnCounted:       snap.TurnCounted,
+		positions:         copyPositions(snap.Positions),
+		endReason:         snap.EndReason,
+		events:            copyEvents(snap.Events),
+	}
+	s.seedRand(snap.Seed, snap.RandDraws)
+
+	for id, pcs := range snap.PlayerCards {
+		if len(pcs.UnseenVisibility) != len(pcs.Unseen) {
+			return nil, fmt.Errorf("player %s has %d unseen cards but %d visibility entries",
+				id, len(pcs.Unseen), len(pcs.UnseenVisibility))
+		}
+
+		visibility := map[int]bool{}
+		for i, c := range pcs.Unseen {
+			visibility[c.ID] = pcs.UnseenVisibility[i]
+		}
+
+		s.PlayerCards[id] = NewPlayerCards(
+			copyCards(pcs.Hand),
+			copyCards(pcs.Seen),
+			copyCards(pcs.Unseen),
+			visibility,
+		)
+	}
+
+	if snap.UnseenDecision != nil {
+		decision := *snap.UnseenDecision
+		decision.Decision = append([]int{}, snap.UnseenDecision.Decision...)
+		s.unseenDecision = &decision
+	}
+
+	for _, entry := range snap.History {
+		s.history = append(s.history, undoEntry{
+			mover:    entry.Mover,
+			snapshot: entry.Game,
+			turn:     entry.Turn,
+			solo:     entry.Solo,
+		})
+	}
+
+	if err := validateStateMachine(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func copyPositions(positions map[uint64]int) map[uint64]int {
+	if positions == nil {
+		return nil
+	}
+	copied := make(map[uint64]int, len(positions))
+	for position, n := range positions {
+		copied[position] = n
+	}
+	return copied
+}
@@ -0,0 +1,141 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Run("captures unexported state", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers(), Seed: 7})
+		utils.AssertNoError(t, err)
+
+		playUntil(t, game, func(s *shed) bool { return s.unseenDecision != nil })
+
+		snap := game.Snapshot()
+		utils.AssertEqual(t, snap.Version, SnapshotVersion)
+		utils.AssertEqual(t, snap.Seed, int64(7))
+		utils.AssertEqual(t, snap.GamePlay, gameInProgress)
+		utils.AssertEqual(t, snap.ExpectedCommand, game.ExpectedCommand)
+		utils.AssertDeepEqual(t, *snap.UnseenDecision, *game.unseenDecision)
+		utils.AssertTrue(t, snap.RandDraws > 0)
+	})
+
+	t.Run("marshals the complete game", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: threePlayers(), Seed: 7})
+		utils.AssertNoError(t, err)
+		playUntil(t, game, func(s *shed) bool { return s.Stage == clearDeck })
+
+		data, err := json.Marshal(game)
+		utils.AssertNoError(t, err)
+
+		var snap Snapshot
+		utils.AssertNoError(t, json.Unmarshal(data, &snap))
+		utils.AssertDeepEqual(t, snap, game.Snapshot())
+	})
+}
+
+func TestRestore(t *testing.T) {
+	t.Run("round-trips mid-turn", func(t *testing.T) {
+		stops := map[string]func(s *shed) bool{
+			"awaiting reorg":          func(s *shed) bool { return s.ExpectedCommand == protocol.Reorg },
+			"awaiting replenish":      func(s *shed) bool { return s.ExpectedCommand == protocol.ReplenishHand },
+			"awaiting burn ack":       func(s *shed) bool { return s.ExpectedCommand == protocol.Burn },
+			"pending unseen decision": func(s *shed) bool { return s.unseenDecision != nil },
+			"after a burn":            func(s *shed) bool { return s.lastBurnedCount > 0 && s.ExpectedCommand == protocol.Null },
+		}
+
+		for name, stop := range stops {
+			t.Run(name, func(t *testing.T) {
+				game, err := NewShed(ShedOpts{Players: threePlayers(), Seed: 11})
+				utils.AssertNoError(t, err)
+				playUntil(t, game, stop)
+
+				data, err := json.Marshal(game)
+				utils.AssertNoError(t, err)
+
+				var snap Snapshot
+				utils.AssertNoError(t, json.Unmarshal(data, &snap))
+
+				restored, err := Restore(snap)
+				utils.AssertNoError(t, err)
+				utils.AssertDeepEqual(t, restored.Snapshot(), game.Snapshot())
+
+				// And the restored game plays out identically
+				utils.AssertDeepEqual(t, playGame(t, restored, 2000), playGame(t, game, 2000))
+			})
+		}
+	})
+
+	t.Run("keeps the undo history", func(t *testing.T) {
+		rules := DefaultRules()
+		rules.UndoLimit = 3
+		game, err := NewShed(ShedOpts{Players: threePlayers(), Seed: 11, Rules: rules})
+		utils.AssertNoError(t, err)
+		playUntil(t, game, func(s *shed) bool { return len(s.history) > 1 })
+
+		data, err := json.Marshal(game)
+		utils.AssertNoError(t, err)
+
+		var snap Snapshot
+		utils.AssertNoError(t, json.Unmarshal(data, &snap))
+
+		restored, err := Restore(snap)
+		utils.AssertNoError(t, err)
+		utils.AssertDeepEqual(t, restored.history, game.history)
+
+		// And the restored game undoes the last move the same way
+		votes := []protocol.InboundMessage{}
+		for _, p := range game.PlayerInfo {
+			votes = append(votes, protocol.InboundMessage{PlayerID: p.PlayerID, Command: protocol.Undo})
+		}
+		_, err = restored.ReceiveResponse(votes)
+		utils.AssertNoError(t, err)
+		_, err = game.ReceiveResponse(votes)
+		utils.AssertNoError(t, err)
+		utils.AssertDeepEqual(t, restored.state(), game.state())
+
+		// Without counting the turn that starts again twice
+		utils.AssertTrue(t, restored.turnCounted)
+		utils.AssertDeepEqual(t, playGame(t, restored, 2000), playGame(t, game, 2000))
+	})
+
+	t.Run("rejects unknown versions", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers()})
+		utils.AssertNoError(t, err)
+
+		snap := game.Snapshot()
+		snap.Version = SnapshotVersion + 1
+
+		_, err = Restore(snap)
+		utils.AssertTrue(t, errors.Is(err, ErrSnapshotVersion))
+	})
+
+	t.Run("rejects snapshots saved before games kept their history", func(t *testing.T) {
+		// a version 1 snapshot has no rules, burned cards, undo history or events to restore
+		data := []byte(`{"version": 1, "seed": 5, "deck": [], "pile": [], "playerCards": {}, "playerInfo": []}`)
+
+		var snap Snapshot
+		utils.AssertNoError(t, json.Unmarshal(data, &snap))
+
+		_, err := Restore(snap)
+		utils.AssertTrue(t, errors.Is(err, ErrSnapshotVersion))
+		utils.AssertContains(t, err.Error(), "version 1")
+	})
+}
+
+// playUntil plays the first legal move at each turn until stop returns true
+func playUntil(t *testing.T, game *shed, stop func(s *shed) bool) {
+	t.Helper()
+
+	for step := 0; !stop(game); step++ {
+		if step > 2000 || game.GameOver() {
+			t.Fatal("game state never reached")
+		}
+		playGame(t, game, 1)
+	}
+}
@@ -0,0 +1,140 @@
+package game
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/minaorangina/shed/protocol"
+)
+
+// StalemateResult decides how a game that is going nowhere ends.
+// Players who have already finished keep their places.
+type StalemateResult int
+
+const (
+	// DrawStalemate ends the game in a draw between the players still holding cards
+	DrawStalemate StalemateResult = iota
+	// FewestCardsStalemate places the players still holding cards by how many they hold, fewest first,
+	// with ties broken by seating order. The player holding the most cards loses.
+	FewestCardsStalemate
+)
+
+var stalemateResultNames = map[StalemateResult]string{
+	DrawStalemate:        "draw",
+	FewestCardsStalemate: "fewestCards",
+}
+
+func (r StalemateResult) String() string {
+	if name, ok := stalemateResultNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("StalemateResult(%d)", int(r))
+}
+
+// MarshalText encodes the result by name
+func (r StalemateResult) MarshalText() ([]byte, error) {
+	if _, ok := stalemateResultNames[r]; !ok {
+		return nil, fmt.Errorf("%w: unknown stalemate result %d", ErrInvalidRules, r)
+	}
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText decodes a result from its name
+func (r *StalemateResult) UnmarshalText(text []byte) error {
+	for result, name := range stalemateResultNames {
+		if name == string(text) {
+			*r = result
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown stalemate result %q", ErrInvalidRules, text)
+}
+
+// checkProgress records the start of a turn, and reports why the game should end
+// if it has gone on for too many turns, or the same position has come round too often
+func (s *shed) checkProgress() (protocol.GameOverReason, bool) {
+	position := s.position()
+	if s.turnCounted {
+		s.turnCounted = false
+	} else {
+		if s.positions == nil {
+			s.positions = map[uint64]int{}
+		}
+		s.turns++
+		s.positions[position]++
+	}
+
+	if s.Rules.MoveLimit > 0 && s.turns > s.Rules.MoveLimit {
+		return protocol.MoveLimitReached, true
+	}
+	if s.Rules.RepeatLimit > 0 && s.positions[position] >= s.Rules.RepeatLimit {
+		return protocol.RepeatedPosition, true
+	}
+	return "", false
+}
+
+// position hashes everything that decides how the game can go on from the start of a turn:
+// who is to play, and where every card is. The order of cards in hand does not matter.
+func (s *shed) position() uint64 {
+	h := fnv.New64a()
+
+	fmt.Fprint(h, s.Stage, s.CurrentPlayer.PlayerID, s.Direction, s.SkipCount, len(s.Burned), len(s.FinishedPlayers))
+	fmt.Fprint(h, cardIDs(s.Deck), cardIDs(s.Pile))
+	for _, p := range s.PlayerInfo {
+		pc := s.PlayerCards[p.PlayerID]
+		if pc == nil {
+			continue
+		}
+		hand := cardIDs(pc.Hand)
+		sort.Ints(hand)
+		visible := []bool{}
+		for _, c := range pc.Unseen {
+			visible = append(visible, pc.UnseenVisibility[c.ID])
+		}
+		fmt.Fprint(h, p.PlayerID, hand, cardIDs(pc.Seen), cardIDs(pc.Unseen), visible)
+	}
+
+	return h.Sum64()
+}
+
+// endStalemate ends a game that is going nowhere, with the result the rules call for
+func (s *shed) endStalemate(reason protocol.GameOverReason) []protocol.OutboundMessage {
+	remaining := copyPlayers(s.ActivePlayers)
+	loserID := ""
+
+	if s.Rules.Stalemate == FewestCardsStalemate {
+		sort.SliceStable(remaining, func(i, j int) bool {
+			return numCards(s.PlayerCards[remaining[i].PlayerID]) < numCards(s.PlayerCards[remaining[j].PlayerID])
+		})
+		for _, p := range remaining[:len(remaining)-1] {
+			s.emit(EventRanked, p.PlayerID, "", nil)
+		}
+		loserID = remaining[len(remaining)-1].PlayerID
+	}
+
+	s.FinishedPlayers = append(s.FinishedPlayers, remaining...)
+	s.ActivePlayers = []protocol.Player{}
+	s.SkipCount = 0
+	s.gamePlay = gameOver
+	s.endReason = reason
+	s.ExpectedCommand = protocol.Null
+	s.emit(EventGameOver, loserID, "", nil)
+
+	return s.buildGameOverMessages()
+}
+
+// drawnPlayers returns the players who drew, if the game ended in a draw
+func (s *shed) drawnPlayers() []protocol.Player {
+	if s.endReason == "" || s.Rules.Stalemate != DrawStalemate {
+		return nil
+	}
+
+	drawn := []protocol.Player{}
+	for _, p := range s.FinishedPlayers {
+		if numCards(s.PlayerCards[p.PlayerID]) > 0 {
+			drawn = append(drawn, p)
+		}
+	}
+	return drawn
+}
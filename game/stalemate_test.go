@@ -0,0 +1,169 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/minaorangina/shed/deck"
+	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
+)
+
+func TestStalemate(t *testing.T) {
+	// endlessSeed deals two players a game that goes round in circles
+	// when each plays the first card they can
+	const endlessSeed = 106
+
+	// repeatRules end a game once the same position has started a turn three times
+	repeatRules := func() Rules {
+		rules := DefaultRules()
+		rules.RepeatLimit = 3
+		return rules
+	}
+
+	endlessGame := func(rules Rules) *shed {
+		game, err := NewShed(ShedOpts{Players: twoPlayers(), Seed: endlessSeed, Rules: rules})
+		utils.AssertNoError(t, err)
+		return game
+	}
+
+	t.Run("a game going round in circles never ends without a move or repeat limit", func(t *testing.T) {
+		rules := DefaultRules()
+		rules.MoveLimit = NoMoveLimit
+		game := endlessGame(rules)
+
+		playGame(t, game, 5000)
+
+		utils.AssertTrue(t, !game.GameOver())
+	})
+
+	t.Run("a game going round in circles ends by default", func(t *testing.T) {
+		game, err := NewShed(ShedOpts{Players: twoPlayers(), Seed: endlessSeed})
+		utils.AssertNoError(t, err)
+
+		playGame(t, game, 20000)
+
+		utils.AssertTrue(t, game.GameOver())
+		utils.AssertEqual(t, game.endReason, protocol.MoveLimitReached)
+		utils.AssertEqual(t, game.turns, DefaultRules().MoveLimit+1)
+	})
+
+	t.Run("a game going round in circles is drawn", func(t *testing.T) {
+		// Given a game that goes round in circles
+		game := endlessGame(repeatRules())
+
+		// When it is played
+		msgs := playGame(t, game, 5000)
+
+		// Then it ends once a position has come round three times
+		utils.AssertTrue(t, game.GameOver())
+		utils.AssertEqual(t, game.endReason, protocol.RepeatedPosition)
+
+		// And everyone is told that both players drew, and why
+		gameOver := msgs[len(msgs)-len(game.PlayerInfo):]
+		for _, m := range gameOver {
+			utils.AssertEqual(t, m.Command, protocol.GameOver)
+			utils.AssertEqual(t, m.EndReason, protocol.RepeatedPosition)
+			utils.AssertDeepEqual(t, m.Drawn, game.PlayerInfo)
+			utils.AssertContains(t, m.Message, "You drew")
+		}
+
+		// And the event log ends without a loser
+		events := game.Events()
+		utils.AssertEqual(t, events[len(events)-1].Kind, EventGameOver)
+		utils.AssertEqual(t, events[len(events)-1].PlayerID, "")
+	})
+
+	t.Run("fewest cards wins a game going round in circles", func(t *testing.T) {
+		rules := repeatRules()
+		rules.Stalemate = FewestCardsStalemate
+		game := endlessGame(rules)
+
+		msgs := playGame(t, game, 5000)
+
+		// Then the player with more cards loses
+		utils.AssertTrue(t, game.GameOver())
+		winner, loser := game.FinishedPlayers[0], game.FinishedPlayers[1]
+		utils.AssertTrue(t, numCards(game.PlayerCards[winner.PlayerID]) <= numCards(game.PlayerCards[loser.PlayerID]))
+
+		events := game.Events()
+		utils.AssertEqual(t, events[len(events)-2].Kind, EventRanked)
+		utils.AssertEqual(t, events[len(events)-2].PlayerID, winner.PlayerID)
+		utils.AssertEqual(t, events[len(events)-1].Kind, EventGameOver)
+		utils.AssertEqual(t, events[len(events)-1].PlayerID, loser.PlayerID)
+
+		last := msgs[len(msgs)-1]
+		utils.AssertEqual(t, last.EndReason, protocol.RepeatedPosition)
+		utils.AssertEqual(t, len(last.Drawn), 0)
+	})
+
+	t.Run("a game ends when it reaches the move limit", func(t *testing.T) {
+		rules := DefaultRules()
+		rules.MoveLimit = 10
+		game, err := NewShed(ShedOpts{Players: threePlayers(), Seed: 1, Rules: rules})
+		utils.AssertNoError(t, err)
+
+		msgs := playGame(t, game, 5000)
+
+		utils.AssertTrue(t, game.GameOver())
+		utils.AssertEqual(t, game.turns, 11)
+		utils.AssertEqual(t, msgs[len(msgs)-1].EndReason, protocol.MoveLimitReached)
+		utils.AssertEqual(t, len(msgs[len(msgs)-1].Drawn), 3)
+	})
+
+	t.Run("a move that is taken back does not start another turn", func(t *testing.T) {
+		rules := repeatRules()
+		rules.UndoLimit = 1
+		ps := twoPlayers()
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearDeck,
+			Deck:          deck.Deck{deck.NewCard(deck.Eight, deck.Clubs)},
+			Players:       ps,
+			CurrentPlayer: ps[0],
+			Rules:         rules,
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards([]deck.Card{deck.NewCard(deck.Four, deck.Clubs)}, nil, nil, nil),
+				"p2": NewPlayerCards([]deck.Card{deck.NewCard(deck.Four, deck.Diamonds)}, nil, nil, nil),
+			},
+		}))
+		utils.AssertNoError(t, err)
+
+		// When p1 moves, takes it back and is asked to move again, three times over
+		for i := 0; i < 3; i++ {
+			_, err := game.Next()
+			utils.AssertNoError(t, err)
+			_, err = game.ReceiveResponse([]protocol.InboundMessage{{PlayerID: "p1", Command: protocol.PlayHand, Decision: []int{0}}})
+			utils.AssertNoError(t, err)
+			_, err = game.ReceiveResponse([]protocol.InboundMessage{{PlayerID: "p1", Command: protocol.Undo}})
+			utils.AssertNoError(t, err)
+		}
+
+		// Then it is still the first turn
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+		utils.AssertTrue(t, !game.GameOver())
+		utils.AssertEqual(t, game.turns, 1)
+	})
+
+	t.Run("is restored along with the game", func(t *testing.T) {
+		game := endlessGame(DefaultRules())
+		playGame(t, game, 200)
+
+		restored, err := Restore(game.Snapshot())
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, restored.turns, game.turns)
+		utils.AssertDeepEqual(t, restored.positions, game.positions)
+	})
+
+	t.Run("results are named in JSON", func(t *testing.T) {
+		data, err := json.Marshal(FewestCardsStalemate)
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, string(data), `"fewestCards"`)
+
+		var result StalemateResult
+		err = json.Unmarshal([]byte(`"sudden death"`), &result)
+		utils.AssertTrue(t, errors.Is(err, ErrInvalidRules))
+	})
+}
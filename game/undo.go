@@ -0,0 +1,131 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/minaorangina/shed/protocol"
+)
+
+// ErrCannotUndo is returned when an Undo cannot be carried out
+var ErrCannotUndo = errors.New("cannot undo")
+
+// undoEntry is the game as it was just before a player's move.
+type undoEntry struct {
+	mover    protocol.Player
+	snapshot Snapshot
+	turn     int  // the turn the move was made in
+	solo     bool // the mover can still take the move back without a vote
+}
+
+// isMove reports whether the messages are a player acting on their cards or the pile,
+// rather than acknowledging something that has happened.
+// Snaps that arrive together are resolved as one move.
+func (s *shed) isMove(msgs []protocol.InboundMessage) bool {
+	if hasSnap(msgs) {
+		return true
+	}
+	if len(msgs) != 1 {
+		return false
+	}
+
+	switch msgs[0].Command {
+	case protocol.PlayHand, protocol.PlaySeen, protocol.PlayHandAndSeen, protocol.PlayUnseen:
+		return true
+	case protocol.PickUpPile:
+		return s.ExpectedCommand != protocol.PickUpPile
+	}
+	return false
+}
+
+// isUndoable reports whether the move can be taken back.
+// Unseen cards cannot be taken back once they have been turned over,
+// and snaps are made out of turn, so they cannot be taken back either.
+func (s *shed) isUndoable(msgs []protocol.InboundMessage) bool {
+	return !hasSnap(msgs) && msgs[0].Command != protocol.PlayUnseen
+}
+
+func hasSnap(msgs []protocol.InboundMessage) bool {
+	for _, m := range msgs {
+		if m.Command == protocol.Snap {
+			return true
+		}
+	}
+	return false
+}
+
+// recordMove keeps the game as it was before an undoable move, up to the undo limit.
+// Any other move means that everything before it can no longer be undone.
+func (s *shed) recordMove(before Snapshot, undoable bool) {
+	if !undoable {
+		s.history = nil
+		return
+	}
+
+	s.history = append(s.history, undoEntry{mover: before.CurrentPlayer, snapshot: before, turn: s.turns, solo: true})
+	if len(s.history) > s.Rules.UndoLimit {
+		s.history = s.history[len(s.history)-s.Rules.UndoLimit:]
+	}
+}
+
+// endSoloUndo stops the mover of the last move from taking it back alone.
+// This happens once the next player has acted, even if they were made to pick up the pile.
+func (s *shed) endSoloUndo() {
+	if len(s.history) > 0 {
+		s.history[len(s.history)-1].solo = false
+	}
+}
+
+// startTurnForUndo is called as each turn starts. The mover can take back the last move alone
+// while the next player decides what to do, but not once a later turn has started.
+func (s *shed) startTurnForUndo() {
+	if len(s.history) > 0 && s.turns > s.history[len(s.history)-1].turn+1 {
+		s.endSoloUndo()
+	}
+}
+
+func (s *shed) canUndo() bool {
+	return len(s.history) > 0 && !s.GameOver()
+}
+
+// undo takes back the last move. A player can take back their own move
+// until the next player acts. Anyone else's move needs every player's vote,
+// one message each.
+func (s *shed) undo(msgs []protocol.InboundMessage) ([]protocol.OutboundMessage, error) {
+	requester := msgs[len(msgs)-1].PlayerID
+	if !s.canUndo() {
+		err := fmt.Errorf("%w: there are no moves to undo", ErrCannotUndo)
+		return []protocol.OutboundMessage{s.buildErrorMessage(requester, err)}, err
+	}
+
+	votes := map[string]bool{}
+	for _, m := range msgs {
+		if m.Command != protocol.Undo || indexOfPlayerID(s.PlayerInfo, m.PlayerID) < 0 {
+			err := fmt.Errorf("%w: unexpected %s from player %s", ErrCannotUndo, m.Command, m.PlayerID)
+			return []protocol.OutboundMessage{s.buildErrorMessage(m.PlayerID, err)}, err
+		}
+		votes[m.PlayerID] = true
+	}
+
+	last := s.history[len(s.history)-1]
+	if !(last.solo && votes[last.mover.PlayerID]) && len(votes) < len(s.PlayerInfo) {
+		return s.buildUndoRequestedMessages(s.PlayerInfo[indexOfPlayerID(s.PlayerInfo, requester)],
+			last.mover, len(votes)), nil
+	}
+
+	restored, err := Restore(last.snapshot)
+	if err != nil {
+		return nil, err
+	}
+	// the event log is kept, so that it records the move and its undoing
+	history, events := s.history[:len(s.history)-1], s.events
+	*s = *restored
+	s.history, s.events = history, events
+	s.emit(EventUndone, last.mover.PlayerID, "", nil)
+
+	// The player who moved is asked to move again
+	s.ExpectedCommand = protocol.Null
+	s.turnCounted = true
+
+	return s.buildUndoMessages(last.mover), nil
+}
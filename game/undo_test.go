@@ -0,0 +1,210 @@
+package game
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/minaorangina/shed/deck"
+	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
+)
+
+func TestUndo(t *testing.T) {
+	gameWithUndo := func(limit int) *shed {
+		rules := DefaultRules()
+		rules.UndoLimit = limit
+
+		ps := twoPlayers()
+		game, err := ExistingShed(burnTheRest(t, ShedOpts{
+			Stage:         clearDeck,
+			Deck:          deck.Deck{deck.NewCard(deck.Eight, deck.Clubs), deck.NewCard(deck.Eight, deck.Diamonds)},
+			Players:       ps,
+			CurrentPlayer: ps[0],
+			Rules:         rules,
+			PlayerCards: map[string]*PlayerCards{
+				"p1": NewPlayerCards([]deck.Card{
+					deck.NewCard(deck.Four, deck.Clubs),
+					deck.NewCard(deck.Five, deck.Clubs),
+					deck.NewCard(deck.Six, deck.Clubs),
+				}, nil, nil, nil),
+				"p2": NewPlayerCards([]deck.Card{
+					deck.NewCard(deck.Four, deck.Diamonds),
+					deck.NewCard(deck.Five, deck.Diamonds),
+					deck.NewCard(deck.Six, deck.Diamonds),
+				}, nil, nil, nil),
+			},
+		}))
+		utils.AssertNoError(t, err)
+		return game
+	}
+
+	// move plays the current player's first hand card, and acknowledges it
+	move := func(t *testing.T, game *shed) {
+		t.Helper()
+		_, err := game.Next()
+		utils.AssertNoError(t, err)
+
+		playerID := game.CurrentPlayer.PlayerID
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: playerID, Command: protocol.PlayHand, Decision: []int{0},
+		}})
+		utils.AssertNoError(t, err)
+
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{PlayerID: playerID, Command: game.AwaitingResponse()}})
+		utils.AssertNoError(t, err)
+	}
+
+	undo := func(game *shed, playerIDs ...string) ([]protocol.OutboundMessage, error) {
+		msgs := []protocol.InboundMessage{}
+		for _, id := range playerIDs {
+			msgs = append(msgs, protocol.InboundMessage{PlayerID: id, Command: protocol.Undo})
+		}
+		return game.ReceiveResponse(msgs)
+	}
+
+	t.Run("player takes back their move before the next player acts", func(t *testing.T) {
+		// Given p1 has played a card, and p2 is being asked to play
+		game := gameWithUndo(3)
+		_, err := game.Next()
+		utils.AssertNoError(t, err)
+		before := game.Snapshot()
+		before.ExpectedCommand = protocol.Null
+		// the turn starts again, without being counted twice
+		before.TurnCounted = true
+
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{
+			PlayerID: "p1", Command: protocol.PlayHand, Decision: []int{0},
+		}})
+		utils.AssertNoError(t, err)
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{PlayerID: "p1", Command: protocol.ReplenishHand}})
+		utils.AssertNoError(t, err)
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p2")
+		utils.AssertTrue(t, game.AcceptsCommand(protocol.Undo))
+
+		// When p1 asks to undo
+		msgs, err := undo(game, "p1")
+		utils.AssertNoError(t, err)
+
+		// Then the game is as it was before p1's move
+		after := game.Snapshot()
+		after.Events, before.Events = nil, nil
+		utils.AssertDeepEqual(t, after, before)
+		utils.AssertNoError(t, validateStateMachine(game))
+
+		// But the event log still records the move, followed by its undoing
+		events := game.Events()
+		utils.AssertEqual(t, events[len(events)-1].Kind, EventUndone)
+		utils.AssertEqual(t, events[len(events)-1].PlayerID, "p1")
+		utils.AssertTrue(t, containsEvent(events, EventPlayed, "p1"))
+		utils.AssertNoError(t, validateStateMachine(game))
+
+		// And every player is sent the restored state
+		utils.AssertEqual(t, len(msgs), len(game.PlayerInfo))
+		for _, m := range msgs {
+			utils.AssertEqual(t, m.Command, protocol.Undo)
+			utils.AssertDeepEqual(t, m.Hand, game.PlayerCards[m.PlayerID].Hand)
+			utils.AssertEqual(t, len(m.Pile), 0)
+		}
+
+		// And p1 is asked to play again
+		msgs, err = game.Next()
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.PlayHand)
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p1")
+		utils.AssertTrue(t, len(getMoves(msgs, "p1")) > 0)
+	})
+
+	t.Run("another player's move needs everyone's vote", func(t *testing.T) {
+		game := gameWithUndo(3)
+		move(t, game)
+		before := game.Snapshot()
+
+		// When only p2 asks to undo p1's move
+		msgs, err := undo(game, "p2")
+
+		// Then the players are told a vote is needed, and nothing is undone
+		utils.AssertNoError(t, err)
+		for _, m := range msgs {
+			utils.AssertEqual(t, m.Command, protocol.UndoRequested)
+		}
+		utils.AssertDeepEqual(t, game.Snapshot(), before)
+
+		// And when everyone agrees, the move is undone
+		msgs, err = undo(game, "p2", "p1")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, msgs[0].Command, protocol.Undo)
+		utils.AssertEqual(t, len(game.Pile), 0)
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p1")
+	})
+
+	t.Run("player cannot take back their move once the next player has acted", func(t *testing.T) {
+		game := gameWithUndo(3)
+		move(t, game)
+		move(t, game)
+		before := game.Snapshot()
+
+		msgs, err := undo(game, "p1")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, msgs[0].Command, protocol.UndoRequested)
+		utils.AssertDeepEqual(t, game.Snapshot(), before)
+	})
+
+	t.Run("player cannot take back their move once the next player has picked up the pile", func(t *testing.T) {
+		// Given p1 plays a King, which p2 cannot beat
+		game := gameWithUndo(3)
+		game.PlayerCards["p1"].Hand[0] = deck.NewCard(deck.King, deck.Clubs)
+		move(t, game)
+
+		// When p2 is made to pick up the pile
+		_, err := game.Next()
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.AwaitingResponse(), protocol.SkipTurn)
+
+		// Then p1 cannot take back their move alone
+		before := game.Snapshot()
+		msgs, err := undo(game, "p1")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, msgs[0].Command, protocol.UndoRequested)
+		utils.AssertDeepEqual(t, game.Snapshot(), before)
+
+		// Nor on their own next turn, once p2 has acknowledged it
+		_, err = game.ReceiveResponse([]protocol.InboundMessage{{PlayerID: "p2", Command: protocol.SkipTurn}})
+		utils.AssertNoError(t, err)
+		_, err = game.Next()
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, game.CurrentPlayer.PlayerID, "p1")
+
+		before = game.Snapshot()
+		msgs, err = undo(game, "p1")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, msgs[0].Command, protocol.UndoRequested)
+		utils.AssertDeepEqual(t, game.Snapshot(), before)
+	})
+
+	t.Run("moves can be undone up to the limit", func(t *testing.T) {
+		game := gameWithUndo(1)
+		move(t, game)
+		move(t, game)
+
+		_, err := undo(game, "p1", "p2")
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, len(game.Pile), 1)
+
+		_, err = undo(game, "p1", "p2")
+		utils.AssertTrue(t, errors.Is(err, ErrCannotUndo))
+		utils.AssertEqual(t, len(game.Pile), 1)
+	})
+
+	t.Run("undo is off unless the rules enable it", func(t *testing.T) {
+		game := gameWithUndo(0)
+		move(t, game)
+		utils.AssertEqual(t, game.AcceptsCommand(protocol.Undo), false)
+
+		msgs, err := undo(game, "p1")
+		utils.AssertTrue(t, errors.Is(err, ErrCannotUndo))
+		utils.AssertEqual(t, msgs[0].Command, protocol.Error)
+		utils.AssertEqual(t, len(game.Pile), 1)
+	})
+}
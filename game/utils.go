@@ -1,7 +1,9 @@
 package game
 
 import (
+	"math/rand"
 	"sort"
+	"time"
 
 	"github.com/minaorangina/shed/deck"
 	"github.com/minaorangina/shed/protocol"
@@ -9,8 +11,8 @@ import (
 
 func intSliceToSet(s []int) map[int]struct{} {
 	set := map[int]struct{}{}
-	for i := range s {
-		set[i] = struct{}{}
+	for _, v := range s {
+		set[v] = struct{}{}
 	}
 
 	return set
@@ -27,20 +29,43 @@ func setToIntSlice(set map[int]struct{}) []int {
 	return s
 }
 
-func cardSliceToSet(s []deck.Card) map[deck.Card]struct{} {
-	set := map[deck.Card]struct{}{}
-	for _, key := range s {
-		set[key] = struct{}{}
+// removeCards removes the cards at the given indices, preserving the order of
+// the cards that remain. The removed cards are returned in the order requested.
+func removeCards(cards []deck.Card, indices []int) ([]deck.Card, []deck.Card) {
+	toRemove := map[int]struct{}{}
+	removed := []deck.Card{}
+	for _, idx := range indices {
+		toRemove[idx] = struct{}{}
+		removed = append(removed, cards[idx])
 	}
-	return set
+
+	remaining := []deck.Card{}
+	for i, c := range cards {
+		if _, ok := toRemove[i]; !ok {
+			remaining = append(remaining, c)
+		}
+	}
+
+	return remaining, removed
 }
 
-func setToCardSlice(set map[deck.Card]struct{}) []deck.Card {
-	s := []deck.Card{}
-	for key := range set {
-		s = append(s, key)
+// mod returns a modulo n, always in the range [0, n)
+func mod(a, n int) int {
+	return ((a % n) + n) % n
+}
+
+func copyCards(cards []deck.Card) []deck.Card {
+	if cards == nil {
+		return []deck.Card{}
 	}
-	return s
+	return append([]deck.Card{}, cards...)
+}
+
+func copyPlayers(players []protocol.Player) []protocol.Player {
+	if players == nil {
+		return []protocol.Player{}
+	}
+	return append([]protocol.Player{}, players...)
 }
 
 func cardsUnique(cards []deck.Card) bool {
@@ -74,24 +99,27 @@ func combineCards(cards []deck.Card, toAdd ...deck.Card) []deck.Card {
 }
 
 func somePlayerCards(num int) *PlayerCards {
-	unseen := someDeck(num)
-	pc := NewPlayerCards(
+	return NewPlayerCards(
+		someDeck(num),
 		someDeck(num),
 		someDeck(num),
-		unseen,
 		nil,
 	)
-	for _, c := range unseen {
-		pc.UnseenVisibility[c] = false
-	}
+}
 
-	return pc
+func indexOfCardID(cards []deck.Card, id int) int {
+	for i, c := range cards {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
 }
 
 func containsCard(s []deck.Card, targets ...deck.Card) bool {
 	for _, c := range s {
 		for _, tg := range targets {
-			if c == tg {
+			if c.Matches(tg) {
 				return true
 			}
 		}
@@ -99,6 +127,26 @@ func containsCard(s []deck.Card, targets ...deck.Card) bool {
 	return false
 }
 
+// containsEvent reports whether any event is of the given kind and involves the player
+func containsEvent(events []Event, kind EventKind, playerID string) bool {
+	for _, e := range events {
+		if e.Kind == kind && e.PlayerID == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// indexOfPlayerID returns the index of the player with the given ID, or -1 if there is none
+func indexOfPlayerID(players []protocol.Player, playerID string) int {
+	for i, p := range players {
+		if p.PlayerID == playerID {
+			return i
+		}
+	}
+	return -1
+}
+
 func sliceContainsPlayerID(haystack []protocol.Player, needle string) bool {
 	var found bool
 	for _, h := range haystack {
@@ -110,3 +158,44 @@ func sliceContainsPlayerID(haystack []protocol.Player, needle string) bool {
 
 	return found
 }
+
+// seedOrNow returns the given seed, or a time-based seed if it is zero
+func seedOrNow(seed int64) int64 {
+	if seed == 0 {
+		return time.Now().UnixNano()
+	}
+	return seed
+}
+
+// countingSource is a rand.Source that counts how many values have been drawn
+// from it, so that its exact state can be recreated from the seed.
+type countingSource struct {
+	rand.Source64
+	draws uint64
+}
+
+// newCountingSource returns a source seeded with seed that has already
+// had draws values drawn from it.
+func newCountingSource(seed int64, draws uint64) *countingSource {
+	src := &countingSource{Source64: rand.NewSource(seed).(rand.Source64)}
+	for i := uint64(0); i < draws; i++ {
+		src.Source64.Uint64()
+	}
+	src.draws = draws
+	return src
+}
+
+func (src *countingSource) Int63() int64 {
+	src.draws++
+	return src.Source64.Int63()
+}
+
+func (src *countingSource) Uint64() uint64 {
+	src.draws++
+	return src.Source64.Uint64()
+}
+
+func (src *countingSource) Seed(seed int64) {
+	src.Source64.Seed(seed)
+	src.draws = 0
+}
@@ -14,6 +14,8 @@ type InboundMessage struct {
 	PlayerID string `json:"playerID"`
 	Command  Cmd    `json:"command"`
 	Decision []int  `json:"decision"`
+	// CardIDs, if given, choose cards by ID rather than by index in Decision
+	CardIDs []int `json:"cardIDs,omitempty"`
 }
 
 // OutboundMessage is a message from GameEngine to Player
@@ -27,6 +29,8 @@ type OutboundMessage struct {
 	Unseen          []deck.Card `json:"unseen"`
 	Pile            []deck.Card `json:"pile"`
 	DeckCount       int         `json:"deckCount"`
+	BurnedCount     int         `json:"burnedCount"`
+	LastBurned      []deck.Card `json:"lastBurned,omitempty"`
 	ShouldRespond   bool        `json:"shouldRespond"`
 	Joiner          Player      `json:"joiner,omitempty"`
 	CurrentTurn     Player      `json:"currentTurn,omitempty"`
@@ -35,6 +39,42 @@ type OutboundMessage struct {
 	Opponents       []Opponent  `json:"opponents,omitempty"`
 	FinishedPlayers []Player    `json:"finishedPlayers,omitempty"`
 	Error           string      `json:"error,omitempty"`
+	MoveError       *MoveError  `json:"moveError,omitempty"`
+	// EndReason explains why the game was ended before all but one player had finished
+	EndReason GameOverReason `json:"endReason,omitempty"`
+	// Drawn are the players who drew, when a game that was going nowhere ends in a draw
+	Drawn []Player `json:"drawn,omitempty"`
+}
+
+// GameOverReason explains why a game was ended before all but one player had finished
+type GameOverReason string
+
+const (
+	RepeatedPosition GameOverReason = "repeatedPosition" // the same position came round again and again
+	MoveLimitReached GameOverReason = "moveLimitReached" // the game went on for more turns than the rules allow
+)
+
+// MoveErrorReason is a code explaining why a move was rejected
+type MoveErrorReason string
+
+const (
+	RankMismatch    MoveErrorReason = "rankMismatch"    // cards played together must have the same rank
+	LowerThanSeven  MoveErrorReason = "lowerThanSeven"  // the top of the pile, such as a Seven, must be followed by a lower card
+	BelowTopCard    MoveErrorReason = "belowTopCard"    // the card is lower than the top of the pile
+	IndexOutOfRange MoveErrorReason = "indexOutOfRange" // there is no card at the chosen index
+	DuplicateCard   MoveErrorReason = "duplicateCard"   // the same card was chosen more than once
+	NoCardsChosen   MoveErrorReason = "noCardsChosen"   // no cards were chosen
+	WrongCardCount  MoveErrorReason = "wrongCardCount"  // the wrong number of cards was chosen
+	SeenBeforeHand  MoveErrorReason = "seenBeforeHand"  // face-up cards were chosen before the hand was emptied
+	NotYourTurn     MoveErrorReason = "notYourTurn"     // it is another player's turn
+	WrongCommand    MoveErrorReason = "wrongCommand"    // the game was expecting a different command
+)
+
+// MoveError explains why a player's move was rejected
+type MoveError struct {
+	Reason  MoveErrorReason `json:"reason"`
+	Message string          `json:"message"`
+	Card    *deck.Card      `json:"card,omitempty"` // the card that could not be played, if any
 }
 
 // Opponent is a representation of an opponent player
@@ -67,48 +107,63 @@ const (
 	UnseenFailure
 	PlayerFinished
 	GameOver
+	PlayHandAndSeen // when a player plays their last hand cards along with matching seen cards
+	Snap            // when a player plays cards matching the top of the pile out of turn
+	PickUpPile      // two way (outbound and ack)
+	Undo            // a request or vote to take back the last move, and the state once it has been
+	UndoRequested   // when another player wants to undo the last move
 )
 
 var CmdNames = map[Cmd]string{
-	Null:           "Null",
-	NewJoiner:      "NewJoiner",
-	Reorg:          "Reorg",
-	Start:          "Start",
-	HasStarted:     "HasStarted",
-	Error:          "Error",
-	PlayHand:       "PlayHand",
-	PlaySeen:       "PlaySeen",
-	PlayUnseen:     "PlayUnseen",
-	ReplenishHand:  "ReplenishHand",
-	Turn:           "Turn",
-	EndOfTurn:      "EndOfTurn",
-	SkipTurn:       "SkipTurn",
-	Burn:           "Burn",
-	UnseenSuccess:  "UnseenSuccess",
-	UnseenFailure:  "UnseenFailure",
-	PlayerFinished: "PlayerFinished",
-	GameOver:       "GameOver",
+	Null:            "Null",
+	NewJoiner:       "NewJoiner",
+	Reorg:           "Reorg",
+	Start:           "Start",
+	HasStarted:      "HasStarted",
+	Error:           "Error",
+	PlayHand:        "PlayHand",
+	PlaySeen:        "PlaySeen",
+	PlayUnseen:      "PlayUnseen",
+	ReplenishHand:   "ReplenishHand",
+	Turn:            "Turn",
+	EndOfTurn:       "EndOfTurn",
+	SkipTurn:        "SkipTurn",
+	Burn:            "Burn",
+	UnseenSuccess:   "UnseenSuccess",
+	UnseenFailure:   "UnseenFailure",
+	PlayerFinished:  "PlayerFinished",
+	GameOver:        "GameOver",
+	PlayHandAndSeen: "PlayHandAndSeen",
+	Snap:            "Snap",
+	PickUpPile:      "PickUpPile",
+	Undo:            "Undo",
+	UndoRequested:   "UndoRequested",
 }
 
 var NameToCmd = map[string]Cmd{
-	"Null":           Null,
-	"NewJoiner":      NewJoiner,
-	"Reorg":          Reorg,
-	"Start":          Start,
-	"HasStarted":     HasStarted,
-	"Error":          Error,
-	"PlayHand":       PlayHand,
-	"PlaySeen":       PlaySeen,
-	"PlayUnseen":     PlayUnseen,
-	"ReplenishHand":  ReplenishHand,
-	"Turn":           Turn,
-	"EndOfTurn":      EndOfTurn,
-	"SkipTurn":       SkipTurn,
-	"Burn":           Burn,
-	"UnseenSuccess":  UnseenSuccess,
-	"UnseenFailure":  UnseenFailure,
-	"PlayerFinished": PlayerFinished,
-	"GameOver":       GameOver,
+	"Null":            Null,
+	"NewJoiner":       NewJoiner,
+	"Reorg":           Reorg,
+	"Start":           Start,
+	"HasStarted":      HasStarted,
+	"Error":           Error,
+	"PlayHand":        PlayHand,
+	"PlaySeen":        PlaySeen,
+	"PlayUnseen":      PlayUnseen,
+	"ReplenishHand":   ReplenishHand,
+	"Turn":            Turn,
+	"EndOfTurn":       EndOfTurn,
+	"SkipTurn":        SkipTurn,
+	"Burn":            Burn,
+	"UnseenSuccess":   UnseenSuccess,
+	"UnseenFailure":   UnseenFailure,
+	"PlayerFinished":  PlayerFinished,
+	"GameOver":        GameOver,
+	"PlayHandAndSeen": PlayHandAndSeen,
+	"Snap":            Snap,
+	"PickUpPile":      PickUpPile,
+	"Undo":            Undo,
+	"UndoRequested":   UndoRequested,
 }
 
 func (c Cmd) String() string {
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -9,12 +10,14 @@ import (
 	"math/rand"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/minaorangina/shed/engine"
 	"github.com/minaorangina/shed/game"
 	"github.com/minaorangina/shed/protocol"
+	"github.com/minaorangina/shed/stats"
 	"github.com/minaorangina/shed/store"
 	str "github.com/minaorangina/shed/store"
 	uuid "github.com/satori/go.uuid"
@@ -25,6 +28,9 @@ var (
 	waitingRoomTemplate = "./static/waiting-room.tmpl"
 )
 
+// botDelay is how long computer players wait before they move, so that people can follow the game
+const botDelay = time.Second
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -34,7 +40,12 @@ var upgrader = websocket.Upgrader{
 }
 
 type NewGameReq struct {
-	Name string `json:"name"`
+	Name        string                 `json:"name"`
+	FirstPlayer game.FirstPlayerPolicy `json:"firstPlayer,omitempty"`
+	UndoLimit   int                    `json:"undoLimit,omitempty"`
+	MoveLimit   int                    `json:"moveLimit,omitempty"`
+	RepeatLimit int                    `json:"repeatLimit,omitempty"`
+	Stalemate   game.StalemateResult   `json:"stalemate,omitempty"`
 }
 
 type PendingGameRes struct {
@@ -50,9 +61,25 @@ type JoinGameReq struct {
 	GameID string `json:"gameID"`
 	Name   string `json:"name"`
 }
+
+// AddBotReq asks for a computer player to be added to a pending game.
+// Only the game's creator can add one.
+type AddBotReq struct {
+	GameID     string            `json:"gameID"`
+	PlayerID   string            `json:"playerID"`
+	Name       string            `json:"name,omitempty"`
+	Difficulty engine.Difficulty `json:"difficulty"`
+}
+
 type GetGameRes struct {
 	State  string `json:"state"`
 	GameID string `json:"gameID"`
+	Seed   int64  `json:"seed"`
+}
+
+type GetStatsRes struct {
+	Games   int                 `json:"games"`
+	Players []stats.PlayerStats `json:"players"`
 }
 
 // GameServer is a game server
@@ -65,19 +92,33 @@ func NewID() string {
 	return uuid.NewV4().String()
 }
 
+// gameIDSource generates game IDs. It is kept apart from game randomness,
+// which is seeded per game.
+var gameIDSource = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
 func NewGameID() string {
 	letters := []byte("ABCDEFGHIJKLMNOPQURSTUVWXYZ")
 	var code = []byte{}
 
+	gameIDSource.Lock()
+	defer gameIDSource.Unlock()
+
 	for i := 0; i < 6; i++ {
-		rand.Seed(time.Now().UnixNano())
-		idx := rand.Intn(25)
+		idx := gameIDSource.Intn(25)
 		code = append(code, letters[idx])
 	}
 
 	return string(code)
 }
 
+// NewSeed generates a seed for a new game
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
+
 func unknownGameIDMsg(unknownID string) string {
 	return fmt.Sprintf("unknown game ID '%s'", unknownID)
 }
@@ -113,8 +154,11 @@ func NewServer(str store.GameStore) *GameServer {
 	router.Handle("/new", http.HandlerFunc(enableCors(s.HandleNewGame)))
 	router.Handle("/game/", http.HandlerFunc(s.HandleFindGame))
 	router.Handle("/join", http.HandlerFunc(enableCors(s.HandleJoinGame)))
+	router.Handle("/bot", http.HandlerFunc(enableCors(s.HandleAddBot)))
 	router.Handle("/waiting-room", http.HandlerFunc(s.HandleWaitingRoom))
 	router.Handle("/ws", http.HandlerFunc(enableCors(s.HandleWS)))
+	router.Handle("/stats", http.HandlerFunc(enableCors(s.HandleStats)))
+	router.Handle("/snapshot", http.HandlerFunc(enableCors(s.HandleSnapshot)))
 
 	s.store = str
 
@@ -146,10 +190,28 @@ func (g *GameServer) HandleNewGame(w http.ResponseWriter, r *http.Request) {
 	// generate game ID
 	gameID := NewGameID()
 	playerID := NewID()
+	seed := NewSeed()
+
+	// the cards keep their default powers
+	rules := game.Rules{
+		FirstPlayer: data.FirstPlayer,
+		UndoLimit:   data.UndoLimit,
+		MoveLimit:   data.MoveLimit,
+		RepeatLimit: data.RepeatLimit,
+		Stalemate:   data.Stalemate,
+	}
+
+	shed, err := game.ExistingShed(game.ShedOpts{Seed: seed, Rules: rules, Creator: playerID})
+	if err != nil {
+		writeParseError(err, w, r)
+		return
+	}
+
 	game, err := engine.NewGameEngine(engine.GameEngineOpts{
 		GameID:    gameID,
 		CreatorID: playerID,
-		Game:      game.ExistingShed(game.ShedOpts{}),
+		Game:      shed,
+		Seed:      seed,
 	})
 	if err != nil {
 		log.Println(err.Error())
@@ -203,7 +265,7 @@ func (g *GameServer) HandleFindGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	gameID := strings.Replace(r.URL.String(), "/game/", "", 1)
+	gameID := strings.Replace(r.URL.Path, "/game/", "", 1)
 	if gameID == "" {
 		http.Error(w, "Missing game ID", http.StatusBadRequest)
 		return
@@ -216,13 +278,25 @@ func (g *GameServer) HandleFindGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	game := engine.Game()
-	if game == nil {
+	if engine.Game() == nil {
 		http.Error(w, "GameEngine had nil game", http.StatusNotFound)
 		return
 	}
 
-	bytes, err := json.Marshal(game)
+	// players only see their own cards, and anyone else sees only the table
+	var (
+		view protocol.OutboundMessage
+		ok   bool
+	)
+	engine.Inspect(func(current game.Game) {
+		view, ok = game.ViewOf(current, r.URL.Query().Get("playerID"))
+	})
+	if !ok {
+		http.Error(w, "GameEngine had no game to view", http.StatusNotFound)
+		return
+	}
+
+	bytes, err := json.Marshal(view)
 	if err != nil {
 		writeMarshalError(w, err)
 		return
@@ -231,6 +305,7 @@ func (g *GameServer) HandleFindGame(w http.ResponseWriter, r *http.Request) {
 	response := GetGameRes{
 		State:  string(bytes),
 		GameID: engine.ID(),
+		Seed:   engine.Seed(),
 	}
 
 	responseBytes, err := json.Marshal(response)
@@ -243,6 +318,96 @@ func (g *GameServer) HandleFindGame(w http.ResponseWriter, r *http.Request) {
 	w.Write(responseBytes)
 }
 
+// HandleSnapshot returns the Snapshot of a finished game, which cmd/replay replays.
+// Only the game's players may export it, as it shows every card they held.
+func (g *GameServer) HandleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writePathNotFoundError(w, fmt.Sprintf("path %s %s not found", r.Method, r.URL.Path))
+		return
+	}
+
+	query := r.URL.Query()
+	gameID, playerID := query.Get("gameID"), query.Get("playerID")
+	if gameID == "" {
+		http.Error(w, "missing game ID", http.StatusBadRequest)
+		return
+	}
+	if playerID == "" {
+		http.Error(w, "missing player ID", http.StatusBadRequest)
+		return
+	}
+
+	engine := g.store.FindGame(gameID)
+	if engine == nil {
+		http.Error(w, unknownGameIDMsg(gameID), http.StatusNotFound)
+		return
+	}
+	if _, ok := engine.Players().Find(playerID); !ok {
+		http.Error(w, "only the game's players can export it", http.StatusForbidden)
+		return
+	}
+
+	var (
+		snap     game.Snapshot
+		ok, over bool
+	)
+	engine.Inspect(func(current game.Game) {
+		if current == nil {
+			return
+		}
+		over = current.GameOver()
+		snap, ok = game.SnapshotOf(current)
+	})
+	if !over {
+		http.Error(w, "the game is not over", http.StatusConflict)
+		return
+	}
+	if !ok {
+		http.Error(w, "GameEngine had no game to export", http.StatusNotFound)
+		return
+	}
+
+	bytes, err := json.Marshal(snap)
+	if err != nil {
+		writeMarshalError(w, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(bytes)
+}
+
+// HandleStats returns every player's results from the games played to the end
+func (g *GameServer) HandleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writePathNotFoundError(w, fmt.Sprintf("path %s %s not found", r.Method, r.URL.Path))
+		return
+	}
+
+	results := stats.New()
+	games := 0
+	for _, ge := range g.store.FinishedGames() {
+		record := stats.GameRecord{Players: ge.Players().Info()}
+		ge.Inspect(func(g game.Game) {
+			record.Events = g.Events()
+		})
+		if err := results.Add(record); err != nil {
+			log.Printf("leaving game %s out of stats: %v", ge.ID(), err)
+			continue
+		}
+		games++
+	}
+
+	bytes, err := json.Marshal(GetStatsRes{Games: games, Players: results.Players()})
+	if err != nil {
+		writeMarshalError(w, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(bytes)
+}
+
 func (g *GameServer) HandleJoinGame(w http.ResponseWriter, r *http.Request) {
 	var data JoinGameReq
 	err := json.NewDecoder(r.Body).Decode(&data)
@@ -307,6 +472,88 @@ func (g *GameServer) HandleJoinGame(w http.ResponseWriter, r *http.Request) {
 	w.Write(bytes)
 }
 
+// HandleAddBot handles a request from a game's creator to fill a seat with a computer player
+func (g *GameServer) HandleAddBot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writePathNotFoundError(w, fmt.Sprintf("%s %s not found", r.Method, r.URL.Path))
+		return
+	}
+
+	var data AddBotReq
+	err := json.NewDecoder(r.Body).Decode(&data)
+	defer r.Body.Close()
+	if err != nil {
+		writeParseError(err, w, r)
+		return
+	}
+
+	if data.GameID == "" {
+		http.Error(w, "Missing game ID", http.StatusBadRequest)
+		return
+	}
+
+	game := g.store.FindInactiveGame(data.GameID)
+	if game == nil {
+		http.Error(w, unknownGameIDMsg(data.GameID), http.StatusBadRequest)
+		return
+	}
+
+	if data.PlayerID != game.CreatorID() {
+		http.Error(w, "Only the game's creator can add a computer player", http.StatusForbidden)
+		return
+	}
+
+	strategy, err := engine.NewStrategyFor(data.Difficulty, game.Game(), NewSeed())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	botID := NewID()
+	name := data.Name
+	if name == "" {
+		name = fmt.Sprintf("Computer (%s)", data.Difficulty)
+	}
+
+	err = g.store.AddPendingPlayer(data.GameID, botID, name)
+	if err != nil {
+		log.Println("error adding computer player to game", err)
+		http.Error(w, "Could not add computer player to game", http.StatusInternalServerError)
+		return
+	}
+
+	bot := engine.NewBotPlayer(engine.BotPlayerOpts{
+		ID:       botID,
+		Name:     name,
+		Strategy: strategy,
+		Engine:   game,
+		Delay:    botDelay,
+	})
+	err = g.store.AddPlayerToGame(data.GameID, bot)
+	if err != nil {
+		log.Println("error adding computer player to game", err)
+		http.Error(w, "Could not add computer player to game", http.StatusInternalServerError)
+		return
+	}
+
+	payload := PendingGameRes{
+		PlayerID:   botID,
+		GameID:     data.GameID,
+		PlayerInfo: protocol.Player{PlayerID: botID, Name: name},
+		Name:       name,
+	}
+
+	bytes, err := json.Marshal(payload)
+	if err != nil {
+		writeMarshalError(w, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(bytes)
+}
+
 func (g *GameServer) HandleWaitingRoom(w http.ResponseWriter, r *http.Request) {
 	// check if this person should get the file
 	query := r.URL.Query()
@@ -405,6 +652,10 @@ func writeParseError(err error, w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing body", http.StatusBadRequest)
 		return
 	}
+	if errors.Is(err, game.ErrInvalidRules) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	http.Error(w, "Could not parse data", http.StatusInternalServerError)
 }
 
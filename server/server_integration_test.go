@@ -87,7 +87,7 @@ func TestCreateAndJoinNewGame(t *testing.T) {
 	err = json.Unmarshal(bodyBytes, &joinPayload)
 	utils.AssertNoError(t, err)
 	utils.AssertNotEmptyString(t, joinPayload.PlayerID)
-	utils.AssertDeepEqual(t, joinPayload.Players, []protocol.Player{{createPayload.PlayerID, createPayload.Name}})
+	utils.AssertDeepEqual(t, joinPayload.Players, []protocol.Player{{PlayerID: createPayload.PlayerID, Name: createPayload.Name}})
 	utils.AssertEqual(t, joinPayload.Admin, false)
 
 	// and a pending player is created
@@ -2,13 +2,16 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/minaorangina/shed/deck"
 	"github.com/minaorangina/shed/engine"
 	"github.com/minaorangina/shed/game"
 	utils "github.com/minaorangina/shed/internal"
@@ -31,7 +34,7 @@ func TestServerPing(t *testing.T) {
 
 func TestServerPOSTNewGame(t *testing.T) {
 	t.Run("succeeds and returns expected data", func(t *testing.T) {
-		data := mustMakeJson(t, NewGameReq{"Elton"})
+		data := mustMakeJson(t, NewGameReq{Name: "Elton"})
 
 		response := httptest.NewRecorder()
 		request := newCreateGameRequest(data)
@@ -43,6 +46,131 @@ func TestServerPOSTNewGame(t *testing.T) {
 		assertPendingGameResponse(t, response.Body, "Elton")
 	})
 
+	t.Run("accepts a first player policy", func(t *testing.T) {
+		data := []byte(`{"name": "Elton", "firstPlayer": "lowestCard"}`)
+
+		response := httptest.NewRecorder()
+		request := newCreateGameRequest(data)
+
+		server := NewServer(NewBasicStore())
+		server.ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusCreated)
+		assertPendingGameResponse(t, response.Body, "Elton")
+	})
+
+	t.Run("returns 400 for an unknown first player policy", func(t *testing.T) {
+		data := []byte(`{"name": "Elton", "firstPlayer": "tallest"}`)
+
+		response := httptest.NewRecorder()
+		request := newCreateGameRequest(data)
+
+		server := NewServer(NewBasicStore())
+		server.ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 400 when the previous loser goes first", func(t *testing.T) {
+		// the server does not know who lost a previous game
+		data := []byte(`{"name": "Elton", "firstPlayer": "previousLoser"}`)
+
+		response := httptest.NewRecorder()
+		request := newCreateGameRequest(data)
+
+		server := NewServer(NewBasicStore())
+		server.ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("accepts an undo limit", func(t *testing.T) {
+		data := []byte(`{"name": "Elton", "undoLimit": 3}`)
+
+		response := httptest.NewRecorder()
+		request := newCreateGameRequest(data)
+
+		server := NewServer(NewBasicStore())
+		server.ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusCreated)
+		assertPendingGameResponse(t, response.Body, "Elton")
+	})
+
+	t.Run("accepts a move limit and a stalemate result", func(t *testing.T) {
+		data := []byte(`{"name": "Elton", "moveLimit": 500, "stalemate": "fewestCards"}`)
+
+		response := httptest.NewRecorder()
+		request := newCreateGameRequest(data)
+
+		server := NewServer(NewBasicStore())
+		server.ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusCreated)
+		assertPendingGameResponse(t, response.Body, "Elton")
+	})
+
+	t.Run("accepts a repeat limit", func(t *testing.T) {
+		data := []byte(`{"name": "Elton", "repeatLimit": 3}`)
+
+		response := httptest.NewRecorder()
+		request := newCreateGameRequest(data)
+
+		store := NewBasicStore()
+		server := NewServer(store)
+		server.ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusCreated)
+
+		var got PendingGameRes
+		utils.AssertNoError(t, json.NewDecoder(response.Body).Decode(&got))
+		rules := game.RulesOf(store.Games[got.GameID].Game())
+		utils.AssertEqual(t, rules.RepeatLimit, 3)
+		// and the game is still limited to the default number of moves
+		utils.AssertEqual(t, rules.MoveLimit, game.DefaultRules().MoveLimit)
+	})
+
+	t.Run("accepts a move limit", func(t *testing.T) {
+		data := []byte(`{"name": "Elton", "moveLimit": 300}`)
+
+		response := httptest.NewRecorder()
+		request := newCreateGameRequest(data)
+
+		store := NewBasicStore()
+		server := NewServer(store)
+		server.ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusCreated)
+
+		var got PendingGameRes
+		utils.AssertNoError(t, json.NewDecoder(response.Body).Decode(&got))
+		utils.AssertEqual(t, game.RulesOf(store.Games[got.GameID].Game()).MoveLimit, 300)
+	})
+
+	t.Run("returns 400 for a repeat limit of one", func(t *testing.T) {
+		data := []byte(`{"name": "Elton", "repeatLimit": 1}`)
+
+		response := httptest.NewRecorder()
+		request := newCreateGameRequest(data)
+
+		server := NewServer(NewBasicStore())
+		server.ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 400 for a negative undo limit", func(t *testing.T) {
+		data := []byte(`{"name": "Elton", "undoLimit": -1}`)
+
+		response := httptest.NewRecorder()
+		request := newCreateGameRequest(data)
+
+		server := NewServer(NewBasicStore())
+		server.ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
 	t.Run("returns 400 if the player's name is missing", func(t *testing.T) {
 		response := httptest.NewRecorder()
 		request := newCreateGameRequest([]byte{})
@@ -113,7 +241,7 @@ func TestJoinGame(t *testing.T) {
 		response := httptest.NewRecorder()
 		request := newJoinGameRequest(nil)
 
-		game := newTestGame(t, engine.GameEngineOpts{GameID: "some-game-id", Players: engine.SomePlayers(), Game: game.ExistingShed(game.ShedOpts{})})
+		game := newTestGame(t, engine.GameEngineOpts{GameID: "some-game-id", Players: engine.SomePlayers(), Game: engine.UndealtGame()})
 		server := newServerWithGame(game)
 
 		server.ServeHTTP(response, request)
@@ -150,14 +278,85 @@ func TestJoinGame(t *testing.T) {
 	})
 }
 
+func TestAddBot(t *testing.T) {
+	t.Run("POST /bot adds a computer player for the creator", func(t *testing.T) {
+		// Given a pending game
+		server, pendingID := newServerWithInactiveGame(t, engine.SomePlayers())
+		ge := server.store.FindGame(pendingID)
+
+		// When its creator adds a computer player
+		data := mustMakeJson(t, AddBotReq{GameID: pendingID, PlayerID: "hersha-1", Difficulty: engine.Easy})
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newAddBotRequest(data))
+
+		// Then the computer player is waiting to play
+		assertStatus(t, response.Code, http.StatusCreated)
+		utils.AssertEqual(t, response.Result().Header.Get("Content-Type"), "application/json")
+
+		var got PendingGameRes
+		utils.AssertNoError(t, json.NewDecoder(response.Body).Decode(&got))
+		utils.AssertEqual(t, got.GameID, pendingID)
+		utils.AssertEqual(t, got.Name, "Computer (easy)")
+		utils.AssertNotNil(t, server.store.FindPendingPlayer(pendingID, got.PlayerID))
+
+		deadline := time.Now().Add(time.Second)
+		for len(ge.Players()) < 3 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		bot, ok := ge.Players().Find(got.PlayerID)
+		utils.AssertTrue(t, ok)
+		utils.AssertEqual(t, bot.Name(), "Computer (easy)")
+	})
+
+	t.Run("POST /bot returns 403 for anyone but the creator", func(t *testing.T) {
+		server, pendingID := newServerWithInactiveGame(t, engine.SomePlayers())
+
+		data := mustMakeJson(t, AddBotReq{GameID: pendingID, PlayerID: "pending-player-id", Difficulty: engine.Easy})
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newAddBotRequest(data))
+
+		assertStatus(t, response.Code, http.StatusForbidden)
+		utils.AssertEqual(t, len(server.store.FindGame(pendingID).Players()), 2)
+	})
+
+	t.Run("POST /bot returns 400 for an unknown difficulty", func(t *testing.T) {
+		server, pendingID := newServerWithInactiveGame(t, engine.SomePlayers())
+
+		data := mustMakeJson(t, AddBotReq{GameID: pendingID, PlayerID: "hersha-1", Difficulty: "impossible"})
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newAddBotRequest(data))
+
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("POST /bot returns 400 for an unknown game id", func(t *testing.T) {
+		server, _ := newServerWithInactiveGame(t, engine.SomePlayers())
+
+		data := mustMakeJson(t, AddBotReq{GameID: "some-game-id", PlayerID: "hersha-1", Difficulty: engine.Easy})
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newAddBotRequest(data))
+
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("Does not match on GET /bot", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodGet, "/bot", nil)
+		response := httptest.NewRecorder()
+
+		NewServer(NewBasicStore()).ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusNotFound)
+	})
+}
+
 func TestServerGETGame(t *testing.T) {
 	t.Run("returns an existing active game", func(t *testing.T) {
 		testID := "12u34"
-		game := game.ExistingShed(game.ShedOpts{})
+		g := engine.UndealtGame()
 		server := newServerWithGame(newTestGame(t, engine.GameEngineOpts{
 			GameID:    testID,
 			PlayState: engine.InProgress,
-			Game:      game,
+			Game:      g,
 		}))
 
 		request := newGetGameRequest(testID)
@@ -165,9 +364,11 @@ func TestServerGETGame(t *testing.T) {
 
 		server.ServeHTTP(response, request)
 
-		gameJSON, err := json.Marshal(game)
+		view, ok := game.ViewOf(g, "")
+		utils.AssertTrue(t, ok)
+		viewJSON, err := json.Marshal(view)
 		utils.AssertNoError(t, err)
-		want := GetGameRes{State: string(gameJSON), GameID: testID}
+		want := GetGameRes{State: string(viewJSON), GameID: testID}
 
 		bodyBytes, err := ioutil.ReadAll(response.Result().Body)
 		utils.AssertNoError(t, err)
@@ -190,9 +391,11 @@ func TestServerGETGame(t *testing.T) {
 
 		ge := server.store.FindGame(pendingID)
 		utils.AssertNotNil(t, ge)
-		gameJSON, err := json.Marshal(ge.Game())
+		view, ok := game.ViewOf(ge.Game(), "")
+		utils.AssertTrue(t, ok)
+		viewJSON, err := json.Marshal(view)
 		utils.AssertNoError(t, err)
-		want := GetGameRes{State: string(gameJSON), GameID: pendingID}
+		want := GetGameRes{State: string(viewJSON), GameID: pendingID}
 
 		bodyBytes, err := ioutil.ReadAll(response.Result().Body)
 		utils.AssertNoError(t, err)
@@ -205,10 +408,48 @@ func TestServerGETGame(t *testing.T) {
 		utils.AssertEqual(t, got, want)
 	})
 
+	t.Run("shows players only their own cards", func(t *testing.T) {
+		testID := "12u34"
+		g := engine.UndealtGame()
+		utils.AssertNoError(t, g.Start([]protocol.Player{{PlayerID: "p1", Name: "Ann"}, {PlayerID: "p2", Name: "Bob"}}))
+		server := newServerWithGame(newTestGame(t, engine.GameEngineOpts{
+			GameID:    testID,
+			PlayState: engine.InProgress,
+			Game:      g,
+		}))
+
+		getView := func(request *http.Request) protocol.OutboundMessage {
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, request)
+			assertStatus(t, response.Code, http.StatusOK)
+
+			var got GetGameRes
+			utils.AssertNoError(t, json.NewDecoder(response.Body).Decode(&got))
+			var view protocol.OutboundMessage
+			utils.AssertNoError(t, json.Unmarshal([]byte(got.State), &view))
+			return view
+		}
+
+		// A player sees their hand, but not their face-down cards or anyone else's hand
+		view := getView(newGetGameRequest(testID + "?playerID=p1"))
+		utils.AssertEqual(t, view.PlayerID, "p1")
+		utils.AssertEqual(t, len(view.Hand), 3)
+		for _, c := range view.Unseen {
+			utils.AssertEqual(t, c.Rank, deck.NullRank)
+		}
+		utils.AssertEqual(t, len(view.Opponents), 1)
+		utils.AssertEqual(t, view.Opponents[0].PlayerID, "p2")
+
+		// And anyone else sees only the table
+		view = getView(newGetGameRequest(testID))
+		utils.AssertEqual(t, len(view.Hand), 0)
+		utils.AssertEqual(t, len(view.Unseen), 0)
+	})
+
 	t.Run("returns a 404 if game doesn't exist", func(t *testing.T) {
 		gameID := "12u34"
 		nonExistentID := "bad-game-id"
-		server := newServerWithGame(newTestGame(t, engine.GameEngineOpts{GameID: gameID, Game: game.ExistingShed(game.ShedOpts{})}))
+		server := newServerWithGame(newTestGame(t, engine.GameEngineOpts{GameID: gameID, Game: engine.UndealtGame()}))
 
 		request := newGetGameRequest(nonExistentID)
 		response := httptest.NewRecorder()
@@ -219,6 +460,146 @@ func TestServerGETGame(t *testing.T) {
 	})
 }
 
+func TestServerGETStats(t *testing.T) {
+	t.Run("returns the results of finished games", func(t *testing.T) {
+		// Given a finished game and a game in progress
+		finished := newTestGame(t, engine.GameEngineOpts{
+			GameID:  "finished-id",
+			Players: engine.NewPlayers(engine.APlayer("p1", "Ann"), engine.APlayer("p2", "Bob")),
+			Game: finishedGame{SpyGame: engine.NewSpyGame(), events: []game.Event{
+				{Seq: 1, Kind: game.EventPlayed, PlayerID: "p1", Group: game.HandGroup, Cards: []deck.Card{deck.NewCard(deck.Ace, deck.Spades)}},
+				{Seq: 2, Kind: game.EventPlayerFinished, PlayerID: "p1"},
+				{Seq: 3, Kind: game.EventGameOver, PlayerID: "p2"},
+			}},
+		})
+		inProgress := newTestGame(t, engine.GameEngineOpts{
+			GameID:    "in-progress-id",
+			PlayState: engine.InProgress,
+			Players:   engine.NewPlayers(engine.APlayer("p3", "Cat")),
+			Game:      engine.UndealtGame(),
+		})
+		str := NewBasicStore()
+		str.Games[finished.ID()] = finished
+		str.Games[inProgress.ID()] = inProgress
+
+		// When the stats are requested
+		response := httptest.NewRecorder()
+		NewServer(str).ServeHTTP(response, newGetStatsRequest())
+
+		// Then only the finished game is counted
+		assertStatus(t, response.Code, http.StatusOK)
+
+		var got GetStatsRes
+		utils.AssertNoError(t, json.NewDecoder(response.Body).Decode(&got))
+		utils.AssertEqual(t, got.Games, 1)
+		utils.AssertEqual(t, len(got.Players), 2)
+		utils.AssertEqual(t, got.Players[0].Name, "Ann")
+		utils.AssertEqual(t, got.Players[0].Wins, 1)
+		utils.AssertEqual(t, got.Players[0].AverageTurnsToFinish, 1.0)
+		utils.AssertEqual(t, got.Players[1].Name, "Bob")
+		utils.AssertEqual(t, got.Players[1].AverageFinishingPosition, 2.0)
+	})
+
+	t.Run("can be requested while a game is played", func(t *testing.T) {
+		// Given a game of bots
+		ge := newBotGame(t, "bot-game")
+		server := newServerWithGame(ge)
+
+		// When the stats are requested while the bots play
+		ge.Receive(protocol.InboundMessage{PlayerID: "bot-0", Command: protocol.Start})
+
+		// Then the game is counted once it is over
+		deadline := time.After(5 * time.Second)
+		for {
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, newGetStatsRequest())
+			assertStatus(t, response.Code, http.StatusOK)
+
+			var got GetStatsRes
+			utils.AssertNoError(t, json.NewDecoder(response.Body).Decode(&got))
+			if got.Games == 1 {
+				utils.AssertEqual(t, len(got.Players), 3)
+				return
+			}
+
+			select {
+			case <-deadline:
+				t.Fatal("the bots did not finish the game")
+			case <-time.After(time.Millisecond):
+			}
+		}
+	})
+
+	t.Run("Does not match on POST /stats", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodPost, "/stats", nil)
+		response := httptest.NewRecorder()
+
+		NewServer(NewBasicStore()).ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusNotFound)
+	})
+}
+
+func TestServerGETSnapshot(t *testing.T) {
+	t.Run("exports a finished game to its players, for it to be replayed", func(t *testing.T) {
+		// Given a game that bots have played to the end
+		server := newServerWithGame(finishedBotGame(t, "bot-game"))
+
+		// When one of its players exports it
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newSnapshotRequest("bot-game", "bot-1"))
+
+		// Then they get a snapshot the whole game can be replayed from
+		assertStatus(t, response.Code, http.StatusOK)
+
+		var record game.Snapshot
+		utils.AssertNoError(t, json.NewDecoder(response.Body).Decode(&record))
+		utils.AssertEqual(t, record.Version, game.SnapshotVersion)
+		utils.AssertEqual(t, record.Events[len(record.Events)-1].Kind, game.EventGameOver)
+
+		replay, err := game.NewReplay(record)
+		utils.AssertNoError(t, err)
+		for err == nil {
+			err = replay.Step()
+		}
+		utils.AssertTrue(t, errors.Is(err, game.ErrReplayFinished))
+		utils.AssertEqual(t, replay.Seq(), replay.Len())
+	})
+
+	t.Run("returns 403 for anyone who did not play the game", func(t *testing.T) {
+		server := newServerWithGame(finishedBotGame(t, "bot-game"))
+
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newSnapshotRequest("bot-game", "onlooker"))
+
+		assertStatus(t, response.Code, http.StatusForbidden)
+	})
+
+	t.Run("returns 409 for a game that is not over", func(t *testing.T) {
+		players := engine.SomePlayers()
+		ge := newTestGame(t, engine.GameEngineOpts{GameID: "some-game-id", Players: players, Game: engine.UndealtGame()})
+
+		response := httptest.NewRecorder()
+		newServerWithGame(ge).ServeHTTP(response, newSnapshotRequest("some-game-id", players[0].ID()))
+
+		assertStatus(t, response.Code, http.StatusConflict)
+	})
+
+	t.Run("returns 404 for an unknown game", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		NewServer(NewBasicStore()).ServeHTTP(response, newSnapshotRequest("unknown-id", "bot-1"))
+
+		assertStatus(t, response.Code, http.StatusNotFound)
+	})
+
+	t.Run("returns 400 without a player ID", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		NewServer(NewBasicStore()).ServeHTTP(response, newSnapshotRequest("bot-game", ""))
+
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+}
+
 func TestWS(t *testing.T) {
 	t.Run("Handles missing game details", func(t *testing.T) {
 		server := httptest.NewServer(NewServer(NewBasicStore()))
@@ -253,7 +634,7 @@ func TestWS(t *testing.T) {
 		gameID := "this-is-a-game-id"
 		name, playerID := "Delilah", "delilah1"
 
-		game := newTestGame(t, engine.GameEngineOpts{GameID: gameID, CreatorID: playerID, Game: game.ExistingShed(game.ShedOpts{})})
+		game := newTestGame(t, engine.GameEngineOpts{GameID: gameID, CreatorID: playerID, Game: engine.UndealtGame()})
 
 		store := NewBasicStore()
 		store.AddInactiveGame(game)
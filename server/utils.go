@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/minaorangina/shed/engine"
@@ -26,12 +27,12 @@ type stubStore struct {
 
 func (s *stubStore) FindActiveGame(ID string) engine.GameEngine {
 	// allows any game
-	game, _ := engine.NewGameEngine(engine.GameEngineOpts{Game: game.ExistingShed(game.ShedOpts{})})
+	game, _ := engine.NewGameEngine(engine.GameEngineOpts{Game: engine.UndealtGame()})
 	return game
 }
 func (s *stubStore) FindInactiveGame(ID string) engine.GameEngine {
 	// allows any pending game
-	game, _ := engine.NewGameEngine(engine.GameEngineOpts{Game: game.ExistingShed(game.ShedOpts{})})
+	game, _ := engine.NewGameEngine(engine.GameEngineOpts{Game: engine.UndealtGame()})
 	return game
 }
 
@@ -50,6 +51,20 @@ func (s *stubStore) ActivateGame(gameID string) error {
 	return nil
 }
 
+// finishedGame is a game that has been played to the end
+type finishedGame struct {
+	*engine.SpyGame
+	events []game.Event
+}
+
+func (g finishedGame) GameOver() bool {
+	return true
+}
+
+func (g finishedGame) Events() []game.Event {
+	return g.events
+}
+
 type fakeStore struct{}
 
 func (s fakeStore) ActiveGames() map[string]engine.GameEngine {
@@ -61,12 +76,12 @@ func (s fakeStore) InactiveGames() map[string]engine.GameEngine {
 
 func (s fakeStore) FindActiveGame(ID string) engine.GameEngine {
 	// allows any game
-	game, _ := engine.NewGameEngine(engine.GameEngineOpts{Game: game.ExistingShed(game.ShedOpts{})})
+	game, _ := engine.NewGameEngine(engine.GameEngineOpts{Game: engine.UndealtGame()})
 	return game
 }
 func (s fakeStore) FindInactiveGame(ID string) engine.GameEngine {
 	// allows any pending game
-	game, _ := engine.NewGameEngine(engine.GameEngineOpts{Game: game.ExistingShed(game.ShedOpts{})})
+	game, _ := engine.NewGameEngine(engine.GameEngineOpts{Game: engine.UndealtGame()})
 	return game
 }
 
@@ -74,6 +89,10 @@ func (s fakeStore) FindPendingPlayer(gameID, playerID string) *protocol.Player {
 	return &protocol.Player{}
 }
 
+func (s fakeStore) FinishedGames() []engine.GameEngine {
+	return nil
+}
+
 func (s fakeStore) AddInactiveGame(game engine.GameEngine) error {
 	return nil
 }
@@ -120,6 +139,11 @@ func newGetGameRequest(gameID string) *http.Request {
 	return request
 }
 
+func newGetStatsRequest() *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/stats", nil)
+	return request
+}
+
 func newJoinGameRequest(data []byte) *http.Request {
 	var request *http.Request
 	if data == nil {
@@ -130,6 +154,11 @@ func newJoinGameRequest(data []byte) *http.Request {
 	return request
 }
 
+func newAddBotRequest(data []byte) *http.Request {
+	request, _ := http.NewRequest(http.MethodPost, "/bot", bytes.NewBuffer(data))
+	return request
+}
+
 func newTestGame(t *testing.T, opts engine.GameEngineOpts) engine.GameEngine {
 	t.Helper()
 
@@ -140,6 +169,52 @@ func newTestGame(t *testing.T, opts engine.GameEngineOpts) engine.GameEngine {
 	return game
 }
 
+// newBotGame returns a game of three bots, ready to start
+func newBotGame(t *testing.T, gameID string) engine.GameEngine {
+	t.Helper()
+
+	shed, err := game.ExistingShed(game.ShedOpts{Seed: 2, Rules: game.DefaultRules()})
+	utils.AssertNoError(t, err)
+	ge := newTestGame(t, engine.GameEngineOpts{GameID: gameID, Game: shed, SnapWindow: time.Millisecond})
+	for i := 0; i < 3; i++ {
+		utils.AssertNoError(t, ge.AddPlayer(engine.NewBotPlayer(engine.BotPlayerOpts{
+			ID:       fmt.Sprintf("bot-%d", i),
+			Name:     fmt.Sprintf("Bot %d", i),
+			Strategy: engine.NewRandomStrategy(int64(i)),
+			Engine:   ge,
+		})))
+	}
+	return ge
+}
+
+// finishedBotGame returns a game that three bots have played to the end
+func finishedBotGame(t *testing.T, gameID string) engine.GameEngine {
+	t.Helper()
+
+	ge := newBotGame(t, gameID)
+	ge.Receive(protocol.InboundMessage{PlayerID: "bot-0", Command: protocol.Start})
+
+	deadline := time.After(5 * time.Second)
+	for {
+		over := false
+		ge.Inspect(func(g game.Game) { over = g.GameOver() })
+		if over {
+			return ge
+		}
+
+		select {
+		case <-deadline:
+			t.Fatal("the bots did not finish the game")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func newSnapshotRequest(gameID, playerID string) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/snapshot?gameID=%s&playerID=%s", gameID, playerID), nil)
+	return request
+}
+
 func newServerWithGame(game engine.GameEngine) http.Handler {
 	id := game.ID()
 	store := &store.InMemoryGameStore{
@@ -161,7 +236,7 @@ func newServerWithInactiveGame(t *testing.T, ps engine.Players) (*GameServer, st
 		GameID:    gameID,
 		CreatorID: "hersha-1",
 		Players:   ps,
-		Game:      game.ExistingShed(game.ShedOpts{}),
+		Game:      engine.UndealtGame(),
 	})
 
 	if err != nil {
@@ -204,7 +279,7 @@ func newTestServerWithInactiveGame(t *testing.T, ps engine.Players, info []proto
 		GameID:    gameID,
 		CreatorID: info[0].PlayerID,
 		Players:   ps,
-		Game:      game.ExistingShed(game.ShedOpts{}),
+		Game:      engine.UndealtGame(),
 	})
 	if err != nil {
 		t.Fatal(err)
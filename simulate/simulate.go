@@ -0,0 +1,190 @@
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/minaorangina/shed/engine"
+	"github.com/minaorangina/shed/game"
+	"github.com/minaorangina/shed/protocol"
+	"github.com/minaorangina/shed/stats"
+)
+
+// ErrNoResponse is returned when the game is waiting for a response that no player was asked for
+var ErrNoResponse = errors.New("no player was asked for the response the game is waiting for")
+
+// defaultMaxSteps is how many steps a game may take before it is called a stalemate
+const defaultMaxSteps = 5000
+
+// Contender is a strategy that takes a seat at every simulated game
+type Contender struct {
+	Name string
+	// New returns the strategy that plays the contender's seat at one game
+	New func(g game.Game, seed int64) engine.Strategy
+}
+
+// Opts configures a simulation
+type Opts struct {
+	// Contenders each take a seat at every game. The seats are rotated from game to game,
+	// so that no contender always plays first.
+	Contenders []Contender
+	Games      int
+	// Seed seeds every game, so that a simulation can be repeated exactly
+	Seed int64
+	// Rules are the house rules the games are played by. If unset, the default rules are used.
+	Rules game.Rules
+	// MaxSteps is how many steps a game may take before it is called a stalemate. Defaults to 5000.
+	MaxSteps int
+}
+
+// Result is the outcome of a simulation
+type Result struct {
+	Games    int
+	Finished int
+	// Stalemates are games that went nowhere: those ended by the rules for endless games,
+	// and those not over after MaxSteps. They are left out of the contenders' results.
+	Stalemates int
+	// AverageMoves is the average number of decisions made in a finished game
+	AverageMoves float64
+	// Contenders are the contenders' results from the finished games, best first
+	Contenders []stats.PlayerStats
+	// Failures are the games that broke the rules of the state machine
+	Failures []Failure
+}
+
+// Failure describes a game that was abandoned because it returned an error or broke an invariant
+type Failure struct {
+	Game int
+	Seed int64
+	Step int
+	Err  error
+}
+
+func (f Failure) Error() string {
+	return fmt.Sprintf("game %d (seed %d), step %d: %v", f.Game, f.Seed, f.Step, f.Err)
+}
+
+// Run plays every game in turn, driving each one directly with Next and ReceiveResponse
+func Run(opts Opts) (Result, error) {
+	if len(opts.Contenders) < 2 {
+		return Result{}, fmt.Errorf("a simulation needs at least 2 contenders, got %d", len(opts.Contenders))
+	}
+	if opts.MaxSteps <= 0 {
+		opts.MaxSteps = defaultMaxSteps
+	}
+
+	result := Result{Games: opts.Games}
+	results := stats.New()
+	moves := 0
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	for i := 0; i < opts.Games; i++ {
+		seed := rng.Int63()
+		outcome, err := play(i, seed, opts)
+		if err != nil {
+			return Result{}, err
+		}
+
+		switch {
+		case outcome.failure != nil:
+			result.Failures = append(result.Failures, *outcome.failure)
+		case !outcome.over || outcome.endReason != "":
+			result.Stalemates++
+		default:
+			if err := results.Add(outcome.record); err != nil {
+				result.Failures = append(result.Failures, Failure{Game: i, Seed: seed, Step: outcome.steps, Err: err})
+				continue
+			}
+			result.Finished++
+			moves += outcome.moves
+		}
+	}
+
+	if result.Finished > 0 {
+		result.AverageMoves = float64(moves) / float64(result.Finished)
+	}
+	result.Contenders = results.Players()
+
+	return result, nil
+}
+
+type outcome struct {
+	record    stats.GameRecord
+	over      bool
+	endReason protocol.GameOverReason
+	steps     int
+	moves     int
+	failure   *Failure
+}
+
+// play plays one game, seating the contenders in an order rotated by the game's number
+func play(n int, seed int64, opts Opts) (outcome, error) {
+	players := []protocol.Player{}
+	for i := range opts.Contenders {
+		c := opts.Contenders[(n+i)%len(opts.Contenders)]
+		players = append(players, protocol.Player{PlayerID: fmt.Sprintf("seat-%d", i), Name: c.Name})
+	}
+
+	g, err := game.NewShed(game.ShedOpts{Players: players, Seed: seed, Rules: opts.Rules})
+	if err != nil {
+		return outcome{}, err
+	}
+
+	strategies := map[string]engine.Strategy{}
+	for i, p := range players {
+		c := opts.Contenders[(n+i)%len(opts.Contenders)]
+		strategies[p.PlayerID] = c.New(g, seed+int64(i))
+	}
+
+	o := outcome{record: stats.GameRecord{Players: players}}
+	fail := func(err error) outcome {
+		o.failure = &Failure{Game: n, Seed: seed, Step: o.steps, Err: err}
+		return o
+	}
+
+	// asked holds the messages from the game that ask for a response
+	var asked []protocol.OutboundMessage
+	for ; o.steps < opts.MaxSteps && !g.GameOver(); o.steps++ {
+		var msgs []protocol.OutboundMessage
+		if g.AwaitingResponse() == protocol.Null {
+			msgs, err = g.Next()
+		} else {
+			responses := []protocol.InboundMessage{}
+			for _, m := range asked {
+				if m.Command == g.AwaitingResponse() {
+					responses = append(responses, engine.Respond(strategies[m.PlayerID], g, m))
+				}
+			}
+			if len(responses) == 0 {
+				return fail(fmt.Errorf("%w: %s", ErrNoResponse, g.AwaitingResponse())), nil
+			}
+			for _, r := range responses {
+				if r.Decision != nil && r.Command != protocol.Reorg {
+					o.moves++
+				}
+			}
+			msgs, err = g.ReceiveResponse(responses)
+		}
+		if err != nil {
+			return fail(err), nil
+		}
+		if err := game.Validate(g); err != nil {
+			return fail(err), nil
+		}
+
+		asked = nil
+		for _, m := range msgs {
+			if m.Command == protocol.GameOver {
+				o.endReason = m.EndReason
+			}
+			if m.ShouldRespond && m.Command != protocol.Error {
+				asked = append(asked, m)
+			}
+		}
+	}
+
+	o.over = g.GameOver()
+	o.record.Events = g.Events()
+	return o, nil
+}
@@ -0,0 +1,81 @@
+package simulate
+
+import (
+	"testing"
+
+	"github.com/minaorangina/shed/engine"
+	"github.com/minaorangina/shed/game"
+	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("plays every game and totals the results", func(t *testing.T) {
+		// Given three contenders
+		opts := Opts{Contenders: someContenders(), Games: 30, Seed: 1}
+
+		// When they play many games
+		result, err := Run(opts)
+		utils.AssertNoError(t, err)
+
+		// Then every game is accounted for, and none break the rules
+		utils.AssertEqual(t, result.Games, 30)
+		utils.AssertEqual(t, len(result.Failures), 0)
+		utils.AssertEqual(t, result.Finished+result.Stalemates, 30)
+		utils.AssertTrue(t, result.Finished > 0)
+		utils.AssertTrue(t, result.AverageMoves > 0)
+
+		// And each contender took a seat at every finished game
+		utils.AssertEqual(t, len(result.Contenders), 3)
+		wins := 0
+		for _, c := range result.Contenders {
+			utils.AssertEqual(t, c.Games, result.Finished)
+			wins += c.Wins
+		}
+		utils.AssertEqual(t, wins, result.Finished)
+	})
+
+	t.Run("plays the same games from the same seed", func(t *testing.T) {
+		opts := Opts{Contenders: someContenders(), Games: 5, Seed: 2}
+
+		first, err := Run(opts)
+		utils.AssertNoError(t, err)
+		second, err := Run(opts)
+		utils.AssertNoError(t, err)
+
+		utils.AssertDeepEqual(t, second, first)
+	})
+
+	t.Run("reports games that go wrong", func(t *testing.T) {
+		// Given a contender that chooses cards it does not have
+		cheat := Contender{Name: "Cheat", New: func(g game.Game, seed int64) engine.Strategy { return badStrategy{} }}
+		opts := Opts{Contenders: append(someContenders(), cheat), Games: 2, Seed: 3}
+
+		result, err := Run(opts)
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, len(result.Failures), 2)
+		utils.AssertEqual(t, result.Finished, 0)
+		utils.AssertEqual(t, result.Failures[1].Game, 1)
+	})
+
+	t.Run("needs at least two contenders", func(t *testing.T) {
+		_, err := Run(Opts{Contenders: someContenders()[:1], Games: 1})
+		utils.AssertErrored(t, err)
+	})
+}
+
+func someContenders() []Contender {
+	rules := game.DefaultRules()
+	return []Contender{
+		{Name: "Random", New: func(g game.Game, seed int64) engine.Strategy { return engine.NewRandomStrategy(seed) }},
+		{Name: "Lowest", New: func(g game.Game, seed int64) engine.Strategy { return engine.NewLowestFirstStrategy(rules) }},
+		{Name: "Power", New: func(g game.Game, seed int64) engine.Strategy { return engine.NewHoldPowerCardsStrategy(rules) }},
+	}
+}
+
+type badStrategy struct{}
+
+func (badStrategy) Decide(msg protocol.OutboundMessage) []int {
+	return []int{99, 100, 101}
+}
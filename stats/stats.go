@@ -0,0 +1,250 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/minaorangina/shed/game"
+	"github.com/minaorangina/shed/protocol"
+)
+
+// ErrGameNotOver is returned when a game that has not finished is added
+var ErrGameNotOver = errors.New("game is not over")
+
+// GameRecord is what is needed to know about a finished game: who played, and what happened
+type GameRecord struct {
+	Players []protocol.Player
+	Events  []game.Event
+}
+
+// PlayerStats are a player's results across every game added.
+// Players are known by name, so that they can be followed from game to game.
+type PlayerStats struct {
+	Name                     string  `json:"name"`
+	Games                    int     `json:"games"`
+	Wins                     int     `json:"wins"`
+	WinRate                  float64 `json:"winRate"`
+	AverageFinishingPosition float64 `json:"averageFinishingPosition"`
+	Burns                    int     `json:"burns"`
+	PilePickUps              int     `json:"pilePickUps"`
+	UnseenPlays              int     `json:"unseenPlays"`
+	UnseenSuccessRate        float64 `json:"unseenSuccessRate"`
+	AverageTurnsToFinish     float64 `json:"averageTurnsToFinish"`
+
+	positions       int
+	unseenSuccesses int
+	finishes        int
+	turnsToFinish   int
+}
+
+// Stats collects results from finished games
+type Stats struct {
+	players map[string]*PlayerStats
+}
+
+// New returns a Stats with no games added
+func New() *Stats {
+	return &Stats{players: map[string]*PlayerStats{}}
+}
+
+// Compute returns the stats of every player in the games, in leaderboard order
+func Compute(records []GameRecord) ([]PlayerStats, error) {
+	s := New()
+	for _, r := range records {
+		if err := s.Add(r); err != nil {
+			return nil, err
+		}
+	}
+	return s.Players(), nil
+}
+
+// Add adds the results of a finished game
+func (s *Stats) Add(record GameRecord) error {
+	events := standingEvents(record.Events)
+	if len(events) == 0 || events[len(events)-1].Kind != game.EventGameOver {
+		return ErrGameNotOver
+	}
+
+	names := map[string]string{}
+	for _, p := range record.Players {
+		names[p.PlayerID] = p.Name
+		if p.Name == "" {
+			names[p.PlayerID] = p.PlayerID
+		}
+	}
+
+	// check every player first, so that a bad record adds nothing
+	for _, e := range events {
+		if isDraw(e) {
+			continue
+		}
+		if _, ok := names[e.PlayerID]; !ok {
+			return fmt.Errorf("event for unknown player %s", e.PlayerID)
+		}
+	}
+
+	turns := map[string]int{}
+	finished := map[string]bool{}
+	position := 0
+	for i, e := range events {
+		if isDraw(e) {
+			// everyone yet to finish shares the next place
+			for _, p := range record.Players {
+				if !finished[p.PlayerID] {
+					s.player(names[p.PlayerID]).positions += position + 1
+				}
+			}
+			continue
+		}
+
+		ps := s.player(names[e.PlayerID])
+		if startsTurn(events, i) {
+			turns[e.PlayerID]++
+		}
+
+		switch e.Kind {
+		case game.EventBurned:
+			ps.Burns++
+		case game.EventPickedUpPile:
+			ps.PilePickUps++
+		case game.EventUnseenSuccess:
+			ps.UnseenPlays++
+			ps.unseenSuccesses++
+		case game.EventUnseenFailure:
+			ps.UnseenPlays++
+		case game.EventPlayerFinished:
+			position++
+			ps.positions += position
+			ps.finishes++
+			ps.turnsToFinish += turns[e.PlayerID]
+			finished[e.PlayerID] = true
+			if position == 1 {
+				ps.Wins++
+			}
+		case game.EventRanked:
+			// placed without having finished, so it says nothing of how long finishing takes
+			position++
+			ps.positions += position
+			finished[e.PlayerID] = true
+			if position == 1 {
+				ps.Wins++
+			}
+		case game.EventGameOver:
+			ps.positions += position + 1
+		}
+	}
+
+	for _, p := range record.Players {
+		ps := s.player(names[p.PlayerID])
+		ps.Games++
+		ps.update()
+	}
+
+	return nil
+}
+
+// Players returns the stats of every player, best first:
+// by win rate, then by average finishing position, then by name
+func (s *Stats) Players() []PlayerStats {
+	players := []PlayerStats{}
+	for _, ps := range s.players {
+		players = append(players, *ps)
+	}
+
+	sort.Slice(players, func(i, j int) bool {
+		a, b := players[i], players[j]
+		if a.WinRate != b.WinRate {
+			return a.WinRate > b.WinRate
+		}
+		if a.AverageFinishingPosition != b.AverageFinishingPosition {
+			return a.AverageFinishingPosition < b.AverageFinishingPosition
+		}
+		return a.Name < b.Name
+	})
+
+	return players
+}
+
+func (s *Stats) player(name string) *PlayerStats {
+	if _, ok := s.players[name]; !ok {
+		s.players[name] = &PlayerStats{Name: name}
+	}
+	return s.players[name]
+}
+
+func (ps *PlayerStats) update() {
+	ps.WinRate = ratio(ps.Wins, ps.Games)
+	ps.AverageFinishingPosition = ratio(ps.positions, ps.Games)
+	ps.UnseenSuccessRate = ratio(ps.unseenSuccesses, ps.UnseenPlays)
+	ps.AverageTurnsToFinish = ratio(ps.turnsToFinish, ps.finishes)
+}
+
+// isDraw reports whether the event ends a game in a draw, which no one loses
+func isDraw(e game.Event) bool {
+	return e.Kind == game.EventGameOver && e.PlayerID == ""
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// standingEvents returns the events of a game without the moves that were undone,
+// nor anything that happened because of them
+func standingEvents(events []game.Event) []game.Event {
+	standing := []game.Event{}
+	turnStarts := []int{}
+
+	for _, e := range events {
+		if e.Kind == game.EventUndone {
+			if len(turnStarts) > 0 {
+				standing = standing[:turnStarts[len(turnStarts)-1]]
+				turnStarts = turnStarts[:len(turnStarts)-1]
+			}
+			continue
+		}
+
+		standing = append(standing, e)
+		if startsTurn(standing, len(standing)-1) {
+			turnStarts = append(turnStarts, len(standing)-1)
+		}
+	}
+
+	return standing
+}
+
+// startsTurn reports whether the event is a player's move on their turn,
+// rather than part of a move already begun, a snap made out of turn, or a consequence of a move
+func startsTurn(events []game.Event, i int) bool {
+	e := events[i]
+	var prev game.Event
+	if i > 0 {
+		prev = events[i-1]
+	}
+
+	switch e.Kind {
+	case game.EventUnseenSuccess, game.EventUnseenFailure:
+		return true
+
+	case game.EventPickedUpPile:
+		// an unseen card that cannot be played is picked up along with the pile
+		return !(prev.Kind == game.EventPlayed && prev.Group == game.UnseenGroup)
+
+	case game.EventPlayed:
+		switch e.Group {
+		case game.UnseenGroup:
+			return false
+		case game.SeenGroup:
+			// hand and seen cards of the same rank can be played together
+			return !(prev.Kind == game.EventPlayed && prev.Group == game.HandGroup &&
+				prev.PlayerID == e.PlayerID && len(prev.Cards) > 0 && len(e.Cards) > 0 &&
+				prev.Cards[0].Rank == e.Cards[0].Rank)
+		}
+		return true
+	}
+
+	return false
+}
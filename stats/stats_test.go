@@ -0,0 +1,185 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/minaorangina/shed/deck"
+	"github.com/minaorangina/shed/game"
+	utils "github.com/minaorangina/shed/internal"
+	"github.com/minaorangina/shed/protocol"
+)
+
+func TestStats(t *testing.T) {
+	t.Run("computes each player's results", func(t *testing.T) {
+		// Given two finished games, one with an undone move
+		records := []GameRecord{threePlayerGame(), twoPlayerGame()}
+
+		// When the stats are computed
+		got, err := Compute(records)
+		utils.AssertNoError(t, err)
+
+		// Then each player's results are totalled, best player first
+		want := []PlayerStats{
+			{
+				Name: "Ann", Games: 2, Wins: 1, WinRate: 0.5, AverageFinishingPosition: 1.5,
+				UnseenPlays: 1, UnseenSuccessRate: 1, AverageTurnsToFinish: 2,
+			},
+			{
+				Name: "Cat", Games: 2, Wins: 1, WinRate: 0.5, AverageFinishingPosition: 2,
+				PilePickUps: 2, UnseenPlays: 1, AverageTurnsToFinish: 1,
+			},
+			{
+				Name: "Bob", Games: 1, AverageFinishingPosition: 2,
+				Burns: 1, AverageTurnsToFinish: 4,
+			},
+		}
+
+		utils.AssertEqual(t, len(got), len(want))
+		for i := range want {
+			utils.AssertEqual(t, got[i].Name, want[i].Name)
+			utils.AssertEqual(t, got[i].Games, want[i].Games)
+			utils.AssertEqual(t, got[i].Wins, want[i].Wins)
+			utils.AssertEqual(t, got[i].WinRate, want[i].WinRate)
+			utils.AssertEqual(t, got[i].AverageFinishingPosition, want[i].AverageFinishingPosition)
+			utils.AssertEqual(t, got[i].Burns, want[i].Burns)
+			utils.AssertEqual(t, got[i].PilePickUps, want[i].PilePickUps)
+			utils.AssertEqual(t, got[i].UnseenPlays, want[i].UnseenPlays)
+			utils.AssertEqual(t, got[i].UnseenSuccessRate, want[i].UnseenSuccessRate)
+			utils.AssertEqual(t, got[i].AverageTurnsToFinish, want[i].AverageTurnsToFinish)
+		}
+	})
+
+	t.Run("rejects a game that is not over", func(t *testing.T) {
+		record := threePlayerGame()
+		record.Events = record.Events[:len(record.Events)-1]
+
+		s := New()
+		err := s.Add(record)
+		utils.AssertTrue(t, errors.Is(err, ErrGameNotOver))
+		utils.AssertEqual(t, len(s.Players()), 0)
+	})
+
+	t.Run("rejects events from unknown players", func(t *testing.T) {
+		record := threePlayerGame()
+		record.Players = record.Players[:2]
+
+		s := New()
+		utils.AssertErrored(t, s.Add(record))
+		utils.AssertEqual(t, len(s.Players()), 0)
+	})
+
+	t.Run("shares the places that are left between players who drew", func(t *testing.T) {
+		// Given a game that Ann won, and that Bob and Cat drew
+		record := GameRecord{
+			Players: []protocol.Player{{PlayerID: "p1", Name: "Ann"}, {PlayerID: "p2", Name: "Bob"}, {PlayerID: "p3", Name: "Cat"}},
+			Events: numbered([]game.Event{
+				event(game.EventPlayed, "p1", game.HandGroup, deck.Ace),
+				event(game.EventPlayerFinished, "p1", ""),
+				event(game.EventGameOver, "", ""),
+			}),
+		}
+
+		got, err := Compute([]GameRecord{record})
+		utils.AssertNoError(t, err)
+
+		utils.AssertEqual(t, len(got), 3)
+		utils.AssertEqual(t, got[0].Name, "Ann")
+		utils.AssertEqual(t, got[0].Wins, 1)
+		for _, ps := range got[1:] {
+			utils.AssertEqual(t, ps.Wins, 0)
+			utils.AssertEqual(t, ps.AverageFinishingPosition, 2.0)
+		}
+	})
+
+	t.Run("places players ranked by a stalemate without counting them as finished", func(t *testing.T) {
+		// Given a game that a stalemate ended, with Ann holding fewer cards than Bob
+		record := GameRecord{
+			Players: []protocol.Player{{PlayerID: "p1", Name: "Ann"}, {PlayerID: "p2", Name: "Bob"}},
+			Events: numbered([]game.Event{
+				event(game.EventPlayed, "p1", game.HandGroup, deck.Four),
+				event(game.EventPickedUpPile, "p2", "", deck.Four),
+				event(game.EventRanked, "p1", ""),
+				event(game.EventGameOver, "p2", ""),
+			}),
+		}
+
+		got, err := Compute([]GameRecord{record})
+		utils.AssertNoError(t, err)
+
+		// Then Ann wins, but has no turns to finish to average
+		utils.AssertEqual(t, len(got), 2)
+		utils.AssertEqual(t, got[0].Name, "Ann")
+		utils.AssertEqual(t, got[0].Wins, 1)
+		utils.AssertEqual(t, got[0].AverageFinishingPosition, 1.0)
+		utils.AssertEqual(t, got[0].AverageTurnsToFinish, 0.0)
+		utils.AssertEqual(t, got[1].AverageFinishingPosition, 2.0)
+	})
+
+	t.Run("knows players without names by their ID", func(t *testing.T) {
+		record := twoPlayerGame()
+		record.Players[0].Name = ""
+
+		got, err := Compute([]GameRecord{record})
+		utils.AssertNoError(t, err)
+		utils.AssertEqual(t, got[0].Name, "c1")
+	})
+}
+
+// threePlayerGame is won by Ann, then Bob, and lost by Cat
+func threePlayerGame() GameRecord {
+	return GameRecord{
+		Players: []protocol.Player{{PlayerID: "p1", Name: "Ann"}, {PlayerID: "p2", Name: "Bob"}, {PlayerID: "p3", Name: "Cat"}},
+		Events: numbered([]game.Event{
+			event(game.EventPlayed, "p1", game.HandGroup, deck.Four),
+			event(game.EventDrew, "p1", "", deck.Jack),
+			event(game.EventPlayed, "p2", game.HandGroup, deck.Ten),
+			event(game.EventBurned, "p2", "", deck.Four, deck.Ten),
+			event(game.EventPlayed, "p2", game.HandGroup, deck.Five),
+			event(game.EventPickedUpPile, "p3", "", deck.Five),
+			event(game.EventUnseenSuccess, "p1", "", deck.Six),
+			event(game.EventPlayed, "p1", game.UnseenGroup, deck.Six),
+			event(game.EventPlayerFinished, "p1", ""),
+			// hand and seen cards played together
+			event(game.EventPlayed, "p2", game.HandGroup, deck.Seven),
+			event(game.EventPlayed, "p2", game.SeenGroup, deck.Seven),
+			event(game.EventUnseenFailure, "p3", "", deck.Four),
+			event(game.EventPlayed, "p3", game.UnseenGroup, deck.Four),
+			event(game.EventPickedUpPile, "p3", "", deck.Six, deck.Seven, deck.Seven, deck.Four),
+			// a move that is undone, then made differently
+			event(game.EventPlayed, "p2", game.HandGroup, deck.Ten),
+			event(game.EventBurned, "p2", "", deck.Ten),
+			event(game.EventUndone, "p2", ""),
+			event(game.EventPlayed, "p2", game.HandGroup, deck.Nine),
+			event(game.EventPlayerFinished, "p2", ""),
+			event(game.EventGameOver, "p3", ""),
+		}),
+	}
+}
+
+// twoPlayerGame is won by Cat and lost by Ann
+func twoPlayerGame() GameRecord {
+	return GameRecord{
+		Players: []protocol.Player{{PlayerID: "c1", Name: "Cat"}, {PlayerID: "a1", Name: "Ann"}},
+		Events: numbered([]game.Event{
+			event(game.EventPlayed, "c1", game.HandGroup, deck.Ace),
+			event(game.EventPlayerFinished, "c1", ""),
+			event(game.EventGameOver, "a1", ""),
+		}),
+	}
+}
+
+func event(kind game.EventKind, playerID string, group game.CardGroup, ranks ...deck.Rank) game.Event {
+	e := game.Event{Kind: kind, PlayerID: playerID, Group: group}
+	for _, r := range ranks {
+		e.Cards = append(e.Cards, deck.NewCard(r, deck.Hearts))
+	}
+	return e
+}
+
+func numbered(events []game.Event) []game.Event {
+	for i := range events {
+		events[i].Seq = i + 1
+	}
+	return events
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/minaorangina/shed/engine"
+	"github.com/minaorangina/shed/game"
 	"github.com/minaorangina/shed/protocol"
 )
 
@@ -22,6 +23,7 @@ type GameStore interface {
 	FindActiveGame(gameID string) engine.GameEngine
 	FindInactiveGame(gameID string) engine.GameEngine
 	FindPendingPlayer(gameID, playerID string) *protocol.Player
+	FinishedGames() []engine.GameEngine
 	AddInactiveGame(engine engine.GameEngine) error
 	AddPendingPlayer(gameID, playerID, name string) error
 	AddPlayerToGame(gameID string, player engine.Player) error
@@ -74,6 +76,21 @@ func (s *InMemoryGameStore) FindInactiveGame(ID string) engine.GameEngine {
 	return game
 }
 
+// FinishedGames returns every game that has been played to the end
+func (s *InMemoryGameStore) FinishedGames() []engine.GameEngine {
+	games := []engine.GameEngine{}
+	for _, ge := range s.Games {
+		over := false
+		ge.Inspect(func(g game.Game) {
+			over = g != nil && g.GameOver()
+		})
+		if over {
+			games = append(games, ge)
+		}
+	}
+	return games
+}
+
 func (s *InMemoryGameStore) FindPendingPlayer(gameID, playerID string) *protocol.Player {
 	pendingPlayers, ok := s.PendingPlayers[gameID]
 	if !ok {
@@ -112,7 +129,7 @@ func (s *InMemoryGameStore) AddPendingPlayer(gameID, playerID, name string) erro
 	}
 
 	// mutex required
-	s.PendingPlayers[gameID] = append(s.PendingPlayers[gameID], protocol.Player{playerID, name})
+	s.PendingPlayers[gameID] = append(s.PendingPlayers[gameID], protocol.Player{PlayerID: playerID, Name: name})
 
 	return nil
 }
@@ -4,7 +4,6 @@ import (
 	"testing"
 
 	"github.com/minaorangina/shed/engine"
-	"github.com/minaorangina/shed/game"
 	utils "github.com/minaorangina/shed/internal"
 	"github.com/minaorangina/shed/protocol"
 )
@@ -143,7 +142,7 @@ func newActiveGame(gameID, playerID string, ps engine.Players) map[string]engine
 		CreatorID: playerID,
 		Players:   ps,
 		PlayState: engine.InProgress,
-		Game:      game.ExistingShed(game.ShedOpts{}),
+		Game:      engine.UndealtGame(),
 	})
 	return map[string]engine.GameEngine{gameID: game}
 }
@@ -153,7 +152,7 @@ func NewInactiveGame(gameID, playerID string, ps engine.Players) map[string]engi
 		GameID:    gameID,
 		CreatorID: playerID,
 		Players:   ps,
-		Game:      game.ExistingShed(game.ShedOpts{}),
+		Game:      engine.UndealtGame(),
 	})
 	return map[string]engine.GameEngine{gameID: game}
 }